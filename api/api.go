@@ -1,2 +1,327 @@
+// Package api serves the HTTP and gRPC control planes of the simulator.
+//
+// The simulator implements Controller and mounts NewHandler next to the
+// Prometheus /metrics endpoint. The OpenAPI description is served at
+// /api/v1/openapi.json. RegisterGRPC exposes the same operations, plus a
+// stream of emitted values, as the Simulator service defined in simpb.
 package api
 
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Prefix is the path every control endpoint lives under.
+const Prefix = "/api/v1"
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+var (
+	// ErrNotFound is returned by a Controller for unknown buses, payloads or data points.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalid is returned by a Controller for requests it cannot apply.
+	ErrInvalid = errors.New("invalid request")
+)
+
+// Payload states
+const (
+	StateRunning = "running"
+	StatePaused  = "paused"
+	StateStopped = "stopped"
+)
+
+// Payload describes a running payload manager.
+type Payload struct {
+	Bus      string            `json:"bus"`
+	Id       string            `json:"id"`
+	State    string            `json:"state"`
+	RateHz   float64           `json:"rate_hz"`
+	DataIds  []string          `json:"data_ids"`
+	Faults   map[string]string `json:"faults,omitempty"`   // configured impairments
+	Schedule map[string]string `json:"schedule,omitempty"` // configured schedule
+}
+
+// DataPoint is the current state of a data point in the store.
+type DataPoint struct {
+	Id     string            `json:"id"`
+	Value  string            `json:"value"`
+	Forced bool              `json:"forced"`
+	Data   map[string]string `json:"data"`
+	Info   map[string]string `json:"info"`
+}
+
+// Value is a data point value as emitted on a tick.
+type Value struct {
+	Id     string `json:"id"`
+	Value  string `json:"value"`
+	Forced bool   `json:"forced"`
+}
+
+// Tick is the set of values emitted by one payload on one tick.
+type Tick struct {
+	Bus       string    `json:"bus"`
+	PayloadId string    `json:"payload_id"`
+	Time      time.Time `json:"time"`
+	Values    []Value   `json:"values"`
+}
+
+// Streamer publishes the values emitted by running payloads.
+type Streamer interface {
+	// Subscribe returns a channel of ticks for payloadIds of bus, or every
+	// payload of bus if payloadIds is empty. The channel is closed once ctx is
+	// done. Ticks are dropped rather than delaying the payload if the
+	// subscriber falls behind.
+	Subscribe(ctx context.Context, bus string, payloadIds []string) (<-chan Tick, error)
+}
+
+// PayloadStats are the monitor counters of a payload.
+type PayloadStats struct {
+	Bus          string         `json:"bus"`
+	PayloadId    string         `json:"payload_id"`
+	ConfiguredHz float64        `json:"configured_hz"`
+	AchievedHz   float64        `json:"achieved_hz"`
+	TxCount      int            `json:"tx_count"`
+	RxCount      int            `json:"rx_count"`
+	Errors       int            `json:"errors"`
+	MissedTicks  int            `json:"missed_ticks"`
+	Overruns     int            `json:"overruns"`
+	Faults       map[string]int `json:"faults,omitempty"` // injected faults by kind
+}
+
+// StatsSource reports the monitor counters of the payloads on a bus.
+type StatsSource interface {
+	Stats(ctx context.Context, bus string) ([]PayloadStats, error)
+}
+
+// Controller is the set of operations the control API exposes.
+type Controller interface {
+	Buses(ctx context.Context) []string
+	Payloads(ctx context.Context, bus string) ([]Payload, error)
+	Payload(ctx context.Context, bus string, id string) (Payload, error)
+	DataPoint(ctx context.Context, id string) (DataPoint, error)
+	SetPayloadState(ctx context.Context, bus string, id string, state string) error
+	SetPayloadRate(ctx context.Context, bus string, id string, hz float64) error
+	SetPayloadFaults(ctx context.Context, bus string, id string, faults map[string]string) error
+	SetPayloadSchedule(ctx context.Context, bus string, id string, schedule map[string]string) error
+	SendPayload(ctx context.Context, bus string, id string, count int) error
+	SetEngineParams(ctx context.Context, dataId string, params map[string]string) error
+	ForceValue(ctx context.Context, dataId string, value string) error
+	ReleaseValue(ctx context.Context, dataId string) error
+	Reload(ctx context.Context, bus string) error
+}
+
+// rateRequest is the body of PUT .../rate
+type rateRequest struct {
+	Hz float64 `json:"hz"`
+}
+
+// sendRequest is the optional body of POST .../send
+type sendRequest struct {
+	Count int `json:"count"`
+}
+
+// valueRequest is the body of PUT .../value
+type valueRequest struct {
+	Value string `json:"value"`
+}
+
+// errorResponse is the body of every error reply
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler returns an http.Handler serving the control API for c.
+func NewHandler(c Controller) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+Prefix+"/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPISpec)
+	})
+
+	mux.HandleFunc("GET "+Prefix+"/buses", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Buses(r.Context()))
+	})
+	mux.HandleFunc("GET "+Prefix+"/buses/{bus}/payloads", func(w http.ResponseWriter, r *http.Request) {
+		payloads, err := c.Payloads(r.Context(), r.PathValue("bus"))
+		reply(w, payloads, err)
+	})
+	mux.HandleFunc("GET "+Prefix+"/buses/{bus}/payloads/{id}", func(w http.ResponseWriter, r *http.Request) {
+		payload, err := c.Payload(r.Context(), r.PathValue("bus"), r.PathValue("id"))
+		reply(w, payload, err)
+	})
+	mux.HandleFunc("GET "+Prefix+"/buses/{bus}/payloads/{id}/data", func(w http.ResponseWriter, r *http.Request) {
+		payload, err := c.Payload(r.Context(), r.PathValue("bus"), r.PathValue("id"))
+		if err != nil {
+			reply(w, nil, err)
+			return
+		}
+		points := make([]DataPoint, 0, len(payload.DataIds))
+		for _, id := range payload.DataIds {
+			dp, err := c.DataPoint(r.Context(), id)
+			if err != nil {
+				reply(w, nil, err)
+				return
+			}
+			points = append(points, dp)
+		}
+		reply(w, points, nil)
+	})
+	for _, action := range []struct{ path, state string }{
+		{"start", StateRunning},
+		{"pause", StatePaused},
+		{"stop", StateStopped},
+	} {
+		mux.HandleFunc("POST "+Prefix+"/buses/{bus}/payloads/{id}/"+action.path, func(w http.ResponseWriter, r *http.Request) {
+			bus, id := r.PathValue("bus"), r.PathValue("id")
+			if err := c.SetPayloadState(r.Context(), bus, id, action.state); err != nil {
+				reply(w, nil, err)
+				return
+			}
+			payload, err := c.Payload(r.Context(), bus, id)
+			reply(w, payload, err)
+		})
+	}
+	mux.HandleFunc("PUT "+Prefix+"/buses/{bus}/payloads/{id}/rate", func(w http.ResponseWriter, r *http.Request) {
+		var req rateRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		bus, id := r.PathValue("bus"), r.PathValue("id")
+		if err := c.SetPayloadRate(r.Context(), bus, id, req.Hz); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		payload, err := c.Payload(r.Context(), bus, id)
+		reply(w, payload, err)
+	})
+	mux.HandleFunc("PUT "+Prefix+"/buses/{bus}/payloads/{id}/faults", func(w http.ResponseWriter, r *http.Request) {
+		faults := map[string]string{}
+		if !readJSON(w, r, &faults) {
+			return
+		}
+		bus, id := r.PathValue("bus"), r.PathValue("id")
+		if err := c.SetPayloadFaults(r.Context(), bus, id, faults); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		payload, err := c.Payload(r.Context(), bus, id)
+		reply(w, payload, err)
+	})
+	mux.HandleFunc("PUT "+Prefix+"/buses/{bus}/payloads/{id}/schedule", func(w http.ResponseWriter, r *http.Request) {
+		schedule := map[string]string{}
+		if !readJSON(w, r, &schedule) {
+			return
+		}
+		bus, id := r.PathValue("bus"), r.PathValue("id")
+		if err := c.SetPayloadSchedule(r.Context(), bus, id, schedule); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		payload, err := c.Payload(r.Context(), bus, id)
+		reply(w, payload, err)
+	})
+	mux.HandleFunc("POST "+Prefix+"/buses/{bus}/payloads/{id}/send", func(w http.ResponseWriter, r *http.Request) {
+		req := sendRequest{Count: 1}
+		if r.ContentLength != 0 && !readJSON(w, r, &req) {
+			return
+		}
+		bus, id := r.PathValue("bus"), r.PathValue("id")
+		if err := c.SendPayload(r.Context(), bus, id, req.Count); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		payload, err := c.Payload(r.Context(), bus, id)
+		reply(w, payload, err)
+	})
+	mux.HandleFunc("POST "+Prefix+"/buses/{bus}/reload", func(w http.ResponseWriter, r *http.Request) {
+		bus := r.PathValue("bus")
+		if err := c.Reload(r.Context(), bus); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		payloads, err := c.Payloads(r.Context(), bus)
+		reply(w, payloads, err)
+	})
+
+	mux.HandleFunc("GET "+Prefix+"/data/{id}", func(w http.ResponseWriter, r *http.Request) {
+		dp, err := c.DataPoint(r.Context(), r.PathValue("id"))
+		reply(w, dp, err)
+	})
+	mux.HandleFunc("PUT "+Prefix+"/data/{id}/value", func(w http.ResponseWriter, r *http.Request) {
+		var req valueRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		id := r.PathValue("id")
+		if err := c.ForceValue(r.Context(), id, req.Value); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		dp, err := c.DataPoint(r.Context(), id)
+		reply(w, dp, err)
+	})
+	mux.HandleFunc("DELETE "+Prefix+"/data/{id}/value", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if err := c.ReleaseValue(r.Context(), id); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		dp, err := c.DataPoint(r.Context(), id)
+		reply(w, dp, err)
+	})
+	mux.HandleFunc("PUT "+Prefix+"/data/{id}/engine", func(w http.ResponseWriter, r *http.Request) {
+		params := map[string]string{}
+		if !readJSON(w, r, &params) {
+			return
+		}
+		id := r.PathValue("id")
+		if err := c.SetEngineParams(r.Context(), id, params); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		dp, err := c.DataPoint(r.Context(), id)
+		reply(w, dp, err)
+	})
+
+	return mux
+}
+
+// readJSON decodes the request body into v, replying 400 on failure
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid body: %s", err)})
+		return false
+	}
+	return true
+}
+
+// reply writes v, or maps err to a status code
+func reply(w http.ResponseWriter, v any, err error) {
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, v)
+	case errors.Is(err, ErrNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+	case errors.Is(err, ErrInvalid):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, context.DeadlineExceeded):
+		writeJSON(w, http.StatusGatewayTimeout, errorResponse{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Sapper177/datagensim/api/simpb"
+)
+
+// grpcServer implements simpb.SimulatorServer on top of a Controller
+type grpcServer struct {
+	simpb.UnimplementedSimulatorServer
+	ctrl    Controller
+	streams Streamer
+}
+
+// RegisterGRPC registers the Simulator service backed by c and s on srv.
+func RegisterGRPC(srv *grpc.Server, c Controller, s Streamer) {
+	simpb.RegisterSimulatorServer(srv, &grpcServer{ctrl: c, streams: s})
+}
+
+func (g *grpcServer) StreamValues(req *simpb.StreamValuesRequest, stream grpc.ServerStreamingServer[simpb.ValueUpdate]) error {
+	ticks, err := g.streams.Subscribe(stream.Context(), req.GetBus(), req.GetPayloadIds())
+	if err != nil {
+		return grpcError(err)
+	}
+	want := make(map[string]bool, len(req.GetDataIds()))
+	for _, id := range req.GetDataIds() {
+		want[id] = true
+	}
+
+	for tick := range ticks {
+		update := &simpb.ValueUpdate{
+			Bus:       tick.Bus,
+			PayloadId: tick.PayloadId,
+			Time:      timestamppb.New(tick.Time),
+			Values:    make([]*simpb.Value, 0, len(tick.Values)),
+		}
+		for _, v := range tick.Values {
+			if len(want) > 0 && !want[v.Id] {
+				continue
+			}
+			update.Values = append(update.Values, &simpb.Value{Id: v.Id, Value: v.Value, Forced: v.Forced})
+		}
+		if len(update.Values) == 0 {
+			continue
+		}
+		if err := stream.Send(update); err != nil {
+			return err
+		}
+	}
+	return stream.Context().Err()
+}
+
+func (g *grpcServer) ListBuses(ctx context.Context, req *simpb.ListBusesRequest) (*simpb.ListBusesResponse, error) {
+	return &simpb.ListBusesResponse{Buses: g.ctrl.Buses(ctx)}, nil
+}
+
+func (g *grpcServer) ListPayloads(ctx context.Context, req *simpb.ListPayloadsRequest) (*simpb.ListPayloadsResponse, error) {
+	payloads, err := g.ctrl.Payloads(ctx, req.GetBus())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toPbPayloads(payloads), nil
+}
+
+func (g *grpcServer) GetPayload(ctx context.Context, req *simpb.PayloadRef) (*simpb.Payload, error) {
+	return g.payload(ctx, req.GetBus(), req.GetId())
+}
+
+func (g *grpcServer) SetPayloadState(ctx context.Context, req *simpb.SetPayloadStateRequest) (*simpb.Payload, error) {
+	state, ok := fromPbState[req.GetState()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid payload state: %s", req.GetState())
+	}
+	if err := g.ctrl.SetPayloadState(ctx, req.GetBus(), req.GetId(), state); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.payload(ctx, req.GetBus(), req.GetId())
+}
+
+func (g *grpcServer) SetPayloadRate(ctx context.Context, req *simpb.SetPayloadRateRequest) (*simpb.Payload, error) {
+	if err := g.ctrl.SetPayloadRate(ctx, req.GetBus(), req.GetId(), req.GetHz()); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.payload(ctx, req.GetBus(), req.GetId())
+}
+
+func (g *grpcServer) SetPayloadFaults(ctx context.Context, req *simpb.SetPayloadFaultsRequest) (*simpb.Payload, error) {
+	if err := g.ctrl.SetPayloadFaults(ctx, req.GetBus(), req.GetId(), req.GetFaults()); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.payload(ctx, req.GetBus(), req.GetId())
+}
+
+func (g *grpcServer) SetPayloadSchedule(ctx context.Context, req *simpb.SetPayloadScheduleRequest) (*simpb.Payload, error) {
+	if err := g.ctrl.SetPayloadSchedule(ctx, req.GetBus(), req.GetId(), req.GetSchedule()); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.payload(ctx, req.GetBus(), req.GetId())
+}
+
+func (g *grpcServer) SendPayload(ctx context.Context, req *simpb.SendPayloadRequest) (*simpb.Payload, error) {
+	count := int(req.GetCount())
+	if count == 0 {
+		count = 1
+	}
+	if err := g.ctrl.SendPayload(ctx, req.GetBus(), req.GetId(), count); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.payload(ctx, req.GetBus(), req.GetId())
+}
+
+func (g *grpcServer) Reload(ctx context.Context, req *simpb.ReloadRequest) (*simpb.ListPayloadsResponse, error) {
+	if err := g.ctrl.Reload(ctx, req.GetBus()); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.ListPayloads(ctx, &simpb.ListPayloadsRequest{Bus: req.GetBus()})
+}
+
+func (g *grpcServer) GetDataPoint(ctx context.Context, req *simpb.DataPointRef) (*simpb.DataPoint, error) {
+	return g.dataPoint(ctx, req.GetId())
+}
+
+func (g *grpcServer) SetEngineParams(ctx context.Context, req *simpb.SetEngineParamsRequest) (*simpb.DataPoint, error) {
+	if err := g.ctrl.SetEngineParams(ctx, req.GetId(), req.GetParams()); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.dataPoint(ctx, req.GetId())
+}
+
+func (g *grpcServer) ForceValue(ctx context.Context, req *simpb.ForceValueRequest) (*simpb.DataPoint, error) {
+	if err := g.ctrl.ForceValue(ctx, req.GetId(), req.GetValue()); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.dataPoint(ctx, req.GetId())
+}
+
+func (g *grpcServer) ReleaseValue(ctx context.Context, req *simpb.DataPointRef) (*simpb.DataPoint, error) {
+	if err := g.ctrl.ReleaseValue(ctx, req.GetId()); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.dataPoint(ctx, req.GetId())
+}
+
+func (g *grpcServer) payload(ctx context.Context, bus string, id string) (*simpb.Payload, error) {
+	p, err := g.ctrl.Payload(ctx, bus, id)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toPbPayload(p), nil
+}
+
+func (g *grpcServer) dataPoint(ctx context.Context, id string) (*simpb.DataPoint, error) {
+	dp, err := g.ctrl.DataPoint(ctx, id)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &simpb.DataPoint{Id: dp.Id, Value: dp.Value, Forced: dp.Forced, Data: dp.Data, Info: dp.Info}, nil
+}
+
+var fromPbState = map[simpb.PayloadState]string{
+	simpb.PayloadState_PAYLOAD_STATE_RUNNING: StateRunning,
+	simpb.PayloadState_PAYLOAD_STATE_PAUSED:  StatePaused,
+	simpb.PayloadState_PAYLOAD_STATE_STOPPED: StateStopped,
+}
+
+var toPbState = map[string]simpb.PayloadState{
+	StateRunning: simpb.PayloadState_PAYLOAD_STATE_RUNNING,
+	StatePaused:  simpb.PayloadState_PAYLOAD_STATE_PAUSED,
+	StateStopped: simpb.PayloadState_PAYLOAD_STATE_STOPPED,
+}
+
+func toPbPayload(p Payload) *simpb.Payload {
+	return &simpb.Payload{
+		Bus:      p.Bus,
+		Id:       p.Id,
+		State:    toPbState[p.State],
+		RateHz:   p.RateHz,
+		DataIds:  p.DataIds,
+		Faults:   p.Faults,
+		Schedule: p.Schedule,
+	}
+}
+
+func toPbPayloads(payloads []Payload) *simpb.ListPayloadsResponse {
+	resp := &simpb.ListPayloadsResponse{Payloads: make([]*simpb.Payload, 0, len(payloads))}
+	for _, p := range payloads {
+		resp.Payloads = append(resp.Payloads, toPbPayload(p))
+	}
+	return resp
+}
+
+// grpcError maps Controller errors to status codes, as reply does for HTTP
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrInvalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
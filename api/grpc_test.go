@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/Sapper177/datagensim/api/simpb"
+)
+
+// scheduleController keeps the schedule of payload A/1 and counts the
+// packets sent of it
+type scheduleController struct {
+	Controller // only the payload calls are made
+	schedule   map[string]string
+	sent       int
+}
+
+func (c *scheduleController) Payload(ctx context.Context, bus string, id string) (Payload, error) {
+	if bus != "A" || id != "1" {
+		return Payload{}, fmt.Errorf("payload %s/%s: %w", bus, id, ErrNotFound)
+	}
+	return Payload{Bus: bus, Id: id, State: StateRunning, Schedule: c.schedule}, nil
+}
+
+func (c *scheduleController) SetPayloadSchedule(ctx context.Context, bus string, id string, schedule map[string]string) error {
+	if _, err := c.Payload(ctx, bus, id); err != nil {
+		return err
+	}
+	if schedule["count"] == "-1" {
+		return fmt.Errorf("%w: count -1", ErrInvalid)
+	}
+	c.schedule = schedule
+	return nil
+}
+
+func (c *scheduleController) SendPayload(ctx context.Context, bus string, id string, count int) error {
+	if _, err := c.Payload(ctx, bus, id); err != nil {
+		return err
+	}
+	if count < 1 {
+		return fmt.Errorf("count %d: %w", count, ErrInvalid)
+	}
+	c.sent += count
+	return nil
+}
+
+// grpcClient serves c over an in-memory connection
+func grpcClient(t *testing.T, c Controller) simpb.SimulatorClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 16)
+	srv := grpc.NewServer()
+	RegisterGRPC(srv, c, nil)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return simpb.NewSimulatorClient(conn)
+}
+
+func TestGRPCSchedule(t *testing.T) {
+	c := &scheduleController{}
+	client := grpcClient(t, c)
+	ctx := context.Background()
+
+	schedule := map[string]string{"count": "10", "start_delay": "1s"}
+	p, err := client.SetPayloadSchedule(ctx, &simpb.SetPayloadScheduleRequest{Bus: "A", Id: "1", Schedule: schedule})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(p.GetSchedule(), schedule) {
+		t.Errorf("schedule = %v, want %v", p.GetSchedule(), schedule)
+	}
+
+	// a count of 0 sends one, like a send without a body
+	for _, count := range []int32{0, 3} {
+		if _, err := client.SendPayload(ctx, &simpb.SendPayloadRequest{Bus: "A", Id: "1", Count: count}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.sent != 4 {
+		t.Errorf("sent %d, want 4", c.sent)
+	}
+
+	for _, tt := range []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"invalid schedule", func() error {
+			_, err := client.SetPayloadSchedule(ctx, &simpb.SetPayloadScheduleRequest{Bus: "A", Id: "1", Schedule: map[string]string{"count": "-1"}})
+			return err
+		}, codes.InvalidArgument},
+		{"schedule of an unknown payload", func() error {
+			_, err := client.SetPayloadSchedule(ctx, &simpb.SetPayloadScheduleRequest{Bus: "A", Id: "2"})
+			return err
+		}, codes.NotFound},
+		{"negative count", func() error {
+			_, err := client.SendPayload(ctx, &simpb.SendPayloadRequest{Bus: "A", Id: "1", Count: -1})
+			return err
+		}, codes.InvalidArgument},
+		{"send of an unknown payload", func() error {
+			_, err := client.SendPayload(ctx, &simpb.SendPayloadRequest{Bus: "B", Id: "1"})
+			return err
+		}, codes.NotFound},
+	} {
+		if code := status.Code(tt.call()); code != tt.code {
+			t.Errorf("%s: code %v, want %v", tt.name, code, tt.code)
+		}
+	}
+}
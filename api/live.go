@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// UIPrefix is the path the live view is served under.
+const UIPrefix = "/ui/"
+
+const (
+	liveStatsInterval = time.Second
+	liveTickInterval  = 100 * time.Millisecond // per payload, browsers cannot keep up with kHz payloads
+	liveWriteTimeout  = 5 * time.Second
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+// liveMessage is a single message on the live view WebSocket
+type liveMessage struct {
+	Type  string         `json:"type"` // stats or tick
+	Stats []PayloadStats `json:"stats,omitempty"`
+	Tick  *Tick          `json:"tick,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// NewLiveHandler returns an http.Handler serving the embedded live view under
+// UIPrefix and its WebSocket feed at UIPrefix+"ws?bus=<bus>". Actions taken
+// in the page go through the control API served by NewHandler. Feeds are
+// closed once ctx is done; the server does not track the WebSockets, so ctx
+// must end when it shuts down.
+func NewLiveHandler(ctx context.Context, c Controller, s Streamer, m StatsSource) (http.Handler, error) {
+	mux := http.NewServeMux()
+
+	static, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		return nil, fmt.Errorf("live view: %w", err)
+	}
+	mux.Handle("GET "+UIPrefix, http.StripPrefix(UIPrefix, http.FileServerFS(static)))
+	mux.HandleFunc("GET "+UIPrefix+"ws", func(w http.ResponseWriter, r *http.Request) {
+		bus := r.URL.Query().Get("bus")
+		if bus == "" {
+			if buses := c.Buses(r.Context()); len(buses) > 0 {
+				bus = buses[0]
+			}
+		}
+		if _, err := m.Stats(r.Context(), bus); err != nil {
+			reply(w, nil, err)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // upgrader has already replied
+		}
+		defer conn.Close()
+		liveFeed(ctx, r, conn, bus, s, m)
+	})
+	return mux, nil
+}
+
+// liveFeed writes stats and throttled ticks of bus to conn until the client
+// goes away or the server shuts down
+func liveFeed(server context.Context, r *http.Request, conn *websocket.Conn, bus string, s Streamer, m StatsSource) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	defer context.AfterFunc(server, cancel)()
+
+	// the client never sends anything, but reading is how a close is noticed
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticks, err := s.Subscribe(ctx, bus, nil)
+	if err != nil {
+		return
+	}
+	stats := time.NewTicker(liveStatsInterval)
+	defer stats.Stop()
+	lastSent := make(map[string]time.Time)
+
+	send := func(msg liveMessage) bool {
+		conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+		return conn.WriteJSON(msg) == nil
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			if server.Err() != nil {
+				msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(liveWriteTimeout))
+			}
+			return
+		case <-stats.C:
+			st, err := m.Stats(ctx, bus)
+			if err != nil || !send(liveMessage{Type: "stats", Stats: st}) {
+				return
+			}
+		case tick, ok := <-ticks:
+			if !ok {
+				return
+			}
+			if tick.Time.Sub(lastSent[tick.PayloadId]) < liveTickInterval {
+				continue
+			}
+			lastSent[tick.PayloadId] = tick.Time
+			if !send(liveMessage{Type: "tick", Tick: &tick}) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// liveSource is a single bus A whose ticks are sent on ticks
+type liveSource struct {
+	Controller // only Buses is called
+	ticks      chan Tick
+}
+
+func (l *liveSource) Buses(ctx context.Context) []string { return []string{"A"} }
+
+func (l *liveSource) Stats(ctx context.Context, bus string) ([]PayloadStats, error) {
+	if bus != "A" {
+		return nil, ErrNotFound
+	}
+	return []PayloadStats{{Bus: "A", PayloadId: "1"}}, nil
+}
+
+func (l *liveSource) Subscribe(ctx context.Context, bus string, payloadIds []string) (<-chan Tick, error) {
+	out := make(chan Tick)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t := <-l.ticks:
+				select {
+				case out <- t:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// liveServer serves the live view of src until the returned cancel is called
+func liveServer(t *testing.T, src *liveSource) (*httptest.Server, context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	h, err := NewLiveHandler(ctx, src, src, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+	return srv, cancel
+}
+
+func dialLive(t *testing.T, srv *httptest.Server, query string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + UIPrefix + "ws" + query
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+func TestLiveFeed(t *testing.T) {
+	src := &liveSource{ticks: make(chan Tick)}
+	srv, shutdown := liveServer(t, src)
+
+	conn, _, err := dialLive(t, srv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// a tick within the throttle interval of the previous one is left out
+	t0 := time.Unix(1000, 0)
+	go func() {
+		for _, at := range []time.Duration{0, 50 * time.Millisecond, 200 * time.Millisecond} {
+			src.ticks <- Tick{Bus: "A", PayloadId: "1", Time: t0.Add(at)}
+		}
+	}()
+	var got []time.Duration
+	for len(got) < 2 {
+		var msg liveMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		switch msg.Type {
+		case "tick":
+			got = append(got, msg.Tick.Time.Sub(t0))
+		case "stats":
+			if len(msg.Stats) != 1 || msg.Stats[0].PayloadId != "1" {
+				t.Errorf("stats = %+v, want payload 1", msg.Stats)
+			}
+		default:
+			t.Fatalf("message of type %q", msg.Type)
+		}
+	}
+	if got[0] != 0 || got[1] != 200*time.Millisecond {
+		t.Errorf("ticks at %v, want 0s and 200ms", got)
+	}
+
+	// the feed ends with the server, though its connection was hijacked
+	shutdown()
+	for {
+		var msg liveMessage
+		err := conn.ReadJSON(&msg)
+		if err == nil {
+			continue // a stats message on its way
+		}
+		if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+			t.Errorf("read after shutdown = %v, want a going away close", err)
+		}
+		break
+	}
+}
+
+func TestLiveFeedUnknownBus(t *testing.T) {
+	srv, _ := liveServer(t, &liveSource{})
+	_, resp, err := dialLive(t, srv, "?bus=B")
+	if !errors.Is(err, websocket.ErrBadHandshake) || resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("dial = %v, want a not found handshake error", err)
+	}
+}
@@ -0,0 +1,4 @@
+// Package simpb holds the protobuf and gRPC definitions of the simulator API.
+package simpb
+
+//go:generate buf generate --template buf.gen.yaml
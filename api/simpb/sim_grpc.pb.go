@@ -0,0 +1,627 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: sim.proto
+
+package simpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Simulator_StreamValues_FullMethodName       = "/datagensim.v1.Simulator/StreamValues"
+	Simulator_ListBuses_FullMethodName          = "/datagensim.v1.Simulator/ListBuses"
+	Simulator_ListPayloads_FullMethodName       = "/datagensim.v1.Simulator/ListPayloads"
+	Simulator_GetPayload_FullMethodName         = "/datagensim.v1.Simulator/GetPayload"
+	Simulator_SetPayloadState_FullMethodName    = "/datagensim.v1.Simulator/SetPayloadState"
+	Simulator_SetPayloadRate_FullMethodName     = "/datagensim.v1.Simulator/SetPayloadRate"
+	Simulator_SetPayloadFaults_FullMethodName   = "/datagensim.v1.Simulator/SetPayloadFaults"
+	Simulator_SetPayloadSchedule_FullMethodName = "/datagensim.v1.Simulator/SetPayloadSchedule"
+	Simulator_SendPayload_FullMethodName        = "/datagensim.v1.Simulator/SendPayload"
+	Simulator_Reload_FullMethodName             = "/datagensim.v1.Simulator/Reload"
+	Simulator_GetDataPoint_FullMethodName       = "/datagensim.v1.Simulator/GetDataPoint"
+	Simulator_SetEngineParams_FullMethodName    = "/datagensim.v1.Simulator/SetEngineParams"
+	Simulator_ForceValue_FullMethodName         = "/datagensim.v1.Simulator/ForceValue"
+	Simulator_ReleaseValue_FullMethodName       = "/datagensim.v1.Simulator/ReleaseValue"
+)
+
+// SimulatorClient is the client API for Simulator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Simulator streams the values emitted on every tick and mirrors the control
+// operations of the HTTP API.
+type SimulatorClient interface {
+	// StreamValues sends one update per emitted payload until the client cancels.
+	StreamValues(ctx context.Context, in *StreamValuesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ValueUpdate], error)
+	ListBuses(ctx context.Context, in *ListBusesRequest, opts ...grpc.CallOption) (*ListBusesResponse, error)
+	ListPayloads(ctx context.Context, in *ListPayloadsRequest, opts ...grpc.CallOption) (*ListPayloadsResponse, error)
+	GetPayload(ctx context.Context, in *PayloadRef, opts ...grpc.CallOption) (*Payload, error)
+	SetPayloadState(ctx context.Context, in *SetPayloadStateRequest, opts ...grpc.CallOption) (*Payload, error)
+	SetPayloadRate(ctx context.Context, in *SetPayloadRateRequest, opts ...grpc.CallOption) (*Payload, error)
+	SetPayloadFaults(ctx context.Context, in *SetPayloadFaultsRequest, opts ...grpc.CallOption) (*Payload, error)
+	SetPayloadSchedule(ctx context.Context, in *SetPayloadScheduleRequest, opts ...grpc.CallOption) (*Payload, error)
+	SendPayload(ctx context.Context, in *SendPayloadRequest, opts ...grpc.CallOption) (*Payload, error)
+	Reload(ctx context.Context, in *ReloadRequest, opts ...grpc.CallOption) (*ListPayloadsResponse, error)
+	GetDataPoint(ctx context.Context, in *DataPointRef, opts ...grpc.CallOption) (*DataPoint, error)
+	SetEngineParams(ctx context.Context, in *SetEngineParamsRequest, opts ...grpc.CallOption) (*DataPoint, error)
+	ForceValue(ctx context.Context, in *ForceValueRequest, opts ...grpc.CallOption) (*DataPoint, error)
+	ReleaseValue(ctx context.Context, in *DataPointRef, opts ...grpc.CallOption) (*DataPoint, error)
+}
+
+type simulatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSimulatorClient(cc grpc.ClientConnInterface) SimulatorClient {
+	return &simulatorClient{cc}
+}
+
+func (c *simulatorClient) StreamValues(ctx context.Context, in *StreamValuesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ValueUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Simulator_ServiceDesc.Streams[0], Simulator_StreamValues_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamValuesRequest, ValueUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Simulator_StreamValuesClient = grpc.ServerStreamingClient[ValueUpdate]
+
+func (c *simulatorClient) ListBuses(ctx context.Context, in *ListBusesRequest, opts ...grpc.CallOption) (*ListBusesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListBusesResponse)
+	err := c.cc.Invoke(ctx, Simulator_ListBuses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) ListPayloads(ctx context.Context, in *ListPayloadsRequest, opts ...grpc.CallOption) (*ListPayloadsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPayloadsResponse)
+	err := c.cc.Invoke(ctx, Simulator_ListPayloads_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) GetPayload(ctx context.Context, in *PayloadRef, opts ...grpc.CallOption) (*Payload, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Payload)
+	err := c.cc.Invoke(ctx, Simulator_GetPayload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) SetPayloadState(ctx context.Context, in *SetPayloadStateRequest, opts ...grpc.CallOption) (*Payload, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Payload)
+	err := c.cc.Invoke(ctx, Simulator_SetPayloadState_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) SetPayloadRate(ctx context.Context, in *SetPayloadRateRequest, opts ...grpc.CallOption) (*Payload, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Payload)
+	err := c.cc.Invoke(ctx, Simulator_SetPayloadRate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) SetPayloadFaults(ctx context.Context, in *SetPayloadFaultsRequest, opts ...grpc.CallOption) (*Payload, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Payload)
+	err := c.cc.Invoke(ctx, Simulator_SetPayloadFaults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) SetPayloadSchedule(ctx context.Context, in *SetPayloadScheduleRequest, opts ...grpc.CallOption) (*Payload, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Payload)
+	err := c.cc.Invoke(ctx, Simulator_SetPayloadSchedule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) SendPayload(ctx context.Context, in *SendPayloadRequest, opts ...grpc.CallOption) (*Payload, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Payload)
+	err := c.cc.Invoke(ctx, Simulator_SendPayload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) Reload(ctx context.Context, in *ReloadRequest, opts ...grpc.CallOption) (*ListPayloadsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPayloadsResponse)
+	err := c.cc.Invoke(ctx, Simulator_Reload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) GetDataPoint(ctx context.Context, in *DataPointRef, opts ...grpc.CallOption) (*DataPoint, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DataPoint)
+	err := c.cc.Invoke(ctx, Simulator_GetDataPoint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) SetEngineParams(ctx context.Context, in *SetEngineParamsRequest, opts ...grpc.CallOption) (*DataPoint, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DataPoint)
+	err := c.cc.Invoke(ctx, Simulator_SetEngineParams_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) ForceValue(ctx context.Context, in *ForceValueRequest, opts ...grpc.CallOption) (*DataPoint, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DataPoint)
+	err := c.cc.Invoke(ctx, Simulator_ForceValue_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) ReleaseValue(ctx context.Context, in *DataPointRef, opts ...grpc.CallOption) (*DataPoint, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DataPoint)
+	err := c.cc.Invoke(ctx, Simulator_ReleaseValue_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SimulatorServer is the server API for Simulator service.
+// All implementations must embed UnimplementedSimulatorServer
+// for forward compatibility.
+//
+// Simulator streams the values emitted on every tick and mirrors the control
+// operations of the HTTP API.
+type SimulatorServer interface {
+	// StreamValues sends one update per emitted payload until the client cancels.
+	StreamValues(*StreamValuesRequest, grpc.ServerStreamingServer[ValueUpdate]) error
+	ListBuses(context.Context, *ListBusesRequest) (*ListBusesResponse, error)
+	ListPayloads(context.Context, *ListPayloadsRequest) (*ListPayloadsResponse, error)
+	GetPayload(context.Context, *PayloadRef) (*Payload, error)
+	SetPayloadState(context.Context, *SetPayloadStateRequest) (*Payload, error)
+	SetPayloadRate(context.Context, *SetPayloadRateRequest) (*Payload, error)
+	SetPayloadFaults(context.Context, *SetPayloadFaultsRequest) (*Payload, error)
+	SetPayloadSchedule(context.Context, *SetPayloadScheduleRequest) (*Payload, error)
+	SendPayload(context.Context, *SendPayloadRequest) (*Payload, error)
+	Reload(context.Context, *ReloadRequest) (*ListPayloadsResponse, error)
+	GetDataPoint(context.Context, *DataPointRef) (*DataPoint, error)
+	SetEngineParams(context.Context, *SetEngineParamsRequest) (*DataPoint, error)
+	ForceValue(context.Context, *ForceValueRequest) (*DataPoint, error)
+	ReleaseValue(context.Context, *DataPointRef) (*DataPoint, error)
+	mustEmbedUnimplementedSimulatorServer()
+}
+
+// UnimplementedSimulatorServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSimulatorServer struct{}
+
+func (UnimplementedSimulatorServer) StreamValues(*StreamValuesRequest, grpc.ServerStreamingServer[ValueUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamValues not implemented")
+}
+func (UnimplementedSimulatorServer) ListBuses(context.Context, *ListBusesRequest) (*ListBusesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBuses not implemented")
+}
+func (UnimplementedSimulatorServer) ListPayloads(context.Context, *ListPayloadsRequest) (*ListPayloadsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPayloads not implemented")
+}
+func (UnimplementedSimulatorServer) GetPayload(context.Context, *PayloadRef) (*Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPayload not implemented")
+}
+func (UnimplementedSimulatorServer) SetPayloadState(context.Context, *SetPayloadStateRequest) (*Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPayloadState not implemented")
+}
+func (UnimplementedSimulatorServer) SetPayloadRate(context.Context, *SetPayloadRateRequest) (*Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPayloadRate not implemented")
+}
+func (UnimplementedSimulatorServer) SetPayloadFaults(context.Context, *SetPayloadFaultsRequest) (*Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPayloadFaults not implemented")
+}
+func (UnimplementedSimulatorServer) SetPayloadSchedule(context.Context, *SetPayloadScheduleRequest) (*Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPayloadSchedule not implemented")
+}
+func (UnimplementedSimulatorServer) SendPayload(context.Context, *SendPayloadRequest) (*Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendPayload not implemented")
+}
+func (UnimplementedSimulatorServer) Reload(context.Context, *ReloadRequest) (*ListPayloadsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reload not implemented")
+}
+func (UnimplementedSimulatorServer) GetDataPoint(context.Context, *DataPointRef) (*DataPoint, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDataPoint not implemented")
+}
+func (UnimplementedSimulatorServer) SetEngineParams(context.Context, *SetEngineParamsRequest) (*DataPoint, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetEngineParams not implemented")
+}
+func (UnimplementedSimulatorServer) ForceValue(context.Context, *ForceValueRequest) (*DataPoint, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForceValue not implemented")
+}
+func (UnimplementedSimulatorServer) ReleaseValue(context.Context, *DataPointRef) (*DataPoint, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseValue not implemented")
+}
+func (UnimplementedSimulatorServer) mustEmbedUnimplementedSimulatorServer() {}
+func (UnimplementedSimulatorServer) testEmbeddedByValue()                   {}
+
+// UnsafeSimulatorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SimulatorServer will
+// result in compilation errors.
+type UnsafeSimulatorServer interface {
+	mustEmbedUnimplementedSimulatorServer()
+}
+
+func RegisterSimulatorServer(s grpc.ServiceRegistrar, srv SimulatorServer) {
+	// If the following call pancis, it indicates UnimplementedSimulatorServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Simulator_ServiceDesc, srv)
+}
+
+func _Simulator_StreamValues_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamValuesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SimulatorServer).StreamValues(m, &grpc.GenericServerStream[StreamValuesRequest, ValueUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Simulator_StreamValuesServer = grpc.ServerStreamingServer[ValueUpdate]
+
+func _Simulator_ListBuses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBusesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).ListBuses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_ListBuses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).ListBuses(ctx, req.(*ListBusesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_ListPayloads_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPayloadsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).ListPayloads(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_ListPayloads_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).ListPayloads(ctx, req.(*ListPayloadsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_GetPayload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PayloadRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).GetPayload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_GetPayload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).GetPayload(ctx, req.(*PayloadRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_SetPayloadState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPayloadStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).SetPayloadState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_SetPayloadState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).SetPayloadState(ctx, req.(*SetPayloadStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_SetPayloadRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPayloadRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).SetPayloadRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_SetPayloadRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).SetPayloadRate(ctx, req.(*SetPayloadRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_SetPayloadFaults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPayloadFaultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).SetPayloadFaults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_SetPayloadFaults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).SetPayloadFaults(ctx, req.(*SetPayloadFaultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_SetPayloadSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPayloadScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).SetPayloadSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_SetPayloadSchedule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).SetPayloadSchedule(ctx, req.(*SetPayloadScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_SendPayload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendPayloadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).SendPayload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_SendPayload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).SendPayload(ctx, req.(*SendPayloadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_Reload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).Reload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_Reload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).Reload(ctx, req.(*ReloadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_GetDataPoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DataPointRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).GetDataPoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_GetDataPoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).GetDataPoint(ctx, req.(*DataPointRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_SetEngineParams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetEngineParamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).SetEngineParams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_SetEngineParams_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).SetEngineParams(ctx, req.(*SetEngineParamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_ForceValue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForceValueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).ForceValue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_ForceValue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).ForceValue(ctx, req.(*ForceValueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_ReleaseValue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DataPointRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).ReleaseValue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulator_ReleaseValue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).ReleaseValue(ctx, req.(*DataPointRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Simulator_ServiceDesc is the grpc.ServiceDesc for Simulator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Simulator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "datagensim.v1.Simulator",
+	HandlerType: (*SimulatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListBuses",
+			Handler:    _Simulator_ListBuses_Handler,
+		},
+		{
+			MethodName: "ListPayloads",
+			Handler:    _Simulator_ListPayloads_Handler,
+		},
+		{
+			MethodName: "GetPayload",
+			Handler:    _Simulator_GetPayload_Handler,
+		},
+		{
+			MethodName: "SetPayloadState",
+			Handler:    _Simulator_SetPayloadState_Handler,
+		},
+		{
+			MethodName: "SetPayloadRate",
+			Handler:    _Simulator_SetPayloadRate_Handler,
+		},
+		{
+			MethodName: "SetPayloadFaults",
+			Handler:    _Simulator_SetPayloadFaults_Handler,
+		},
+		{
+			MethodName: "SetPayloadSchedule",
+			Handler:    _Simulator_SetPayloadSchedule_Handler,
+		},
+		{
+			MethodName: "SendPayload",
+			Handler:    _Simulator_SendPayload_Handler,
+		},
+		{
+			MethodName: "Reload",
+			Handler:    _Simulator_Reload_Handler,
+		},
+		{
+			MethodName: "GetDataPoint",
+			Handler:    _Simulator_GetDataPoint_Handler,
+		},
+		{
+			MethodName: "SetEngineParams",
+			Handler:    _Simulator_SetEngineParams_Handler,
+		},
+		{
+			MethodName: "ForceValue",
+			Handler:    _Simulator_ForceValue_Handler,
+		},
+		{
+			MethodName: "ReleaseValue",
+			Handler:    _Simulator_ReleaseValue_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamValues",
+			Handler:       _Simulator_StreamValues_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sim.proto",
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"log/syslog"
+	"os"
+	"sync"
+
+	"github.com/Sapper177/datagensim/pkg/config"
+)
+
+// startLogging makes the default slog logger write to the log file of cfg
+// in its format, at its level, and returns the function closing the file.
+// The standard logger goes through it too.
+func startLogging(cfg *config.Config) (func(), error) {
+	var level config.LogLevels
+	if err := level.Set(cfg.LogLevel); err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level.Level()}
+
+	var w io.Writer
+	closeFn := func() error { return nil }
+	var sink *syslogSink
+	switch cfg.LogFile {
+	case "stderr":
+		w = os.Stderr
+	case "stdout":
+		w = os.Stdout
+	case "syslog":
+		sw, err := syslog.New(syslog.LOG_INFO|syslog.LOG_LOCAL0, "gosim")
+		if err != nil {
+			return nil, err
+		}
+		sink = &syslogSink{w: sw}
+		w, closeFn = sink, sw.Close
+		// syslog stamps the time itself
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}
+	default:
+		f, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		w, closeFn = f, f.Close
+	}
+
+	var handler slog.Handler
+	if cfg.LogFormat == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	if sink != nil {
+		handler = &syslogHandler{Handler: handler, sink: sink}
+	}
+	slog.SetDefault(slog.New(handler))
+
+	return func() {
+		if err := closeFn(); err != nil {
+			slog.Error("closing log", "err", err)
+		}
+	}, nil
+}
+
+// syslogSink writes each formatted record to syslog at the priority of its
+// level
+type syslogSink struct {
+	mu    sync.Mutex
+	w     *syslog.Writer
+	level slog.Level // of the record being written
+}
+
+func (s *syslogSink) Write(p []byte) (int, error) {
+	msg := string(p)
+	var err error
+	switch {
+	case s.level >= slog.LevelError:
+		err = s.w.Err(msg)
+	case s.level >= slog.LevelWarn:
+		err = s.w.Warning(msg)
+	case s.level >= slog.LevelInfo:
+		err = s.w.Info(msg)
+	default:
+		err = s.w.Debug(msg)
+	}
+	return len(p), err
+}
+
+// syslogHandler passes the level of each record to the syslog sink of the
+// handler formatting it
+type syslogHandler struct {
+	slog.Handler
+	sink *syslogSink
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.sink.mu.Lock()
+	defer h.sink.mu.Unlock()
+	h.sink.level = r.Level
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{Handler: h.Handler.WithAttrs(attrs), sink: h.sink}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{Handler: h.Handler.WithGroup(name), sink: h.sink}
+}
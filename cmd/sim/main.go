@@ -3,78 +3,175 @@ package main
 import (
 	"context"
 	"flag"
-	"log"
-	"log/syslog"
+	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"runtime/debug"
+	"strings"
+	"syscall"
+
 	"github.com/Sapper177/datagensim/internal/sim"
 	"github.com/Sapper177/datagensim/pkg/config"
 )
 
-func parseargs(cfg *config.Config) {
+// version is set at build time with -ldflags "-X main.version=<version>"
+var version = "dev"
 
-	// Get Bus Name
-	flag.StringVar(&cfg.BusName, "b", "MainBus", "Bus Name")
+// command is a subcommand of the CLI
+type command struct {
+	name  string
+	args  string // positional arguments, for the usage line
+	short string
+	run   func(args []string) int
+}
 
-	// Get Source IP
-	flag.StringVar(&cfg.SrcHost, "sh", "127.0.0.1", "Source Hostname or IP address")
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "", "Run the simulation (default)", runCmd},
+		{"validate", "", "Check the configuration and the definitions in the store", validateCmd},
+		{"inspect", "", "Print the resolved payload layouts and stored values", inspectCmd},
+		{"record", "", "Run the simulation and capture the generated traffic", recordCmd},
+		{"replay", "<capture>", "Send captured traffic on the configured buses", replayCmd},
+		{"version", "", "Print the version", versionCmd},
+		{"help", "[command]", "Show help for a command", helpCmd},
+	}
+}
 
-	// Get Destination IP
-	flag.StringVar(&cfg.DestHost, "dh", "127.0.0.1", "Destination Hostname or IP address")
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.short)
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags before any command run the simulation. Use \"%s help <command>\" for its flags.\n", os.Args[0])
+}
 
-	// Get Source Port
-	flag.IntVar(&cfg.SrcPort, "sp", 0, "Source port")
+func main() {
+	args := os.Args[1:]
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	for _, c := range commands {
+		if c.name == name {
+			os.Exit(c.run(args))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
 
-	// Get Destination Port
-	flag.IntVar(&cfg.DestPort, "dp", 0, "Destination port")
+// newFlagSet returns the flag set of a subcommand, with the config settings
+// registered by parseConfig
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		for _, c := range commands {
+			if c.name == name {
+				fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] %s\n\n%s.\n\nFlags:\n", os.Args[0], name, c.args, c.short)
+			}
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
 
-	// Optional Arguments
-	flag.StringVar(&cfg.LogFile, "l", "/var/tmp/log", "Log file path")
-	flag.StringVar(&cfg.LogLevel, "ll", "info", "Log level (debug, info, warn, error)")
+// parseConfig parses args and loads the config from the defaults, the
+// config file, the environment and the command line args, in increasing
+// precedence. It exits on an invalid config and after -print-config.
+func parseConfig(fs *flag.FlagSet, args []string) *config.Config {
+	loader := config.NewLoader(fs)
+	fs.Parse(args)
 
-	flag.Parse()
+	cfg, err := loader.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n", err)
+		os.Exit(2)
+	}
+	if loader.PrintConfig() {
+		if err := cfg.Write(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	return cfg
 }
 
-func main(){
-	log.SetOutput(os.Stdout)
+// signalContext returns a context cancelled by SIGINT or SIGTERM. Later
+// signals get their default behaviour, so a second interrupt exits at once.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
 
-	// Create config and load from command line args
-	cfg := new(sim.Config)
-	parseargs(&cfg)
+// runCmd exits with 0 after a clean shutdown and 1 when the simulation
+// fails
+func runCmd(args []string) int {
+	// Load config from file, environment and command line args
+	cfg := parseConfig(newFlagSet("run"), args)
 
 	// Create a new logger
-	logger, err := syslog.New(syslog.LOG_INFO|syslog.LOG_LOCAL0, "gosim")
+	closeLog, err := startLogging(cfg)
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintf(os.Stderr, "unable to open log: %s\n", err)
+		return 1
 	}
-	log.SetOutput(logger)
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-	log.Println("Starting simulation with config:", cfg)
-
-	// Create a context with cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	defer closeLog()
+	slog.Info("starting simulation", "buses", len(cfg.BusConfigs()), "db", cfg.DbHost+":"+cfg.DbPort)
 
 	// Handle OS signals for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, os.Kill)
+	ctx, stop := signalContext()
+	defer stop()
+
+	// SIGHUP reloads the payload definitions
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// Run the simulation until a signal or a fatal error
+	if err := sim.Sim(&ctx, cfg, sim.WithReload(hup)); err != nil {
+		slog.Error("simulation failed", "err", err)
+		return 1
+	}
+	return 0
+}
 
-	// Start the simulation in a goroutine
-	go func() {
-		if err := sim.Sim(ctx, cfg); err != nil {
-			log.Println("Error in simulation:", err)
-		}
-	}()
+func versionCmd(args []string) int {
+	fs := newFlagSet("version")
+	fs.Parse(args)
 
-	// Wait for OS signal
-	sig := <-sigChan
-	log.Println("Received signal:", sig)
-	// Cancel the context to stop the simulation
-	cancel()
-	log.Println("Simulation stopped gracefully")
+	fmt.Printf("datagensim %s\n", version)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" || s.Key == "vcs.modified" {
+				fmt.Printf("%s %s\n", s.Key, s.Value)
+			}
+		}
+		fmt.Printf("go %s\n", strings.TrimPrefix(info.GoVersion, "go"))
+	}
+	return 0
+}
 
-	// Close the logger
-	if err := logger.Close(); err != nil {
-		log.Println("Error closing logger:", err)
+func helpCmd(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return 0
+	}
+	for _, c := range commands {
+		if c.name == args[0] && c.name != "help" {
+			// -h prints the usage of the command with all of its flags
+			return c.run([]string{"-h"})
+		}
 	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	usage()
+	return 2
 }
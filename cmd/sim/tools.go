@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Sapper177/datagensim/internal/sim"
+)
+
+func validateCmd(args []string) int {
+	cfg := parseConfig(newFlagSet("validate"), args)
+
+	ctx := context.Background()
+	if err := sim.Validate(&ctx, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid definitions:\n%s\n", err)
+		return 1
+	}
+	fmt.Printf("ok: %d buses\n", len(cfg.BusConfigs()))
+	return 0
+}
+
+func inspectCmd(args []string) int {
+	fs := newFlagSet("inspect")
+	bus := fs.String("bus", "", "Only the payloads of this bus")
+	payloads := fs.String("payload", "", "Only these payload ids, comma separated")
+	asJSON := fs.Bool("json", false, "Print JSON instead of tables")
+	cfg := parseConfig(fs, args)
+
+	var ids []string
+	if *payloads != "" {
+		ids = strings.Split(*payloads, ",")
+	}
+	ctx := context.Background()
+	layouts, err := sim.Inspect(&ctx, cfg, *bus, ids)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(layouts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	for i, p := range layouts {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Bus %s payload %s: %s, %g Hz, %d bytes (header %d, data %d, footer %d)\n",
+			p.Bus, p.Id, p.PacketType, p.RateHz, p.HeaderBytes+p.DataBytes+p.FooterBytes, p.HeaderBytes, p.DataBytes, p.FooterBytes)
+		if len(p.Faults) > 0 {
+			fmt.Printf("  faults: %v\n", p.Faults)
+		}
+		if len(p.Schedule) > 0 {
+			fmt.Printf("  schedule: %v\n", p.Schedule)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "  DATA\tNAME\tTYPE\tOFFSET\tBITS\tSOURCE\tVALUE")
+		for _, d := range p.Data {
+			source := d.Source
+			if d.Detail != "" {
+				source += " " + d.Detail
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%d\t%d\t%s\t%s\n", d.Id, d.Name, d.Type, d.Offset, d.Bits, source, d.Value)
+		}
+		tw.Flush()
+	}
+	return 0
+}
+
+func recordCmd(args []string) int {
+	fs := newFlagSet("record")
+	out := fs.String("o", "datagensim.pcapng", "Capture file (pcapng)")
+	duration := fs.Duration("duration", 0, "Stop after this long, 0 to record until interrupted")
+	cfg := parseConfig(fs, args)
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+	capture, err := sim.NewCapture(f, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	closeLog, err := startLogging(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to open log: %s\n", err)
+		return 1
+	}
+	defer closeLog()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	if *duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+	slog.Info("recording", "file", *out)
+	simErr := sim.Sim(&ctx, cfg, sim.WithCapture(capture))
+
+	if err := capture.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "writing %s: %s\n", *out, err)
+		return 1
+	}
+	fmt.Printf("captured %d payloads to %s\n", capture.Packets(), *out)
+	if simErr != nil {
+		fmt.Fprintln(os.Stderr, simErr)
+		return 1
+	}
+	return 0
+}
+
+func replayCmd(args []string) int {
+	fs := newFlagSet("replay")
+	var opts sim.ReplayOptions
+	fs.StringVar(&opts.Bus, "bus", "", "Send every payload on this bus")
+	fs.Float64Var(&opts.Speed, "speed", 1, "Playback speed")
+	fs.BoolVar(&opts.Loop, "loop", false, "Start over at the end of the capture")
+	cfg := parseConfig(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if opts.Speed <= 0 {
+		fmt.Fprintf(os.Stderr, "invalid speed %v\n", opts.Speed)
+		return 2
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	start := time.Now()
+	sent, err := sim.Replay(&ctx, cfg, fs.Arg(0), opts)
+	fmt.Printf("replayed %d payloads in %s\n", sent, time.Since(start).Round(time.Millisecond))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
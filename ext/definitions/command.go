@@ -0,0 +1,94 @@
+package definitions
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// --- Example command/response definition ---
+//
+// command:  0xC5 | opcode (2) | sequence (2) | argument length (2) | arguments | CRC32 (4)
+// response: 0xA5 | opcode (2) | sequence (2) | status (1) | CRC32 (4)
+
+const (
+	CommandSync  byte = 0xC5
+	ResponseSync byte = 0xA5
+
+	CommandHeaderSize = 7
+)
+
+// Response status codes
+const (
+	StatusOk byte = iota
+	StatusUnknownOpcode
+	StatusBadLength
+	StatusBadChecksum
+	StatusInvalidArgument
+	StatusFailed
+)
+
+// StatusText returns a short name for a response status code.
+func StatusText(status byte) string {
+	switch status {
+	case StatusOk:
+		return "ok"
+	case StatusUnknownOpcode:
+		return "unknown_opcode"
+	case StatusBadLength:
+		return "bad_length"
+	case StatusBadChecksum:
+		return "bad_checksum"
+	case StatusInvalidArgument:
+		return "invalid_argument"
+	case StatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// CommandHeader holds the decoded fields of a command packet header.
+type CommandHeader struct {
+	Opcode   uint16
+	Sequence uint16
+	Length   uint16 // argument bytes following the header
+}
+
+// ParseCommandHeader decodes the command header at the start of buf.
+func ParseCommandHeader(buf []byte) (CommandHeader, error) {
+	if len(buf) < CommandHeaderSize {
+		return CommandHeader{}, fmt.Errorf("command too short: %d bytes", len(buf))
+	}
+	if buf[0] != CommandSync {
+		return CommandHeader{}, fmt.Errorf("bad command sync byte: %#x", buf[0])
+	}
+	return CommandHeader{
+		Opcode:   binary.BigEndian.Uint16(buf[1:]),
+		Sequence: binary.BigEndian.Uint16(buf[3:]),
+		Length:   binary.BigEndian.Uint16(buf[5:]),
+	}, nil
+}
+
+// NewCommandHeader returns the header for a command packet with length argument bytes.
+func NewCommandHeader(opcode, seq, length uint16) *Header {
+	return &Header{
+		Elements: []ByteSource{
+			ByteConstant(CommandSync),
+			Uint16Constant(opcode),
+			Uint16Constant(seq),
+			Uint16Constant(length),
+		},
+	}
+}
+
+// NewResponseHeader returns the header for the response to a command.
+func NewResponseHeader(opcode, seq uint16, status byte) *Header {
+	return &Header{
+		Elements: []ByteSource{
+			ByteConstant(ResponseSync),
+			Uint16Constant(opcode),
+			Uint16Constant(seq),
+			ByteConstant(status),
+		},
+	}
+}
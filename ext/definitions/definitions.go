@@ -8,9 +8,11 @@ import (
 )
 
 // ByteSource is an interface that any header element must implement.
-// It defines a method to get the byte representation of the element.
+// It appends the byte representation of the element to dst and returns the
+// extended slice, so that headers can be assembled into a reused buffer
+// without allocating.
 type ByteSource interface {
-	Bytes() ([]byte, uint16, error)
+	AppendBytes(dst []byte) ([]byte, error)
 }
 
 // --- Implementations for Constant Values ---
@@ -18,66 +20,102 @@ type ByteSource interface {
 // ByteConstant wraps a single byte constant.
 type ByteConstant byte
 
-// Bytes returns the byte representation of the ByteConstant.
-func (b ByteConstant) Bytes() ([]byte, uint16, error) {
-	s := uint16(1)
-	return []byte{byte(b)}, s, nil
+// AppendBytes appends the byte representation of the ByteConstant.
+func (b ByteConstant) AppendBytes(dst []byte) ([]byte, error) {
+	return append(dst, byte(b)), nil
 }
 
+// Size returns the encoded size of the ByteConstant.
+func (b ByteConstant) Size() uint16 { return 1 }
+
 // Uint16Constant wraps a uint16 constant.
 type Uint16Constant uint16
 
-// Bytes returns the big-endian byte representation of the Uint16Constant.
-func (u Uint16Constant) Bytes() ([]byte, uint16, error) {
-	s := uint16(2)
-	buf := make([]byte, s)
-	binary.BigEndian.PutUint16(buf, uint16(u)) // Use network byte order (big-endian)
-	return buf, s, nil
+// AppendBytes appends the big-endian byte representation of the Uint16Constant.
+func (u Uint16Constant) AppendBytes(dst []byte) ([]byte, error) {
+	return binary.BigEndian.AppendUint16(dst, uint16(u)), nil // Use network byte order (big-endian)
 }
 
+// Size returns the encoded size of the Uint16Constant.
+func (u Uint16Constant) Size() uint16 { return 2 }
+
 // Uint32Constant wraps a uint32 constant.
 type Uint32Constant uint32
 
-// Bytes returns the big-endian byte representation of the Uint32Constant.
-func (u Uint32Constant) Bytes() ([]byte, uint16, error) {
-	s := uint16(4)
-	buf := make([]byte, s)
-	binary.BigEndian.PutUint32(buf, uint32(u)) // Use network byte order (big-endian)
-	return buf, s, nil
+// AppendBytes appends the big-endian byte representation of the Uint32Constant.
+func (u Uint32Constant) AppendBytes(dst []byte) ([]byte, error) {
+	return binary.BigEndian.AppendUint32(dst, uint32(u)), nil // Use network byte order (big-endian)
 }
 
+// Size returns the encoded size of the Uint32Constant.
+func (u Uint32Constant) Size() uint16 { return 4 }
+
 // StringConstant wraps a string constant.
 // Note: This assumes the string bytes themselves are the desired payload representation.
 type StringConstant string
 
-// Bytes returns the byte representation of the StringConstant (UTF-8 bytes).
-func (s StringConstant) Bytes() ([]byte, uint16, error) {
-	l := len(s)
-	if l > math.MaxUint16 {
-		return []byte(s), math.MaxUint16, fmt.Errorf("string larger than uint16 max")
+// AppendBytes appends the byte representation of the StringConstant (UTF-8 bytes).
+func (s StringConstant) AppendBytes(dst []byte) ([]byte, error) {
+	if len(s) > math.MaxUint16 {
+		return dst, fmt.Errorf("string larger than uint16 max")
 	}
-	return []byte(s), uint16(l), nil
+	return append(dst, s...), nil
 }
 
 
 // --- Implementation for Function Results ---
 
-// ByteFunc is a function type that returns bytes and an error.
-type ByteFunc func() ([]byte, uint16, error)
+// ByteFunc is a function type that appends bytes to dst.
+type ByteFunc func(dst []byte) ([]byte, error)
 
 // FuncSource wraps a ByteFunc.
 type FuncSource struct {
 	Fn ByteFunc
 }
 
-// Bytes calls the wrapped function and returns its result.
-func (fs FuncSource) Bytes() ([]byte, uint16, error) {
+// AppendBytes calls the wrapped function and returns its result.
+func (fs FuncSource) AppendBytes(dst []byte) ([]byte, error) {
 	if fs.Fn == nil {
-		return nil, 0, errors.New("FuncSource has a nil function")
+		return dst, errors.New("FuncSource has a nil function")
 	}
-	return fs.Fn()
+	return fs.Fn(dst)
+}
+
+// --- Implementations for Receive-side Fields ---
+
+// Sizer is implemented by elements whose size is known without producing bytes.
+// Elements with side effects (such as counters) must implement it so that
+// offsets can be located without advancing them.
+type Sizer interface {
+	Size() uint16
+}
+
+// PayloadId wraps the payload id so that receivers can locate it in a header.
+type PayloadId uint32
+
+// AppendBytes appends the big-endian byte representation of the PayloadId.
+func (p PayloadId) AppendBytes(dst []byte) ([]byte, error) {
+	return Uint32Constant(p).AppendBytes(dst)
+}
+
+// Size returns the encoded size of the PayloadId.
+func (p PayloadId) Size() uint16 { return 4 }
+
+// SequenceCounter is a 16-bit counter that increments every time its bytes are produced.
+type SequenceCounter struct {
+	next uint16
+}
+
+// AppendBytes appends the current count in big-endian order and advances the counter.
+func (c *SequenceCounter) AppendBytes(dst []byte) ([]byte, error) {
+	dst = binary.BigEndian.AppendUint16(dst, c.next)
+	c.next++
+	return dst, nil
 }
 
+// Size returns the encoded size of the counter.
+func (c *SequenceCounter) Size() uint16 { return 2 }
+
 // --- Header Structure ---
 
 // Header defines the structure of the payload header as a sequence of ByteSource elements.
@@ -85,6 +123,48 @@ type Header struct {
 	Elements []ByteSource
 }
 
+// Locate returns the byte offset and element of the first header element
+// for which match returns true.
+func (h *Header) Locate(match func(ByteSource) bool) (int, ByteSource, error) {
+	idx := 0
+	for i, element := range h.Elements {
+		if element == nil {
+			return 0, nil, fmt.Errorf("header element at index %d is nil", i)
+		}
+		if match(element) {
+			return idx, element, nil
+		}
+		if sz, ok := element.(Sizer); ok {
+			idx += int(sz.Size())
+			continue
+		}
+		b, err := element.AppendBytes(nil)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to size header element at index %d: %w", i, err)
+		}
+		idx += len(b)
+	}
+	return 0, nil, errors.New("element not found in header")
+}
+
+// IdOffset returns the byte offset of the PayloadId element in the header.
+func (h *Header) IdOffset() (int, error) {
+	off, _, err := h.Locate(func(e ByteSource) bool {
+		_, ok := e.(PayloadId)
+		return ok
+	})
+	return off, err
+}
+
+// SequenceOffset returns the byte offset of the SequenceCounter element in the header.
+func (h *Header) SequenceOffset() (int, error) {
+	off, _, err := h.Locate(func(e ByteSource) bool {
+		_, ok := e.(*SequenceCounter)
+		return ok
+	})
+	return off, err
+}
+
 type Footer struct {
 	Elements []ByteSource
 }
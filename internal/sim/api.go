@@ -0,0 +1,327 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"time"
+
+	"github.com/Sapper177/datagensim/api"
+	"github.com/Sapper177/datagensim/pkg/database"
+)
+
+// apiController implements api.Controller, api.Streamer and api.StatsSource on top of the payload controllers of every bus
+type apiController struct {
+	buses   []*bus // in configuration order
+	db      *database.RedisClient
+	monitor *payloadMonitor
+	values  *valueHub
+}
+
+func newAPIController(buses []*bus, db *database.RedisClient, monitor *payloadMonitor, values *valueHub) *apiController {
+	return &apiController{buses: buses, db: db, monitor: monitor, values: values}
+}
+
+func (a *apiController) Buses(ctx context.Context) []string {
+	names := make([]string, len(a.buses))
+	for i, b := range a.buses {
+		names[i] = b.name
+	}
+	return names
+}
+
+// bus returns the running bus called name, mapping unknown names to api.ErrNotFound
+func (a *apiController) bus(name string) (*bus, error) {
+	for _, b := range a.buses {
+		if b.name == name {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("bus %s: %w", name, api.ErrNotFound)
+}
+
+// dataBuses returns the buses with a running payload containing dataId
+func (a *apiController) dataBuses(dataId string) ([]*bus, error) {
+	var buses []*bus
+	for _, b := range a.buses {
+		if len(b.ctl.ownersOf(dataId)) > 0 {
+			buses = append(buses, b)
+		}
+	}
+	if len(buses) == 0 {
+		return nil, fmt.Errorf("data point %s: %w", dataId, api.ErrNotFound)
+	}
+	return buses, nil
+}
+
+// liveValue returns the latest value of dataId held by a running payload, and
+// reports whether a payload has it
+func (a *apiController) liveValue(ctx context.Context, dataId string) (string, bool, error) {
+	for _, b := range a.buses {
+		for _, pid := range b.ctl.ownersOf(dataId) {
+			var value string
+			found := false
+			err := b.ctl.call(ctx, pid, func(pm *payloadManager) error {
+				if dp, ok := pm.dpMap[dataId]; ok {
+					value, found = string(dp.appendValue(nil)), true
+				}
+				return nil
+			})
+			if err != nil && ctx.Err() != nil {
+				return "", false, err
+			}
+			if found {
+				return value, true, nil
+			}
+		}
+	}
+	return "", false, nil
+}
+
+func (a *apiController) Payloads(ctx context.Context, bus string) ([]api.Payload, error) {
+	b, err := a.bus(bus)
+	if err != nil {
+		return nil, err
+	}
+	ids := b.ctl.ids()
+	payloads := make([]api.Payload, 0, len(ids))
+	for _, id := range ids {
+		p, err := a.Payload(ctx, bus, id)
+		if err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, p)
+	}
+	return payloads, nil
+}
+
+func (a *apiController) Payload(ctx context.Context, bus string, id string) (api.Payload, error) {
+	p := api.Payload{Bus: bus, Id: id}
+	err := a.call(ctx, bus, id, func(pm *payloadManager) error {
+		p.State = pm.state
+		p.RateHz = float64(time.Second) / float64(pm.freq)
+		p.DataIds = make([]string, 0, len(pm.dpMap))
+		for dataId := range pm.dpMap {
+			p.DataIds = append(p.DataIds, dataId)
+		}
+		sort.Strings(p.DataIds)
+		p.Faults = maps.Clone(pm.faultCfg.params)
+		p.Schedule = maps.Clone(pm.sched.params)
+		return nil
+	})
+	return p, err
+}
+
+func (a *apiController) DataPoint(ctx context.Context, id string) (api.DataPoint, error) {
+	dp := api.DataPoint{Id: id}
+	buses, err := a.dataBuses(id)
+	if err != nil {
+		return dp, err
+	}
+	b := buses[0]
+	err = b.ctl.call(ctx, b.ctl.ownersOf(id)[0], func(pm *payloadManager) error {
+		_, dp.Forced = pm.forced[id]
+		if d, ok := pm.dpMap[id]; ok {
+			dp.Value = string(d.appendValue(nil))
+		}
+		return nil
+	})
+	if err != nil {
+		return dp, err
+	}
+	if dp.Data, err = a.db.GetData(id); err != nil {
+		return dp, err
+	}
+	if dp.Info, err = a.db.GetDataInfo(id); err != nil {
+		return dp, err
+	}
+	// the store is synced periodically, the payload has the latest value
+	if dp.Value != "" {
+		dp.Data["value"] = dp.Value
+	} else {
+		dp.Value = dp.Data["value"]
+	}
+	return dp, nil
+}
+
+func (a *apiController) SetPayloadState(ctx context.Context, bus string, id string, state string) error {
+	switch state {
+	case api.StateRunning, api.StatePaused, api.StateStopped:
+	default:
+		return fmt.Errorf("state %s: %w", state, api.ErrInvalid)
+	}
+	return a.call(ctx, bus, id, func(pm *payloadManager) error {
+		return pm.setState(state)
+	})
+}
+
+func (a *apiController) SetPayloadRate(ctx context.Context, bus string, id string, hz float64) error {
+	if _, err := ratePeriod(hz); err != nil {
+		return err
+	}
+	err := a.call(ctx, bus, id, func(pm *payloadManager) error {
+		return pm.setRate(hz)
+	})
+	if err != nil {
+		return err
+	}
+	a.monitor.setConfiguredRate(bus, id, hz)
+	return nil
+}
+
+func (a *apiController) SetPayloadFaults(ctx context.Context, bus string, id string, faults map[string]string) error {
+	if _, err := parseFaults(faults); err != nil {
+		return fmt.Errorf("%w: %s", api.ErrInvalid, err)
+	}
+	return a.call(ctx, bus, id, func(pm *payloadManager) error {
+		return pm.setFaultParams(faults)
+	})
+}
+
+func (a *apiController) SetPayloadSchedule(ctx context.Context, bus string, id string, schedule map[string]string) error {
+	if _, err := parseSchedule(schedule); err != nil {
+		return fmt.Errorf("%w: %s", api.ErrInvalid, err)
+	}
+	return a.call(ctx, bus, id, func(pm *payloadManager) error {
+		return pm.setSchedule(schedule)
+	})
+}
+
+func (a *apiController) SendPayload(ctx context.Context, bus string, id string, count int) error {
+	if count < 1 {
+		return fmt.Errorf("count %d: %w", count, api.ErrInvalid)
+	}
+	return a.call(ctx, bus, id, func(pm *payloadManager) error {
+		if count > cap(pm.cs.writeChan) {
+			return fmt.Errorf("%w: count %d, at most %d at once", api.ErrInvalid, count, cap(pm.cs.writeChan))
+		}
+		return pm.sendNow(count)
+	})
+}
+
+func (a *apiController) SetEngineParams(ctx context.Context, dataId string, params map[string]string) error {
+	buses, err := a.dataBuses(dataId)
+	if err != nil {
+		return err
+	}
+	// apply in a fixed order so that min/max checks are deterministic
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	setParams := func(dp dataPoint) error {
+		for _, name := range names {
+			if err := dp.setParam(name, params[name]); err != nil {
+				return fmt.Errorf("%w: %s", api.ErrInvalid, err)
+			}
+		}
+		return nil
+	}
+	if err := a.check(buses, dataId, setParams); err != nil {
+		return err
+	}
+	for _, b := range buses {
+		if err := b.ctl.callData(ctx, dataId, setParams); err != nil {
+			return err
+		}
+	}
+	return a.db.UpdateDataInfo(dataId, params)
+}
+
+func (a *apiController) ForceValue(ctx context.Context, dataId string, value string) error {
+	buses, err := a.dataBuses(dataId)
+	if err != nil {
+		return err
+	}
+	err = a.check(buses, dataId, func(dp dataPoint) error {
+		if err := dp.set(value); err != nil {
+			return fmt.Errorf("%w: invalid value for %s: %s", api.ErrInvalid, dataId, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = a.callOwners(ctx, dataId, func(pm *payloadManager) error {
+		if err := pm.force(dataId, value); err != nil {
+			return fmt.Errorf("%w: %s", api.ErrInvalid, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return a.db.UpdateData(dataId, map[string]string{"value": value})
+}
+
+func (a *apiController) ReleaseValue(ctx context.Context, dataId string) error {
+	return a.callOwners(ctx, dataId, func(pm *payloadManager) error {
+		pm.release(dataId)
+		return nil
+	})
+}
+
+// check runs fn on a copy of dataId, as stored, for every payload of buses
+// containing it, so that a change is validated against every owner before
+// any of them is changed
+func (a *apiController) check(buses []*bus, dataId string, fn func(dp dataPoint) error) error {
+	for _, b := range buses {
+		for _, payloadId := range b.ctl.ownersOf(dataId) {
+			dp, err := stagedDataPoint(a.db, payloadId, dataId)
+			if err != nil {
+				return err
+			}
+			if err := fn(dp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// callOwners runs fn on every payload containing dataId, on every bus
+func (a *apiController) callOwners(ctx context.Context, dataId string, fn func(pm *payloadManager) error) error {
+	buses, err := a.dataBuses(dataId)
+	if err != nil {
+		return err
+	}
+	for _, b := range buses {
+		for _, payloadId := range b.ctl.ownersOf(dataId) {
+			if err := b.ctl.call(ctx, payloadId, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *apiController) Reload(ctx context.Context, bus string) error {
+	b, err := a.bus(bus)
+	if err != nil {
+		return err
+	}
+	_, err = b.reload(ctx)
+	return err
+}
+
+// Stats implements api.StatsSource
+func (a *apiController) Stats(ctx context.Context, bus string) ([]api.PayloadStats, error) {
+	if _, err := a.bus(bus); err != nil {
+		return nil, err
+	}
+	return a.monitor.stats(bus), nil
+}
+
+// call runs fn on payload id of bus, mapping unknown ids to api.ErrNotFound
+func (a *apiController) call(ctx context.Context, bus string, id string, fn func(pm *payloadManager) error) error {
+	b, err := a.bus(bus)
+	if err != nil {
+		return err
+	}
+	if !b.ctl.has(id) {
+		return fmt.Errorf("payload %s/%s: %w", bus, id, api.ErrNotFound)
+	}
+	return b.ctl.call(ctx, id, fn)
+}
@@ -0,0 +1,86 @@
+package sim
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// bufferPool recycles the payload buffers of queued packets, so that emitting
+// allocates nothing once the pool holds as many buffers as are in flight. A
+// buffer comes back once its packet is sent or dropped.
+type bufferPool struct {
+	mu   sync.Mutex
+	free [][]byte
+}
+
+// get returns a buffer of n bytes
+func (p *bufferPool) get(n int) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.free) > 0 {
+		b := p.free[len(p.free)-1]
+		p.free = p.free[:len(p.free)-1]
+		// buffers of a layout before a reload may be too small
+		if cap(b) >= n {
+			return b[:n]
+		}
+	}
+	return make([]byte, n)
+}
+
+// put hands a buffer back for reuse
+func (p *bufferPool) put(b []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.free = append(p.free, b[:0])
+}
+
+// delayQueue holds impaired packets until they are due and hands them to the
+// manager loop through its timer. Callers hold cs.mu.
+type delayQueue struct {
+	pending []delayedPacket // by due time, in the order queued for equal ones
+	timer   *time.Timer     // fires once the earliest packet is due
+}
+
+func newDelayQueue() *delayQueue {
+	return &delayQueue{timer: newStoppedTimer()}
+}
+
+// push holds pkt until due
+func (q *delayQueue) push(pkt Packet, due time.Time) {
+	i, _ := slices.BinarySearchFunc(q.pending, due, func(d delayedPacket, due time.Time) int {
+		if d.due.After(due) {
+			return 1
+		}
+		return -1
+	})
+	q.pending = slices.Insert(q.pending, i, delayedPacket{pkt: pkt, due: due})
+	if i == 0 {
+		q.timer.Reset(time.Until(due))
+	}
+}
+
+// release calls fn on every packet due at now, in order, and arms the timer
+// for the next one
+func (q *delayQueue) release(now time.Time, fn func(pkt Packet)) {
+	n := 0
+	for n < len(q.pending) && !q.pending[n].due.After(now) {
+		fn(q.pending[n].pkt)
+		n++
+	}
+	q.pending = slices.Delete(q.pending, 0, n)
+	if len(q.pending) > 0 {
+		q.timer.Reset(q.pending[0].due.Sub(now))
+	}
+}
+
+// stop drops the packets still held and hands their buffers back to pool
+func (q *delayQueue) stop(pool *bufferPool) {
+	q.timer.Stop()
+	for _, d := range q.pending {
+		pool.put(d.pkt.Payload)
+	}
+	clear(q.pending)
+	q.pending = q.pending[:0]
+}
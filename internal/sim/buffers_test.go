@@ -0,0 +1,36 @@
+package sim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelayQueue(t *testing.T) {
+	pool := &bufferPool{}
+	q := newDelayQueue()
+	t0 := time.Now()
+	for i, delay := range []time.Duration{30, 10, 20, 10} {
+		buf := pool.get(1)
+		buf[0] = byte(i)
+		q.push(Packet{Payload: buf}, t0.Add(delay*time.Millisecond))
+	}
+
+	// due in order, equal ones in the order queued
+	var got []byte
+	q.release(t0.Add(20*time.Millisecond), func(pkt Packet) {
+		got = append(got, pkt.Payload[0])
+		pool.put(pkt.Payload)
+	})
+	if string(got) != "\x01\x03\x02" || len(q.pending) != 1 {
+		t.Errorf("released %v with %d held, want [1 3 2] with 1 held", got, len(q.pending))
+	}
+
+	// the ones still held go back to the pool
+	q.stop(pool)
+	if len(q.pending) != 0 || len(pool.free) != 4 {
+		t.Errorf("after stop %d held and %d pooled, want 0 held and 4 pooled", len(q.pending), len(pool.free))
+	}
+	if q.timer.Stop() {
+		t.Error("timer still armed after stop")
+	}
+}
@@ -0,0 +1,184 @@
+package sim
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Sapper177/datagensim/pkg/engine"
+)
+
+// benchPayload returns a payload of engine driven floats, ints, a bool and a
+// string, unaligned fields among them, and a derived value. It is built
+// without the store, which the build path no longer needs.
+func benchPayload(b *testing.B) *payloadManager {
+	b.Helper()
+	defs := []struct{ id, typ, offset, size string }{
+		{"temp", "float64", "0", "64"},
+		{"volts", "float32", "64", "32"},
+		{"count", "int32", "96", "32"},
+		{"flags", "uint8", "128", "8"},
+		{"status", "int16", "136", "12"},
+		{"valid", "bool", "148", "1"},
+		{"label", "string", "152", "16"},
+		{"power", "float64", "280", "64"},
+	}
+	info := map[string]string{"min": "0", "max": "100", "step": "1", "frequency": "10"}
+	dps := make(map[string]dataPoint, len(defs))
+	var engines []string
+	for _, d := range defs {
+		dp, err := buildDataPoint("100", d.id, map[string]string{"type": d.typ, "offset": d.offset, "size": d.size}, info)
+		if err != nil {
+			b.Fatal(err)
+		}
+		dps[d.id] = dp
+		if d.id != "power" {
+			engines = append(engines, d.id)
+		}
+	}
+	if err := dps["label"].setParam("type", "sin"); err != nil {
+		b.Fatal(err)
+	}
+	expr, err := engine.ParseExpr("volts * 2 + min(count, flags)")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	plan := &updatePlan{
+		dps:     dps,
+		order:   append(engines, "power"),
+		derived: map[string]*engine.Expr{"power": expr},
+	}
+	plan.resolve = plan.lookup
+	pm := &payloadManager{
+		key:    "100",
+		id:     100,
+		dpMap:  dps,
+		plan:   plan,
+		forced: map[string]string{"count": "42"},
+		sender: discardSender{},
+		cs: &PayloadChans{
+			writeChan: make(chan Packet, 8),
+			buffers:   &bufferPool{},
+			delayed:   newDelayQueue(),
+			values:    newValueHub(),
+			monitor:   newPayloadMonitor(),
+			logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+	}
+	pm.layout()
+	return pm
+}
+
+func BenchmarkBuildPayload(b *testing.B) {
+	pm := benchPayload(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if err := pm.buildPayload(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAssemblePayload(b *testing.B) {
+	pm := benchPayload(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if err := pm.buildPayload(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := pm.assemblePayload(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// discardSender accepts every payload
+type discardSender struct{}
+
+func (discardSender) Write(payload []byte) (int, error) { return len(payload), nil }
+func (discardSender) Close() error                      { return nil }
+
+// BenchmarkEmit measures a tick from build to send, the queued packet in a
+// pooled buffer, and fails if a tick allocates
+func BenchmarkEmit(b *testing.B) {
+	for _, bc := range []struct {
+		name   string
+		faults map[string]string
+	}{
+		{"plain", nil},
+		{"faults", map[string]string{
+			"loss": "0.1", "duplicate": "0.2", "reorder": "0.2", "delay": "1ms", "jitter": "1ms",
+			"bitflip_data": "0.1", "truncate": "0.1", "seed": "7",
+		}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			pm := benchPayload(b)
+			cfg, err := parseFaults(bc.faults)
+			if err != nil {
+				b.Fatal(err)
+			}
+			pm.setFaults(cfg)
+			now := time.Now()
+			tick := func() {
+				if err := pm.emit(now, 0); err != nil {
+					b.Fatal(err)
+				}
+				// delayed packets fall due at once
+				now = now.Add(10 * time.Millisecond)
+				pm.cs.delayed.release(now, func(pkt Packet) {
+					if err := pm.queue(pkt); err != nil {
+						b.Fatal(err)
+					}
+				})
+				for len(pm.cs.writeChan) > 0 {
+					pm.send(<-pm.cs.writeChan)
+				}
+			}
+			if allocs := testing.AllocsPerRun(1000, tick); allocs != 0 {
+				b.Fatalf("%v allocations per tick", allocs)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				tick()
+			}
+		})
+	}
+}
+
+func BenchmarkHeader(b *testing.B) {
+	pm := benchPayload(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if err := pm.getHeader(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteBits(b *testing.B) {
+	buf := make([]byte, 16)
+	for _, bc := range []struct {
+		name         string
+		offset, size int
+	}{
+		{"aligned64", 0, 64},
+		{"aligned16", 8, 16},
+		{"unaligned12", 3, 12},
+		{"bit", 77, 1},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := range b.N {
+				if err := writeBits(buf, bc.offset, uint64(i), bc.size); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
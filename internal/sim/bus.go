@@ -1,7 +1,325 @@
 package sim
 
-type Bus struct {
-    Name      string
-    Interface string // e.g., "eth0"
-    IP        string // e.g., "192.168.1.10"
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Sapper177/datagensim/api"
+	"github.com/Sapper177/datagensim/pkg/config"
+	"github.com/Sapper177/datagensim/pkg/database"
+	"github.com/Sapper177/datagensim/pkg/pktgen"
+)
+
+// bus is a simulated bus. Its payload managers, receive path and command
+// handling run under their own context, derived from the simulation context.
+type bus struct {
+	name   string
+	cfg    *config.Config
+	ctx    context.Context
+	cancel context.CancelFunc
+	ctl    *controller
+	sender pktgen.Sender
+	logger *slog.Logger // labels every record with the bus name
+
+	// what payloads started after the bus need
+	sup      *supervisor
+	db       *database.RedisClient
+	monitor  *payloadMonitor
+	values   *valueHub
+	sched    *scheduler
+	shared   *sharedState
+	infoChan chan<- packetInfo
+	routes   *routeTable
+
+	mu    sync.Mutex                    // serializes reloads
+	stops map[string]context.CancelFunc // payload id -> stops its manager
+}
+
+// reloadResult is what a reload did to the payloads of a bus
+type reloadResult struct {
+	added, removed, changed []string
+}
+
+// startBus loads the payloads of the bus configured by cfg and starts them,
+// along with the receive path and command handling of the bus, under sup.
+func startBus(sup *supervisor, cfg *config.Config, db *database.RedisClient, monitor *payloadMonitor, values *valueHub, sched *scheduler, infoChan chan<- packetInfo, o *options) (*bus, error) {
+	b := &bus{
+		name:     cfg.BusName,
+		cfg:      cfg,
+		logger:   slog.With("bus", cfg.BusName),
+		sup:      sup,
+		db:       db,
+		monitor:  monitor,
+		values:   values,
+		sched:    sched,
+		infoChan: infoChan,
+		routes:   newRouteTable(),
+		stops:    make(map[string]context.CancelFunc),
+	}
+
+	// Get payload configs from database
+	payloadIds, err := db.GetPayloads(cfg.BusName)
+	if err != nil {
+		return nil, fmt.Errorf("did not find payload ids: %w", err)
+	}
+
+	// derived data points may depend on each other across payloads
+	if err := checkDerived(db, payloadIds); err != nil {
+		return nil, fmt.Errorf("invalid derived data points: %w", err)
+	}
+
+	b.shared = newSharedState(db, cfg.BusName, sched, b.logger)
+	if err := b.shared.load(payloadIds); err != nil {
+		return nil, fmt.Errorf("unable to set up shared data points: %w", err)
+	}
+
+	// Create the bus transport shared by all payloads
+	b.sender, err = newSender(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create sender: %w", err)
+	}
+	if o.capture != nil {
+		b.sender = &captureSender{Sender: b.sender, bus: cfg.BusName, capture: o.capture}
+	}
+
+	// open the receive path and command port before starting anything
+	receiver, err := newReceiver(cfg)
+	if err != nil {
+		b.close()
+		return nil, fmt.Errorf("unable to create receiver: %w", err)
+	}
+	b.ctl = newController(b.shared)
+	cmdServer, err := newCommandServer(cfg, db, b.ctl, monitor, b.logger)
+	if err != nil {
+		b.close()
+		if receiver != nil {
+			receiver.Close()
+		}
+		return nil, fmt.Errorf("unable to start command handling: %w", err)
+	}
+
+	// initialize payload routines
+	b.ctx, b.cancel = context.WithCancel(sup.ctx)
+	ctx := &b.ctx
+	context.AfterFunc(b.ctx, b.shared.stop)
+	sup.run("bus "+b.name+" store", func() error {
+		b.syncStore(ctx)
+		return nil
+	})
+	b.mu.Lock()
+	for _, id := range payloadIds {
+		if err := b.startPayload(id, len(payloadIds)); err != nil {
+			b.mu.Unlock()
+			return b, err
+		}
+	}
+	b.mu.Unlock()
+
+	// initialize receive path
+	if receiver != nil {
+		sup.run("bus "+b.name+" receiver", func() error {
+			listen(ctx, cfg.BusName, receiver, b.routes, b.logger, infoChan)
+			return nil
+		})
+	}
+
+	// initialize command handling
+	if cmdServer != nil {
+		sup.run("bus "+b.name+" commands", func() error {
+			cmdServer.serve(ctx)
+			return nil
+		})
+	}
+	return b, nil
+}
+
+// startPayload spawns the manager of payload id under the supervisor. n is
+// the number of payloads on the bus, which sizes the queues. Callers hold b.mu.
+func (b *bus) startPayload(id string, n int) error {
+	// get payload info
+	logger := b.logger.With("payload", id)
+	pInfo, err := b.db.GetPayloadInfo(id)
+	if err != nil {
+		return fmt.Errorf("no info found for payload %s: %w", id, err)
+	}
+	hz, _, err := parseRate(pInfo["frequency"])
+	if err != nil {
+		return fmt.Errorf("invalid frequency for payload %s: %w", id, err)
+	}
+	dataIds, err := b.db.GetPayloadData(id)
+	if err != nil {
+		return fmt.Errorf("no data found for payload %s: %w", id, err)
+	}
+
+	// Create channels for i/o, the manager ticks on the scheduler
+	cs := &PayloadChans{
+		writeChan: make(chan Packet, 3*n),
+		readChan:  make(chan Packet, n),
+		ctlChan:   make(chan controlMsg),
+		sched:     b.sched,
+		buffers:   &bufferPool{},
+		values:    b.values,
+		monitor:   b.monitor,
+		shared:    b.shared,
+		logger:    logger,
+	}
+	ctx, cancel := context.WithCancel(b.ctx)
+	b.stops[id] = cancel
+	b.ctl.add(id, cs.ctlChan, ctx.Done(), dataIds)
+	if payId, err := strconv.ParseUint(id, 0, 32); err == nil {
+		b.routes.set(uint32(payId), id, cs.readChan)
+	}
+	b.monitor.setConfiguredRate(b.name, id, hz)
+
+	// spawn go routine for the payload
+	b.sup.run("payload "+id, func() error {
+		return manager(&ctx, b.cfg, cs, id, pInfo["packet_type"], b.sender, b.infoChan)
+	})
+	return nil
+}
+
+// stopPayload stops the manager of payload id once it has sent the packets
+// it has queued. Callers hold b.mu.
+func (b *bus) stopPayload(id string) {
+	b.ctl.remove(id)
+	if payId, err := strconv.ParseUint(id, 0, 32); err == nil {
+		b.routes.delete(uint32(payId))
+	}
+	if stop, ok := b.stops[id]; ok {
+		stop()
+		delete(b.stops, id)
+	}
+}
+
+// reload re-reads the payloads of the bus from the store and applies the
+// difference: new payloads start, removed ones stop and changed ones are
+// rebuilt in place. Definitions that would not start are refused before
+// anything changes.
+func (b *bus) reload(ctx context.Context) (reloadResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var res reloadResult
+	payloadIds, err := b.db.GetPayloads(b.name)
+	if err != nil {
+		return res, err
+	}
+	if err := checkDerived(b.db, payloadIds); err != nil {
+		return res, fmt.Errorf("%w: %s", api.ErrInvalid, err)
+	}
+	for _, id := range payloadIds {
+		if _, _, err := loadPayload(b.db, b.cfg, id); err != nil {
+			return res, fmt.Errorf("%w: %s", api.ErrInvalid, err)
+		}
+	}
+	if err := b.shared.load(payloadIds); err != nil {
+		return res, fmt.Errorf("reloading shared data points: %w", err)
+	}
+
+	added, removed, kept := diffPayloads(b.ctl.ids(), payloadIds)
+	for _, id := range removed {
+		b.stopPayload(id)
+		res.removed = append(res.removed, id)
+	}
+	for _, id := range added {
+		if err := b.startPayload(id, len(payloadIds)); err != nil {
+			return res, err
+		}
+		res.added = append(res.added, id)
+	}
+	for _, id := range kept {
+		var changed bool
+		var dataIds []string
+		err := b.ctl.call(ctx, id, func(pm *payloadManager) error {
+			var err error
+			if changed, err = pm.reload(); err != nil || !changed {
+				return err
+			}
+			for dataId := range pm.dpMap {
+				dataIds = append(dataIds, dataId)
+			}
+			return nil
+		})
+		if err != nil {
+			return res, fmt.Errorf("reloading payload %s: %w", id, err)
+		}
+		if changed {
+			b.ctl.rebind(id, dataIds)
+			res.changed = append(res.changed, id)
+		}
+	}
+	b.logger.Info("reloaded payloads", "added", res.added, "removed", res.removed, "changed", res.changed)
+	return res, nil
+}
+
+// diffPayloads splits the payload ids of a reload into those to start, those
+// to stop and those running on, each in the order given
+func diffPayloads(running, stored []string) (added, removed, kept []string) {
+	for _, id := range running {
+		if !slices.Contains(stored, id) {
+			removed = append(removed, id)
+		}
+	}
+	for _, id := range stored {
+		if slices.Contains(running, id) {
+			kept = append(kept, id)
+		} else {
+			added = append(added, id)
+		}
+	}
+	return added, removed, kept
+}
+
+// close releases the transport of the bus once its payloads have stopped
+func (b *bus) close() {
+	if err := b.sender.Close(); err != nil {
+		b.logger.Error("closing sender", "err", err)
+	}
+}
+
+// syncStore exchanges the values of the data points of the bus with the store
+// every cfg.StoreSync until ctx is done. Payloads are built from the values their
+// data points hold, so ticks never wait on the store.
+func (b *bus) syncStore(ctx *context.Context) {
+	stored := newStoredValues(b.db)
+	ticker := time.NewTicker(b.cfg.StoreSync)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-(*ctx).Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := make(map[string]string)
+		b.shared.values(current)
+		for _, id := range b.ctl.ids() {
+			err := b.ctl.call(*ctx, id, func(pm *payloadManager) error {
+				pm.values(current)
+				return nil
+			})
+			if err != nil && (*ctx).Err() == nil {
+				// a payload stopped or removed by a reload since ids
+				b.logger.Debug("reading data values", "payload", id, "err", err)
+			}
+		}
+		changed, err := stored.sync(current)
+		if err != nil {
+			b.logger.Error("storing data values", "err", err)
+		}
+		if err := b.shared.external.refresh(b.db, current); err != nil && (*ctx).Err() == nil {
+			b.logger.Error("reading referenced data values", "err", err)
+		}
+		for id, value := range changed {
+			err := b.ctl.callData(*ctx, id, func(dp dataPoint) error { return dp.set(value) })
+			if err != nil && (*ctx).Err() == nil {
+				b.logger.Warn("taking over stored value", "data", id, "err", err)
+			}
+		}
+	}
 }
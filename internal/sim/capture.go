@@ -0,0 +1,334 @@
+package sim
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Sapper177/datagensim/pkg/config"
+	"github.com/Sapper177/datagensim/pkg/pktgen"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// linkTypeUser0 carries serial payloads, which have no IP headers
+const linkTypeUser0 layers.LinkType = 147
+
+// pcapng section header block type, the first bytes of a pcapng file
+var pcapngMagic = []byte{0x0a, 0x0d, 0x0d, 0x0a}
+
+// Capture writes the payloads sent by the simulation to a pcapng file, with
+// one capture interface per bus named after it. Payloads of IP buses are
+// wrapped in IP and UDP headers built from the bus addresses, serial
+// payloads are written as is, before framing.
+type Capture struct {
+	mu      sync.Mutex
+	w       *pcapgo.NgWriter
+	buses   map[string]captureBus
+	packets int
+	err     error // first write error
+	closed  bool
+}
+
+type captureBus struct {
+	index int
+	cfg   *config.Config
+}
+
+// NewCapture starts a capture of every bus of cfg on w.
+func NewCapture(w io.Writer, cfg *config.Config) (*Capture, error) {
+	c := &Capture{buses: make(map[string]captureBus)}
+	for i, busCfg := range cfg.BusConfigs() {
+		intf := pcapgo.NgInterface{
+			Name:                busCfg.BusName,
+			Description:         "datagensim bus " + busCfg.BusName,
+			LinkType:            layers.LinkTypeRaw,
+			TimestampResolution: 9,
+		}
+		if busCfg.BusType == "serial" {
+			intf.LinkType = linkTypeUser0
+		}
+		var err error
+		if i == 0 {
+			c.w, err = pcapgo.NewNgWriterInterface(w, intf, pcapgo.DefaultNgWriterOptions)
+		} else {
+			_, err = c.w.AddInterface(intf)
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.buses[busCfg.BusName] = captureBus{index: i, cfg: busCfg}
+	}
+	return c, nil
+}
+
+// write adds a payload sent on bus at t
+func (c *Capture) write(bus string, payload []byte, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.buses[bus]
+	if !ok || c.closed || c.err != nil {
+		return
+	}
+	data := payload
+	if b.cfg.BusType != "serial" {
+		var err error
+		if data, err = encapsulate(b.cfg, payload); err != nil {
+			c.err = fmt.Errorf("bus %s: %w", bus, err)
+			return
+		}
+	}
+	ci := gopacket.CaptureInfo{Timestamp: t, CaptureLength: len(data), Length: len(data), InterfaceIndex: b.index}
+	if err := c.w.WritePacket(ci, data); err != nil {
+		c.err = err
+		return
+	}
+	c.packets++
+}
+
+// Packets returns the number of payloads captured so far.
+func (c *Capture) Packets() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.packets
+}
+
+// Close flushes the capture and reports the first error writing it. Later
+// payloads are dropped.
+func (c *Capture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if err := c.w.Flush(); err != nil && c.err == nil {
+		c.err = err
+	}
+	return c.err
+}
+
+// captureSender copies every payload written to a bus sender to a capture
+type captureSender struct {
+	pktgen.Sender
+	bus     string
+	capture *Capture
+}
+
+func (s *captureSender) Write(payload []byte) (int, error) {
+	n, err := s.Sender.Write(payload)
+	if err == nil {
+		s.capture.write(s.bus, payload, time.Now())
+	}
+	return n, err
+}
+
+// encapsulate wraps a payload in the IP and UDP headers of the bus
+func encapsulate(cfg *config.Config, payload []byte) ([]byte, error) {
+	udp := &layers.UDP{SrcPort: layers.UDPPort(cfg.SrcPort), DstPort: layers.UDPPort(cfg.DestPort)}
+	src := cfg.SrcHost
+	var ip gopacket.NetworkLayer
+	if version, _ := cfg.IPVersion(); version == 6 {
+		if src == nil {
+			src = net.IPv6unspecified
+		}
+		ip = &layers.IPv6{Version: 6, HopLimit: 64, NextHeader: layers.IPProtocolUDP, SrcIP: src, DstIP: cfg.DestHost}
+	} else {
+		if src == nil {
+			src = net.IPv4zero
+		}
+		ip = &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: src.To4(), DstIP: cfg.DestHost.To4()}
+	}
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		return nil, err
+	}
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	err := gopacket.SerializeLayers(buf, opts, ip.(gopacket.SerializableLayer), udp, gopacket.Payload(payload))
+	return buf.Bytes(), err
+}
+
+// decapsulate returns the UDP payload of a captured packet, or false when
+// it carries none
+func decapsulate(linkType layers.LinkType, data []byte) ([]byte, bool) {
+	var first gopacket.LayerType
+	switch linkType {
+	case linkTypeUser0:
+		return data, true
+	case layers.LinkTypeEthernet:
+		first = layers.LayerTypeEthernet
+	case layers.LinkTypeRaw, layers.LinkTypeIPv4, layers.LinkTypeIPv6:
+		if len(data) == 0 {
+			return nil, false
+		}
+		first = layers.LayerTypeIPv4
+		if data[0]>>4 == 6 {
+			first = layers.LayerTypeIPv6
+		}
+	default:
+		return nil, false
+	}
+	pkt := gopacket.NewPacket(data, first, gopacket.NoCopy)
+	udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok {
+		return nil, false
+	}
+	return udp.Payload, true
+}
+
+// ReplayOptions control Replay.
+type ReplayOptions struct {
+	Bus   string  // send every payload on this bus instead of the bus it was captured on
+	Speed float64 // playback speed, 0 for 1
+	Loop  bool    // start over at the end of the capture
+}
+
+// captureReader reads pcap and pcapng files alike
+type captureReader interface {
+	ReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+}
+
+// Replay sends the payloads of a pcap or pcapng capture on the configured
+// buses with their original timing. Packets of a pcapng interface go to the
+// bus of the same name; with a single bus, or opts.Bus, every packet goes to
+// that bus. Packets without a UDP payload are skipped. Replay returns the
+// number of payloads sent once the capture is done or ctx is.
+func Replay(ctx *context.Context, cfg *config.Config, path string, opts ReplayOptions) (int, error) {
+	speed := opts.Speed
+	if speed == 0 {
+		speed = 1
+	}
+	if speed < 0 {
+		return 0, fmt.Errorf("invalid speed %v", opts.Speed)
+	}
+	busCfgs := make(map[string]*config.Config)
+	for _, busCfg := range cfg.BusConfigs() {
+		busCfgs[busCfg.BusName] = busCfg
+	}
+	if opts.Bus != "" && busCfgs[opts.Bus] == nil {
+		return 0, fmt.Errorf("bus %s is not configured", opts.Bus)
+	}
+	// bus for packets of an interface name
+	target := func(name string) (*config.Config, error) {
+		switch {
+		case opts.Bus != "":
+			return busCfgs[opts.Bus], nil
+		case busCfgs[name] != nil:
+			return busCfgs[name], nil
+		case len(busCfgs) == 1:
+			return cfg.BusConfigs()[0], nil
+		}
+		return nil, fmt.Errorf("no bus for capture interface %q, select one", name)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	senders := make(map[string]pktgen.Sender)
+	defer func() {
+		for _, s := range senders {
+			s.Close()
+		}
+	}()
+
+	sent, skipped := 0, 0
+	for {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return sent, err
+		}
+		r, interfaceOf, err := openCapture(f)
+		if err != nil {
+			return sent, fmt.Errorf("%s: %w", path, err)
+		}
+
+		var first time.Time
+		start := time.Now()
+		for {
+			data, ci, err := r.ReadPacketData()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return sent, fmt.Errorf("%s: %w", path, err)
+			}
+			name, linkType := interfaceOf(ci.InterfaceIndex)
+			payload, ok := decapsulate(linkType, data)
+			if !ok {
+				skipped++
+				continue
+			}
+			busCfg, err := target(name)
+			if err != nil {
+				return sent, err
+			}
+			sender, ok := senders[busCfg.BusName]
+			if !ok {
+				if sender, err = newSender(busCfg); err != nil {
+					return sent, fmt.Errorf("bus %s: %w", busCfg.BusName, err)
+				}
+				senders[busCfg.BusName] = sender
+			}
+
+			if first.IsZero() {
+				first = ci.Timestamp
+			}
+			due := start.Add(time.Duration(float64(ci.Timestamp.Sub(first)) / speed))
+			timer := time.NewTimer(time.Until(due))
+			select {
+			case <-(*ctx).Done():
+				timer.Stop()
+				return sent, nil
+			case <-timer.C:
+			}
+			if _, err := sender.Write(payload); err != nil {
+				slog.Error("replaying payload", "bus", busCfg.BusName, "err", err)
+				continue
+			}
+			sent++
+		}
+		if !opts.Loop || sent == 0 {
+			break
+		}
+	}
+	if skipped > 0 {
+		slog.Info("skipped packets without a UDP payload", "capture", path, "packets", skipped)
+	}
+	return sent, nil
+}
+
+// openCapture returns a reader for a pcap or pcapng file and a function
+// mapping a packet interface index to its name and link type
+func openCapture(f io.Reader) (captureReader, func(int) (string, layers.LinkType), error) {
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(magic, pcapngMagic) {
+		r, err := pcapgo.NewNgReader(br, pcapgo.DefaultNgReaderOptions)
+		if err != nil {
+			return nil, nil, err
+		}
+		return r, func(i int) (string, layers.LinkType) {
+			intf, err := r.Interface(i)
+			if err != nil {
+				return "", r.LinkType()
+			}
+			return intf.Name, intf.LinkType
+		}, nil
+	}
+	r, err := pcapgo.NewReader(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, func(int) (string, layers.LinkType) { return "", r.LinkType() }, nil
+}
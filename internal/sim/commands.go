@@ -0,0 +1,435 @@
+package sim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Sapper177/datagensim/ext/definitions"
+	"github.com/Sapper177/datagensim/pkg/config"
+	"github.com/Sapper177/datagensim/pkg/database"
+	"github.com/Sapper177/datagensim/pkg/pktgen"
+)
+
+const cmdLogMax = 1000 // entries kept in <bus>_cmd_log
+
+// errNotCommand marks a packet without a command header, which gets no response
+var errNotCommand = errors.New("not a command packet")
+
+// commandArg describes one argument of a command and the data point it drives
+type commandArg struct {
+	id     string
+	offset int // bit offset in the argument bytes
+	size   int // in bits, characters for strings
+	dtype  dtype
+	target string // data id
+	field  string // value or an engine parameter
+	min    *float64
+	max    *float64
+}
+
+// commandDef is a command dictionary entry
+type commandDef struct {
+	opcode   uint16
+	name     string
+	response string // payload id emitted after the command, optional
+	args     []commandArg
+	argLen   int // argument bytes
+}
+
+// commandEntry is a command log record, stored in Redis as JSON
+type commandEntry struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Opcode   uint16    `json:"opcode"`
+	Name     string    `json:"name"`
+	Sequence uint16    `json:"sequence"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// loadCommands reads the command dictionary of a bus from the store. The
+// response of a command must be a payload of the bus.
+func loadCommands(db *database.RedisClient, bus string) (map[uint16]*commandDef, error) {
+	opcodes, err := db.GetCommands(bus)
+	if err != nil {
+		return nil, err
+	}
+	payloads, err := db.GetPayloads(bus)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := make(map[uint16]*commandDef, len(opcodes))
+	for _, op := range opcodes {
+		opcode, err := strconv.ParseUint(op, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid opcode %s: %w", op, err)
+		}
+		info, err := db.GetCommandInfo(bus, op)
+		if err != nil {
+			return nil, err
+		}
+		def := &commandDef{
+			opcode:   uint16(opcode),
+			name:     info["name"],
+			response: info["response"],
+		}
+		if def.response != "" && !slices.Contains(payloads, def.response) {
+			return nil, fmt.Errorf("command %s (%s): response payload %s is not on bus %s", op, def.name, def.response, bus)
+		}
+
+		argIds, err := db.GetCommandArgs(bus, op)
+		if err != nil {
+			argIds = nil // commands without arguments have no list
+		}
+		bits := 0
+		for _, argId := range argIds {
+			a, err := db.GetData(argId)
+			if err != nil {
+				return nil, err
+			}
+			arg, err := newCommandArg(argId, a)
+			if err != nil {
+				return nil, fmt.Errorf("command %s (%s): %w", op, def.name, err)
+			}
+			width := arg.size
+			if arg.dtype == D_STRING || arg.dtype == D_BYTES {
+				width *= 8
+			}
+			if end := arg.offset + width; end > bits {
+				bits = end
+			}
+			def.args = append(def.args, arg)
+		}
+		def.argLen = (bits + 7) / 8
+		defs[def.opcode] = def
+	}
+	return defs, nil
+}
+
+// newCommandArg parses an argument definition hash
+func newCommandArg(id string, a map[string]string) (commandArg, error) {
+	offset, err := strconv.Atoi(a["offset"])
+	if err != nil {
+		return commandArg{}, fmt.Errorf("argument %s offset: %w", id, err)
+	}
+	size, err := strconv.Atoi(a["size"])
+	if err != nil {
+		return commandArg{}, fmt.Errorf("argument %s size: %w", id, err)
+	}
+	if a["target"] == "" {
+		return commandArg{}, fmt.Errorf("argument %s has no target", id)
+	}
+	arg := commandArg{
+		id:     id,
+		offset: offset,
+		size:   size,
+		dtype:  selectDtype(a["type"]),
+		target: a["target"],
+		field:  a["field"],
+	}
+	if arg.field == "" {
+		arg.field = "value"
+	}
+	for _, lim := range []struct {
+		key string
+		dst **float64
+	}{{"min", &arg.min}, {"max", &arg.max}} {
+		if v, ok := a[lim.key]; ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return commandArg{}, fmt.Errorf("argument %s %s: %w", id, lim.key, err)
+			}
+			*lim.dst = &f
+		}
+	}
+	return arg, nil
+}
+
+// decode reads the argument from the argument bytes and checks its limits.
+// It returns the value formatted for the store.
+func (a *commandArg) decode(buf []byte) (string, error) {
+	if a.dtype == D_STRING || a.dtype == D_BYTES {
+		return readBitsStr(buf, a.offset, a.size)
+	}
+
+	raw, err := readBits(buf, a.offset, a.size)
+	if err != nil {
+		return "", err
+	}
+	var num float64
+	var str string
+	switch a.dtype {
+	case D_BOOL:
+		num, str = 0, "0"
+		if raw != 0 {
+			num, str = 1, "1"
+		}
+	case D_UINT, D_UINT8, D_UINT16, D_UINT32, D_UINT64:
+		num, str = float64(raw), strconv.FormatUint(raw, 10)
+	case D_FLOAT32:
+		num = float64(math.Float32frombits(uint32(raw)))
+		str = strconv.FormatFloat(num, 'f', -1, 32)
+	case D_FLOAT64:
+		num = math.Float64frombits(raw)
+		str = strconv.FormatFloat(num, 'f', -1, 64)
+	default:
+		v := signExtend(raw, a.size)
+		num, str = float64(v), strconv.FormatInt(v, 10)
+	}
+
+	if a.min != nil && num < *a.min {
+		return "", fmt.Errorf("argument %s value %s below min %v", a.id, str, *a.min)
+	}
+	if a.max != nil && num > *a.max {
+		return "", fmt.Errorf("argument %s value %s above max %v", a.id, str, *a.max)
+	}
+	return str, nil
+}
+
+// commandServer receives command packets for a bus, applies them and replies
+type commandServer struct {
+	bus    string
+	defs   map[uint16]*commandDef
+	conn   *pktgen.UDPReceiver
+	ctl    *controller
+	db     *database.RedisClient
+	mon    *payloadMonitor
+	logger *slog.Logger
+}
+
+// newCommandServer loads the command dictionary and opens the command port,
+// or returns nil when commands are disabled.
+func newCommandServer(cfg *config.Config, db *database.RedisClient, ctl *controller, mon *payloadMonitor, logger *slog.Logger) (*commandServer, error) {
+	if cfg.CommandPort == 0 {
+		return nil, nil
+	}
+	defs, err := loadCommands(db, cfg.BusName)
+	if err != nil {
+		return nil, fmt.Errorf("loading command dictionary: %w", err)
+	}
+	var iface *net.Interface
+	if cfg.Interface.Name != "" {
+		iface = &cfg.Interface
+	}
+	conn, err := pktgen.NewUDPReceiver(cfg.ListenHost, cfg.CommandPort, iface)
+	if err != nil {
+		return nil, err
+	}
+	return &commandServer{
+		bus:    cfg.BusName,
+		defs:   defs,
+		conn:   conn,
+		ctl:    ctl,
+		db:     db,
+		mon:    mon,
+		logger: logger,
+	}, nil
+}
+
+// serve handles command packets until the context is cancelled
+func (s *commandServer) serve(ctx *context.Context) {
+	go func() {
+		<-(*ctx).Done()
+		s.conn.Close()
+	}()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if (*ctx).Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.Error("receiving command", "err", err)
+			continue
+		}
+
+		entry := commandEntry{Time: time.Now(), Source: addr.String()}
+		hdr, status, err := s.handle(*ctx, buf[:n])
+		if errors.Is(err, errNotCommand) {
+			// stray traffic on the port, not a command to log or answer
+			continue
+		}
+		entry.Opcode = hdr.Opcode
+		entry.Sequence = hdr.Sequence
+		entry.Status = definitions.StatusText(status)
+		if def, ok := s.defs[hdr.Opcode]; ok {
+			entry.Name = def.name
+		}
+		if err != nil {
+			entry.Error = err.Error()
+			s.logger.Warn("command failed", "opcode", hdr.Opcode, "command", entry.Name, "source", entry.Source, "err", err)
+		}
+
+		s.mon.addCommand(hdr.Opcode, entry.Name, entry.Status)
+		s.log(entry)
+		s.respond(hdr, status, addr)
+	}
+}
+
+// handle validates and executes a single command packet
+func (s *commandServer) handle(ctx context.Context, buf []byte) (definitions.CommandHeader, byte, error) {
+	hdr, err := definitions.ParseCommandHeader(buf)
+	if err != nil {
+		return hdr, definitions.StatusBadLength, fmt.Errorf("%w: %w", errNotCommand, err)
+	}
+	def, ok := s.defs[hdr.Opcode]
+	if !ok {
+		return hdr, definitions.StatusUnknownOpcode, fmt.Errorf("unknown opcode %d", hdr.Opcode)
+	}
+
+	body := definitions.CommandHeaderSize + int(hdr.Length)
+	fSize, _ := calcHeaderSize(definitions.Header(*definitions.NewUdpFooter(nil)))
+	if int(hdr.Length) != def.argLen || len(buf) != body+int(fSize) {
+		return hdr, definitions.StatusBadLength, fmt.Errorf("length %d, expected %d argument bytes", len(buf), def.argLen)
+	}
+	expected := make([]byte, fSize)
+	if _, err := writeElements(expected, definitions.NewUdpFooter(buf[:body]).Elements); err != nil {
+		return hdr, definitions.StatusFailed, err
+	}
+	if !bytes.Equal(expected, buf[body:]) {
+		return hdr, definitions.StatusBadChecksum, fmt.Errorf("checksum mismatch")
+	}
+
+	// decode and validate every argument, and the response, before applying
+	// any of them
+	if def.response != "" && !s.ctl.has(def.response) {
+		return hdr, definitions.StatusFailed, fmt.Errorf("response payload %s is not running", def.response)
+	}
+	args := buf[definitions.CommandHeaderSize:body]
+	values := make([]string, len(def.args))
+	for i := range def.args {
+		v, err := def.args[i].decode(args)
+		if err != nil {
+			return hdr, definitions.StatusInvalidArgument, err
+		}
+		values[i] = v
+	}
+	if err := s.stage(def, values); err != nil {
+		return hdr, definitions.StatusInvalidArgument, err
+	}
+
+	if err := s.execute(ctx, def, values); err != nil {
+		return hdr, definitions.StatusFailed, err
+	}
+	return hdr, definitions.StatusOk, nil
+}
+
+// stage checks every argument value against a copy of its target data point
+// loaded from the store, so that a command applies all of its arguments or
+// none of them. Arguments with the same target see the earlier ones.
+func (s *commandServer) stage(def *commandDef, values []string) error {
+	staged := make(map[string]dataPoint) // target -> copy
+	for i, arg := range def.args {
+		owners := s.ctl.ownersOf(arg.target)
+		if len(owners) == 0 {
+			if arg.field != "value" {
+				return fmt.Errorf("setting %s %s: data point %s is not in any running payload", arg.target, arg.field, arg.target)
+			}
+			continue // only stored
+		}
+		dp, ok := staged[arg.target]
+		if !ok {
+			var err error
+			if dp, err = stagedDataPoint(s.db, owners[0], arg.target); err != nil {
+				return err
+			}
+			staged[arg.target] = dp
+		}
+		var err error
+		if arg.field == "value" {
+			err = dp.set(values[i])
+		} else {
+			err = dp.setParam(arg.field, values[i])
+		}
+		if err != nil {
+			return fmt.Errorf("setting %s %s: %w", arg.target, arg.field, err)
+		}
+	}
+	return nil
+}
+
+// execute applies the staged argument values to their target data points,
+// then sends the response. handle has validated them all.
+func (s *commandServer) execute(ctx context.Context, def *commandDef, values []string) error {
+	for i, arg := range def.args {
+		if arg.field == "value" {
+			if err := s.db.UpdateData(arg.target, map[string]string{"value": values[i]}); err != nil {
+				return err
+			}
+			// running data points take it now rather than at the next store
+			// sync, so that the response carries it
+			if len(s.ctl.ownersOf(arg.target)) == 0 {
+				continue
+			}
+			err := s.ctl.callData(ctx, arg.target, func(dp dataPoint) error {
+				return dp.set(values[i])
+			})
+			if err != nil {
+				return fmt.Errorf("setting %s value: %w", arg.target, err)
+			}
+			continue
+		}
+		err := s.ctl.callData(ctx, arg.target, func(dp dataPoint) error {
+			return dp.setParam(arg.field, values[i])
+		})
+		if err != nil {
+			return fmt.Errorf("setting %s %s: %w", arg.target, arg.field, err)
+		}
+		if err := s.db.UpdateDataInfo(arg.target, map[string]string{arg.field: values[i]}); err != nil {
+			return err
+		}
+	}
+
+	if def.response != "" {
+		return s.ctl.call(ctx, def.response, func(pm *payloadManager) error {
+			return pm.sendNow(1)
+		})
+	}
+	return nil
+}
+
+// respond sends the acknowledgement for a command back to its source
+func (s *commandServer) respond(hdr definitions.CommandHeader, status byte, addr *net.UDPAddr) {
+	header := definitions.NewResponseHeader(hdr.Opcode, hdr.Sequence, status)
+	hSize, err := calcHeaderSize(*header)
+	if err != nil {
+		s.logger.Error("sizing command response", "err", err)
+		return
+	}
+	fSize, _ := calcHeaderSize(definitions.Header(*definitions.NewUdpFooter(nil)))
+	resp := make([]byte, int(hSize)+int(fSize))
+	if _, err := writeElements(resp, header.Elements); err != nil {
+		s.logger.Error("building command response", "err", err)
+		return
+	}
+	if _, err := writeElements(resp[hSize:], definitions.NewUdpFooter(resp[:hSize]).Elements); err != nil {
+		s.logger.Error("building command response", "err", err)
+		return
+	}
+	if _, err := s.conn.WriteTo(resp, addr); err != nil {
+		s.logger.Error("sending command response", "addr", addr, "err", err)
+	}
+}
+
+// log appends an entry to the bus command log in the store
+func (s *commandServer) log(entry commandEntry) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		s.logger.Error("encoding command log entry", "err", err)
+		return
+	}
+	if err := s.db.LogCommand(s.bus, string(b), cmdLogMax); err != nil {
+		s.logger.Error("writing command log", "err", err)
+	}
+}
@@ -0,0 +1,203 @@
+package sim
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/Sapper177/datagensim/ext/definitions"
+)
+
+// limit returns a pointer to an argument limit
+func limit(v float64) *float64 { return &v }
+
+func TestNewCommandArg(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     map[string]string
+		want    commandArg
+		wantErr bool
+	}{
+		{
+			name: "value by default",
+			def:  map[string]string{"offset": "8", "size": "16", "type": "uint16", "target": "speed"},
+			want: commandArg{id: "a", offset: 8, size: 16, dtype: D_UINT16, target: "speed", field: "value"},
+		},
+		{
+			name: "engine parameter with limits",
+			def:  map[string]string{"offset": "0", "size": "32", "type": "float32", "target": "speed", "field": "max", "min": "-1.5", "max": "100"},
+			want: commandArg{id: "a", size: 32, dtype: D_FLOAT32, target: "speed", field: "max", min: limit(-1.5), max: limit(100)},
+		},
+		{name: "no offset", def: map[string]string{"size": "8", "target": "speed"}, wantErr: true},
+		{name: "bad size", def: map[string]string{"offset": "0", "size": "eight", "target": "speed"}, wantErr: true},
+		{name: "no target", def: map[string]string{"offset": "0", "size": "8"}, wantErr: true},
+		{name: "bad min", def: map[string]string{"offset": "0", "size": "8", "target": "speed", "min": "low"}, wantErr: true},
+		{name: "bad max", def: map[string]string{"offset": "0", "size": "8", "target": "speed", "max": "high"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newCommandArg("a", tt.def)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("newCommandArg succeeded with %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.id != tt.want.id || got.offset != tt.want.offset || got.size != tt.want.size ||
+				got.dtype != tt.want.dtype || got.target != tt.want.target || got.field != tt.want.field {
+				t.Errorf("newCommandArg = %+v, want %+v", got, tt.want)
+			}
+			for _, l := range []struct {
+				name      string
+				got, want *float64
+			}{{"min", got.min, tt.want.min}, {"max", got.max, tt.want.max}} {
+				if (l.got == nil) != (l.want == nil) || (l.got != nil && *l.got != *l.want) {
+					t.Errorf("%s = %v, want %v", l.name, l.got, l.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCommandArgDecode(t *testing.T) {
+	f32 := binary.BigEndian.AppendUint32(nil, math.Float32bits(2.5))
+	f64 := binary.BigEndian.AppendUint64(nil, math.Float64bits(-0.125))
+	tests := []struct {
+		name    string
+		arg     commandArg
+		buf     []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "uint8", arg: commandArg{size: 8, dtype: D_UINT8}, buf: []byte{200}, want: "200"},
+		{name: "uint16 at offset", arg: commandArg{offset: 8, size: 16, dtype: D_UINT16}, buf: []byte{0xff, 0x01, 0x02}, want: "258"},
+		{name: "int8 negative", arg: commandArg{size: 8, dtype: D_INT8}, buf: []byte{0xfe}, want: "-2"},
+		{name: "int16 positive", arg: commandArg{size: 16, dtype: D_INT16}, buf: []byte{0x7f, 0xff}, want: "32767"},
+		{name: "unaligned int4", arg: commandArg{offset: 4, size: 4, dtype: D_INT}, buf: []byte{0x0c}, want: "-4"},
+		{name: "bool set", arg: commandArg{offset: 7, size: 1, dtype: D_BOOL}, buf: []byte{0x01}, want: "1"},
+		{name: "bool clear", arg: commandArg{offset: 6, size: 1, dtype: D_BOOL}, buf: []byte{0x01}, want: "0"},
+		{name: "float32", arg: commandArg{size: 32, dtype: D_FLOAT32}, buf: f32, want: "2.5"},
+		{name: "float64", arg: commandArg{size: 64, dtype: D_FLOAT64}, buf: f64, want: "-0.125"},
+		{name: "string", arg: commandArg{offset: 8, size: 4, dtype: D_STRING}, buf: []byte{0, 'o', 'n', 0, 0}, want: "on"},
+		{name: "within limits", arg: commandArg{size: 8, dtype: D_INT8, min: limit(-5), max: limit(5)}, buf: []byte{0xfb}, want: "-5"},
+		{name: "below min", arg: commandArg{size: 8, dtype: D_INT8, min: limit(-5)}, buf: []byte{0xfa}, wantErr: true},
+		{name: "above max", arg: commandArg{size: 32, dtype: D_FLOAT32, max: limit(2)}, buf: f32, wantErr: true},
+		{name: "past the arguments", arg: commandArg{offset: 8, size: 16, dtype: D_UINT16}, buf: []byte{1, 2}, wantErr: true},
+		{name: "string past the arguments", arg: commandArg{size: 3, dtype: D_STRING}, buf: []byte{'a', 'b'}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.arg.id = "a"
+			got, err := tt.arg.decode(tt.buf)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decode = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("decode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleUnanswered(t *testing.T) {
+	known := make([]byte, definitions.CommandHeaderSize)
+	known[0] = definitions.CommandSync
+	tests := []struct {
+		name       string
+		buf        []byte
+		status     byte
+		unanswered bool
+	}{
+		{"empty", nil, definitions.StatusBadLength, true},
+		{"short", []byte{definitions.CommandSync, 0, 1}, definitions.StatusBadLength, true},
+		{"bad sync", make([]byte, definitions.CommandHeaderSize), definitions.StatusBadLength, true},
+		{"unknown opcode", known, definitions.StatusUnknownOpcode, false},
+	}
+	s := &commandServer{defs: map[uint16]*commandDef{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, status, err := s.handle(context.Background(), tt.buf)
+			if err == nil {
+				t.Fatal("handle succeeded, want an error")
+			}
+			if status != tt.status {
+				t.Errorf("status %s, want %s", definitions.StatusText(status), definitions.StatusText(tt.status))
+			}
+			if got := errors.Is(err, errNotCommand); got != tt.unanswered {
+				t.Errorf("unanswered = %v, want %v (%v)", got, tt.unanswered, err)
+			}
+		})
+	}
+}
+
+// commandPacket encodes a command with its arguments and checksum
+func commandPacket(t *testing.T, opcode uint16, args []byte) []byte {
+	t.Helper()
+	buf := make([]byte, definitions.CommandHeaderSize, definitions.CommandHeaderSize+len(args)+4)
+	if _, err := writeElements(buf, definitions.NewCommandHeader(opcode, 1, uint16(len(args))).Elements); err != nil {
+		t.Fatal(err)
+	}
+	buf = append(buf, args...)
+	footer := make([]byte, 4)
+	if _, err := writeElements(footer, definitions.NewUdpFooter(buf).Elements); err != nil {
+		t.Fatal(err)
+	}
+	return append(buf, footer...)
+}
+
+func TestHandleValidatesFirst(t *testing.T) {
+	// nothing may be applied, the server has no store to apply it to
+	tests := []struct {
+		name   string
+		def    *commandDef
+		args   []byte
+		status byte
+	}{
+		{
+			name:   "response not running",
+			def:    &commandDef{opcode: 1, response: "9"},
+			status: definitions.StatusFailed,
+		},
+		{
+			name: "argument out of range",
+			def: &commandDef{opcode: 1, argLen: 2, args: []commandArg{
+				{id: "a", size: 8, dtype: D_UINT8, target: "x", field: "value"},
+				{id: "b", offset: 8, size: 8, dtype: D_UINT8, target: "y", field: "value", max: limit(10)},
+			}},
+			args:   []byte{1, 11},
+			status: definitions.StatusInvalidArgument,
+		},
+		{
+			name: "parameter of a data point not running",
+			def: &commandDef{opcode: 1, argLen: 2, args: []commandArg{
+				{id: "a", size: 8, dtype: D_UINT8, target: "x", field: "value"},
+				{id: "b", offset: 8, size: 8, dtype: D_UINT8, target: "y", field: "step"},
+			}},
+			args:   []byte{1, 2},
+			status: definitions.StatusInvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &commandServer{defs: map[uint16]*commandDef{1: tt.def}, ctl: newController(nil)}
+			_, status, err := s.handle(context.Background(), commandPacket(t, 1, tt.args))
+			if err == nil {
+				t.Fatal("handle succeeded, want an error")
+			}
+			if status != tt.status {
+				t.Errorf("status %s, want %s (%v)", definitions.StatusText(status), definitions.StatusText(tt.status), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/Sapper177/datagensim/ext/definitions"
+	"github.com/Sapper177/datagensim/pkg/database"
+)
+
+// controlMsg runs fn on the goroutine of the payload manager that receives it,
+// under the lock its builds take, so that engines and buffers are never
+// touched concurrently.
+type controlMsg struct {
+	fn     func(pm *payloadManager) error
+	result chan error
+}
+
+// controller routes control requests to running payload managers
+type controller struct {
+	mu       sync.RWMutex
+	payloads map[string]managerHandle // payload id -> its manager
+	owners   map[string][]string      // data id -> payload ids containing it
+	shared   *sharedState             // bus-level data points
+}
+
+// managerHandle reaches a payload manager
+type managerHandle struct {
+	ctlChan chan<- controlMsg
+	done    <-chan struct{} // closed once the manager stops
+}
+
+// errStopped is returned by calls to a payload that has stopped
+var errStopped = errors.New("payload stopped")
+
+func newController(shared *sharedState) *controller {
+	return &controller{
+		payloads: make(map[string]managerHandle),
+		owners:   make(map[string][]string),
+		shared:   shared,
+	}
+}
+
+// add registers a payload manager's control channel and the data ids it owns
+func (c *controller) add(payloadId string, ctlChan chan<- controlMsg, done <-chan struct{}, dataIds []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.payloads[payloadId] = managerHandle{ctlChan: ctlChan, done: done}
+	for _, dataId := range dataIds {
+		c.owners[dataId] = append(c.owners[dataId], payloadId)
+	}
+}
+
+// remove unregisters a payload that no longer runs
+func (c *controller) remove(payloadId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.payloads, payloadId)
+	c.unbind(payloadId)
+}
+
+// rebind replaces the data ids owned by payloadId, e.g. after a reload
+func (c *controller) rebind(payloadId string, dataIds []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unbind(payloadId)
+	for _, dataId := range dataIds {
+		c.owners[dataId] = append(c.owners[dataId], payloadId)
+	}
+}
+
+// unbind drops payloadId from the owners of every data id. Callers hold c.mu.
+func (c *controller) unbind(payloadId string) {
+	for dataId, owners := range c.owners {
+		owners = slices.DeleteFunc(owners, func(id string) bool { return id == payloadId })
+		if len(owners) == 0 {
+			delete(c.owners, dataId)
+		} else {
+			c.owners[dataId] = owners
+		}
+	}
+}
+
+// call runs fn on the manager of payloadId and waits for its result
+func (c *controller) call(ctx context.Context, payloadId string, fn func(pm *payloadManager) error) error {
+	c.mu.RLock()
+	h, ok := c.payloads[payloadId]
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown payload: %s", payloadId)
+	}
+
+	msg := controlMsg{fn: fn, result: make(chan error, 1)}
+	select {
+	case h.ctlChan <- msg:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-h.done:
+		return errStopped
+	}
+	select {
+	case err := <-msg.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ids returns the ids of all registered payloads in sorted order
+func (c *controller) ids() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ids := make([]string, 0, len(c.payloads))
+	for id := range c.payloads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// has reports whether payloadId is registered
+func (c *controller) has(payloadId string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.payloads[payloadId]
+	return ok
+}
+
+// ownersOf returns the ids of the payloads containing dataId
+func (c *controller) ownersOf(dataId string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.owners[dataId]...)
+}
+
+// callData runs fn on the data point dataId in every payload that contains
+// it, and on the bus-level data point if it is shared
+func (c *controller) callData(ctx context.Context, dataId string, fn func(dp dataPoint) error) error {
+	payloadIds := c.ownersOf(dataId)
+	if len(payloadIds) == 0 {
+		return fmt.Errorf("data point %s is not in any running payload", dataId)
+	}
+	if err := c.shared.callData(dataId, fn); err != nil {
+		return err
+	}
+
+	for _, payloadId := range payloadIds {
+		err := c.call(ctx, payloadId, func(pm *payloadManager) error {
+			dp, ok := pm.dpMap[dataId]
+			if !ok {
+				return fmt.Errorf("data point %s not found in payload %s", dataId, payloadId)
+			}
+			return fn(dp)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stagedDataPoint loads a copy of data point dataId of payload payloadId from
+// the store, for a change to be checked against before it is applied
+func stagedDataPoint(db *database.RedisClient, payloadId string, dataId string) (dataPoint, error) {
+	stored, _, err := newStoredDataPoint(db, payloadId, dataId)
+	if err != nil {
+		return nil, err
+	}
+	// the plan wraps a data point driven by a plant or playback, which takes
+	// the parameters
+	dps := map[string]dataPoint{dataId: stored}
+	if _, err := newUpdatePlan(db, dps, nil, newExternalValues()); err != nil {
+		return nil, err
+	}
+	return dps[dataId], nil
+}
+
+// payload states
+const (
+	payloadRunning = "running"
+	payloadPaused  = "paused"  // ticks continue but nothing is emitted
+	payloadStopped = "stopped" // ticker stopped
+)
+
+// setState starts, pauses or stops the payload. A stopped payload starts
+// from the beginning of its schedule. Runs on the manager goroutine.
+func (pm *payloadManager) setState(state string) error {
+	switch state {
+	case payloadRunning, payloadPaused:
+		if pm.state == payloadStopped {
+			pm.start(state)
+			return nil
+		}
+	case payloadStopped:
+		pm.halt()
+		return nil
+	default:
+		return fmt.Errorf("unknown payload state: %s", state)
+	}
+	pm.state = state
+	return nil
+}
+
+// setRate changes the payload frequency. Runs on the manager goroutine.
+func (pm *payloadManager) setRate(hz float64) error {
+	period, err := ratePeriod(hz)
+	if err != nil {
+		return err
+	}
+	pm.freq = period
+	pm.retick()
+	return nil
+}
+
+// force overrides the value of a data point until released. Runs on the manager goroutine.
+func (pm *payloadManager) force(dataId string, value string) error {
+	dp, ok := pm.dpMap[dataId]
+	if !ok {
+		return fmt.Errorf("data point %s not in payload %d", dataId, pm.id)
+	}
+	if err := dp.set(value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", dataId, err)
+	}
+	pm.forced[dataId] = value
+	return nil
+}
+
+// release removes a forced value. Runs on the manager goroutine.
+func (pm *payloadManager) release(dataId string) {
+	delete(pm.forced, dataId)
+}
+
+// reload rebuilds the payload from the store when its definition changed,
+// keeping the runtime state (channels, ticker, forced values, sequence
+// counter) and the engine state of the data points whose definition did not.
+// Rate, faults and schedule set at runtime stay unless their definition
+// changed. It reports whether the payload changed. Runs on the manager goroutine.
+func (pm *payloadManager) reload() (bool, error) {
+	info, err := pm.db.GetPayloadInfo(pm.key)
+	if err != nil {
+		return false, err
+	}
+	hz, period, err := parseRate(info["frequency"])
+	if err != nil {
+		return false, fmt.Errorf("invalid frequency: %w", err)
+	}
+	next, err := newPayloadManager(pm.cfg, pm.key, period, pm.db, pm.sender, pm.cs.shared)
+	if err != nil {
+		return false, err
+	}
+	next.def.info = info
+	return pm.adopt(next, hz)
+}
+
+// adopt takes over next, the payload rebuilt from its stored definition at hz,
+// with the state reload keeps. It reports whether the definition changed.
+// Runs on the manager goroutine.
+func (pm *payloadManager) adopt(next *payloadManager, hz float64) (bool, error) {
+	info := next.def.info
+	if next.def.equal(pm.def) {
+		return false, nil
+	}
+	sched, err := parseSchedule(scheduleParams(info))
+	if err != nil {
+		return false, fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	next.cs, next.proto, next.ctx, next.db, next.cfg = pm.cs, info["packet_type"], pm.ctx, pm.db, pm.cfg
+	next.state, next.ticks = pm.state, pm.ticks
+	next.sched, next.sent, next.burstLeft = pm.sched, pm.sent, pm.burstLeft
+	next.wake, next.end = pm.wake, pm.end
+	restart := !maps.Equal(sched.params, scheduleParams(pm.def.info))
+	if restart {
+		next.sched = sched
+	}
+	next.rxSeq, next.rxSeqValid = pm.rxSeq, pm.rxSeqValid
+	if info["frequency"] == pm.def.info["frequency"] {
+		next.freq = pm.freq
+	} else {
+		pm.cs.monitor.setConfiguredRate(pm.bus, pm.key, hz)
+	}
+	if maps.Equal(next.def.faults, pm.def.faults) {
+		next.faultCfg = pm.faultCfg
+	}
+	next.setFaults(next.faultCfg)
+	for id, def := range next.def.data {
+		if pm.def.data[id] == def {
+			next.dpMap[id] = pm.dpMap[id]
+			next.plan.keep(pm.plan, id)
+		}
+	}
+	for id, f := range pm.forced {
+		if _, ok := next.dpMap[id]; ok {
+			next.forced[id] = f
+		}
+	}
+	// receivers see no sequence gap
+	if _, seq, err := pm.header.Locate(isSequence); err == nil {
+		for i, e := range next.header.Elements {
+			if isSequence(e) {
+				next.header.Elements[i] = seq
+			}
+		}
+	}
+
+	rateChanged := next.freq != pm.freq
+	*pm = *next
+	// a changed schedule restarts the payload under it
+	if restart {
+		pm.begin()
+	} else if rateChanged {
+		pm.retick()
+	}
+	return true, nil
+}
+
+func isSequence(e definitions.ByteSource) bool {
+	_, ok := e.(*definitions.SequenceCounter)
+	return ok
+}
+
+// setFaultParams replaces the impairments of the payload. Runs on the manager goroutine.
+func (pm *payloadManager) setFaultParams(params map[string]string) error {
+	cfg, err := parseFaults(params)
+	if err != nil {
+		return err
+	}
+	pm.setFaults(cfg)
+	return nil
+}
@@ -1,7 +1,9 @@
 package sim
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
+	"math"
 	"strconv"
 
 	"github.com/Sapper177/datagensim/pkg/engine"
@@ -60,15 +62,35 @@ func selectDtype(dtypeStr string) dtype {
 	case "bytes":
 		return D_BYTES
 	default:
-		log.Printf("Unknown data type: %s. Defaulting to int32.", dtypeStr)
+		slog.Warn("unknown data type, defaulting to int32", "type", dtypeStr)
 		return D_INT32
 	}
 }
 
+// dataPoint is a value placed in a payload. It holds its current value, typed,
+// so that updating it and encoding it into a payload allocate nothing; the
+// store sees it as a string.
 type dataPoint interface {
-	appendData(buf []byte, val any) error
-	update(val any) (any, string)
+	update()                             // next value from the engine
+	set(value string) error              // value as stored
+	setFloat(v float64) error            // result of an expression, plant or playback
+	load(src dataPoint)                  // value of another data point of the same type
+	float() (float64, bool)              // value for expressions, false when not numeric
+	appendValue(dst []byte) []byte       // value as stored
+	writeData(buf []byte) error          // encode the value at its offset
+	readData(buf []byte) (string, error) // decode a value as stored
+	setParam(name string, value string) error
 	getSize() uint16
+	getOffset() uint16
+	getBits() int // number of bits occupied in the payload
+}
+
+// unwrapped returns dp without a plant or playback around it
+func unwrapped(dp dataPoint) dataPoint {
+	if p, ok := dp.(*drivenPoint); ok {
+		return p.dataPoint
+	}
+	return dp
 }
 
 type dataPointFloat struct {
@@ -76,6 +98,7 @@ type dataPointFloat struct {
 	eng    *engine.NumEngine64
 	offset uint16
 	size   uint16 // in bits
+	val    float64
 }
 
 func newDataPointFloat(dtype dtype, numEng *engine.NumEngine64, offset uint16, size uint16) *dataPointFloat {
@@ -86,26 +109,74 @@ func newDataPointFloat(dtype dtype, numEng *engine.NumEngine64, offset uint16, s
 		size:   size,
 	}
 }
-func (d *dataPointFloat) appendData(buf []byte, val any) error {
-	return writeBits(buf, int(d.offset), val, int(d.size))
+func (d *dataPointFloat) update() {
+	d.val = d.eng.Update(d.val)
+}
+func (d *dataPointFloat) set(value string) error {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	d.val = v
+	return nil
+}
+func (d *dataPointFloat) setFloat(v float64) error {
+	d.val = v
+	return nil
+}
+func (d *dataPointFloat) load(src dataPoint) {
+	if v, ok := src.float(); ok {
+		d.val = v
+	}
+}
+func (d *dataPointFloat) float() (float64, bool) {
+	return d.val, true
+}
+func (d *dataPointFloat) appendValue(dst []byte) []byte {
+	return strconv.AppendFloat(dst, d.val, 'f', -1, 64)
+}
+func (d *dataPointFloat) writeData(buf []byte) error {
+	bits := math.Float64bits(d.val)
+	if d.size == 32 {
+		bits = uint64(math.Float32bits(float32(d.val)))
+	}
+	return writeBits(buf, int(d.offset), bits, int(d.size))
 }
-func (d *dataPointFloat) update(val any) (any, string) {
-	newVal := 0.0
-	switch v := val.(type) {
-	case float64:
-		newVal = d.eng.Update(v)
+func (d *dataPointFloat) readData(buf []byte) (string, error) {
+	raw, err := readBits(buf, int(d.offset), int(d.size))
+	if err != nil {
+		return "", err
 	}
-	return newVal, strconv.FormatFloat(newVal, 'f', -1, 64)
+	var val float64
+	switch d.size {
+	case 32:
+		val = float64(math.Float32frombits(uint32(raw)))
+	case 64:
+		val = math.Float64frombits(raw)
+	default:
+		return "", fmt.Errorf("unable to decode %d bit float", d.size)
+	}
+	return strconv.FormatFloat(val, 'f', -1, 64), nil
+}
+func (d *dataPointFloat) setParam(name string, value string) error {
+	return d.eng.SetParam(name, value)
 }
 func (d *dataPointFloat) getSize() uint16 {
 	return d.size
 }
+func (d *dataPointFloat) getOffset() uint16 {
+	return d.offset
+}
+func (d *dataPointFloat) getBits() int {
+	return int(d.size)
+}
 
 type dataPointInt struct {
 	dtype  dtype
 	eng    *engine.NumEngineInt
 	offset uint16
 	size   uint16 // in bits
+	val    int64  // the bits of a uint64 for unsigned types
 }
 
 func newDataPoint32(dtype dtype, numEng *engine.NumEngineInt, offset uint16, size uint16) *dataPointInt {
@@ -116,26 +187,95 @@ func newDataPoint32(dtype dtype, numEng *engine.NumEngineInt, offset uint16, siz
 		size:   size,
 	}
 }
-func (d *dataPointInt) appendData(buf []byte, val any) error {
-	return writeBits(buf, int(d.offset), val, int(d.size))
+func (d *dataPointInt) unsigned() bool {
+	switch d.dtype {
+	case D_UINT, D_UINT8, D_UINT16, D_UINT32, D_UINT64:
+		return true
+	}
+	return false
+}
+func (d *dataPointInt) update() {
+	d.val = d.eng.Update(d.val)
+}
+func (d *dataPointInt) set(value string) error {
+	if d.unsigned() {
+		v, err := strconv.ParseUint(value, 0, 64)
+		if err != nil {
+			return err
+		}
+		d.val = int64(v)
+		return nil
+	}
+	v, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return err
+	}
+	d.val = v
+	return nil
+}
+func (d *dataPointInt) setFloat(v float64) error {
+	v = math.Round(v)
+	if d.unsigned() && v < 0 {
+		return fmt.Errorf("%v out of range for %d bit unsigned value", v, d.size)
+	}
+	if d.unsigned() {
+		d.val = int64(uint64(v))
+	} else {
+		d.val = int64(v)
+	}
+	return nil
+}
+func (d *dataPointInt) load(src dataPoint) {
+	if s, ok := unwrapped(src).(*dataPointInt); ok {
+		d.val = s.val
+	} else if v, ok := src.float(); ok {
+		d.setFloat(v)
+	}
+}
+func (d *dataPointInt) float() (float64, bool) {
+	if d.unsigned() {
+		return float64(uint64(d.val)), true
+	}
+	return float64(d.val), true
 }
-func (d *dataPointInt) update(val any) (any, string) {
-	var newVal int64 = 0
-	switch v := val.(type) {
-	case int64:
-		newVal = d.eng.Update(v)
+func (d *dataPointInt) appendValue(dst []byte) []byte {
+	if d.unsigned() {
+		return strconv.AppendUint(dst, uint64(d.val), 10)
 	}
-	return newVal, strconv.FormatInt(newVal, 10)
+	return strconv.AppendInt(dst, d.val, 10)
+}
+func (d *dataPointInt) writeData(buf []byte) error {
+	return writeBits(buf, int(d.offset), uint64(d.val), int(d.size))
+}
+func (d *dataPointInt) readData(buf []byte) (string, error) {
+	raw, err := readBits(buf, int(d.offset), int(d.size))
+	if err != nil {
+		return "", err
+	}
+	if d.unsigned() {
+		return strconv.FormatUint(raw, 10), nil
+	}
+	return strconv.FormatInt(signExtend(raw, int(d.size)), 10), nil
+}
+func (d *dataPointInt) setParam(name string, value string) error {
+	return d.eng.SetParam(name, value)
 }
 func (d *dataPointInt) getSize() uint16 {
 	return d.size
 }
+func (d *dataPointInt) getOffset() uint16 {
+	return d.offset
+}
+func (d *dataPointInt) getBits() int {
+	return int(d.size)
+}
 
 type strDataPoint struct {
 	dtype  dtype
 	eng    *engine.StrEngine
 	offset uint16
 	size   uint16 // length of string
+	val    []byte // with room for size characters
 }
 
 func newStrDataPoint(dtype dtype, strEng *engine.StrEngine, offset uint16, size uint16) *strDataPoint {
@@ -144,32 +284,72 @@ func newStrDataPoint(dtype dtype, strEng *engine.StrEngine, offset uint16, size
 		eng:    strEng,
 		offset: offset,
 		size:   size,
+		val:    make([]byte, 0, size),
+	}
+}
+func (d *strDataPoint) update() {
+	d.val = d.eng.Next(d.val)
+}
+func (d *strDataPoint) set(value string) error {
+	if len(value) > int(d.size) {
+		return fmt.Errorf("string longer than %d characters", d.size)
+	}
+	d.val = append(d.val[:0], value...)
+	return nil
+}
+func (d *strDataPoint) setFloat(v float64) error {
+	var tmp [32]byte
+	b := strconv.AppendFloat(tmp[:0], v, 'f', -1, 64)
+	if len(b) > int(d.size) {
+		return fmt.Errorf("string longer than %d characters", d.size)
 	}
+	d.val = append(d.val[:0], b...)
+	return nil
 }
-func (d *strDataPoint) appendData(buf []byte, val any) error {
-	valStr := ""
-	switch v := val.(type) {
-	case string:
-		valStr = v
+func (d *strDataPoint) load(src dataPoint) {
+	if s, ok := unwrapped(src).(*strDataPoint); ok {
+		d.val = append(d.val[:0], s.val[:min(len(s.val), int(d.size))]...)
+	} else if v, ok := src.float(); ok {
+		d.setFloat(v)
 	}
-	return writeBitsStr(buf, int(d.offset), valStr, int(d.size))
 }
-func (d *strDataPoint) update(val any) (any, string) {
-	newVal := ""
-	switch v := val.(type) {
-	case string:
-		newVal = d.eng.Update(v)
+func (d *strDataPoint) float() (float64, bool) {
+	switch string(d.val) {
+	case "true":
+		return 1, true
+	case "false":
+		return 0, true
 	}
-	return newVal, newVal
+	v, err := strconv.ParseFloat(string(d.val), 64)
+	return v, err == nil
+}
+func (d *strDataPoint) appendValue(dst []byte) []byte {
+	return append(dst, d.val...)
+}
+func (d *strDataPoint) writeData(buf []byte) error {
+	return writeBitsStr(buf, int(d.offset), d.val, int(d.size))
+}
+func (d *strDataPoint) readData(buf []byte) (string, error) {
+	return readBitsStr(buf, int(d.offset), int(d.size))
+}
+func (d *strDataPoint) setParam(name string, value string) error {
+	return d.eng.SetParam(name, value)
 }
 func (d *strDataPoint) getSize() uint16 {
 	return d.size
 }
+func (d *strDataPoint) getOffset() uint16 {
+	return d.offset
+}
+func (d *strDataPoint) getBits() int {
+	return int(d.size) * 8
+}
 
 type boolDataPoint struct {
 	eng    *engine.BoolEngine
 	offset uint16
-	size   uint16 // length of string
+	size   uint16 // in bits
+	val    bool
 }
 
 func newBoolDataPoint(boolEng *engine.BoolEngine, offset uint16, size uint16) *boolDataPoint {
@@ -179,21 +359,64 @@ func newBoolDataPoint(boolEng *engine.BoolEngine, offset uint16, size uint16) *b
 		size:   size,
 	}
 }
-func (d *boolDataPoint) appendData(buf []byte, val any) error {
-	return writeBits(buf, int(d.offset), val, int(d.size))
+func (d *boolDataPoint) update() {
+	d.val = d.eng.Update(d.val)
+}
+func (d *boolDataPoint) set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	d.val = v
+	return nil
+}
+func (d *boolDataPoint) setFloat(v float64) error {
+	d.val = v != 0
+	return nil
 }
-func (d *boolDataPoint) update(val any) (any, string) {
-	newVal := false
-	switch v := val.(type) {
-	case bool:
-		newVal = d.eng.Update(v)
+func (d *boolDataPoint) load(src dataPoint) {
+	if v, ok := src.float(); ok {
+		d.val = v != 0
 	}
-	s := "0"
-	if newVal {
-		s = "1"
+}
+func (d *boolDataPoint) float() (float64, bool) {
+	if d.val {
+		return 1, true
+	}
+	return 0, true
+}
+func (d *boolDataPoint) appendValue(dst []byte) []byte {
+	if d.val {
+		return append(dst, '1')
 	}
-	return newVal, s
+	return append(dst, '0')
+}
+func (d *boolDataPoint) writeData(buf []byte) error {
+	var bit uint64
+	if d.val {
+		bit = 1
+	}
+	return writeBits(buf, int(d.offset), bit, int(d.size))
+}
+func (d *boolDataPoint) readData(buf []byte) (string, error) {
+	raw, err := readBits(buf, int(d.offset), int(d.size))
+	if err != nil {
+		return "", err
+	}
+	if raw != 0 {
+		return "1", nil
+	}
+	return "0", nil
+}
+func (d *boolDataPoint) setParam(name string, value string) error {
+	return d.eng.SetParam(name, value)
 }
 func (d *boolDataPoint) getSize() uint16 {
 	return d.size
 }
+func (d *boolDataPoint) getOffset() uint16 {
+	return d.offset
+}
+func (d *boolDataPoint) getBits() int {
+	return int(d.size)
+}
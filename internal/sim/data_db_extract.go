@@ -1,7 +1,7 @@
 package sim
 
 import (
-	"log"
+	"log/slog"
 	"strconv"
 
 	"github.com/Sapper177/datagensim/pkg/config"
@@ -21,19 +21,19 @@ func newDBExtract(id string, dataid string, dataInfo map[string]string, info map
 	// Convert offset and size to int
 	offset, err := strconv.Atoi(dataInfo["offset"])
 	if err != nil {
-		log.Printf("Error converting offset for Payload (%s) - data ID (%s): %s", id, dataid, err)
+		slog.Warn("invalid offset", "payload", id, "data", dataid, "err", err)
 		return nil
 	}
 	size, err := strconv.Atoi(dataInfo["size"])
 	if err != nil {
-		log.Printf("Error converting size for Payload (%s) - data ID (%s): %s", id, dataid, err)
+		slog.Warn("invalid size", "payload", id, "data", dataid, "err", err)
 		return nil
 	}
 
 	// Convert string type to go Type
 	t, err := convertDtype(dataInfo["type"])
 	if err != nil {
-		log.Printf("Error converting data type for Payload (%s) - data ID (%s): %s", id, dataid, err)
+		slog.Warn("invalid data type", "payload", id, "data", dataid, "err", err)
 		return nil
 	}
 
@@ -49,22 +49,22 @@ func newDBExtract(id string, dataid string, dataInfo map[string]string, info map
 	}
 	min, ok := info["min"]
 	if !ok {
-		log.Printf("Error converting min for Payload (%s) - data ID (%s): %s", id, dataid, err)
+		slog.Warn("invalid min", "payload", id, "data", dataid, "err", err)
 		min = "0"
 	}
 	max, ok := info["max"]
 	if !ok {
-		log.Printf("Error converting max for Payload (%s) - data ID (%s): %s", id, dataid, err)
+		slog.Warn("invalid max", "payload", id, "data", dataid, "err", err)
 		max = "1000"
 	}
 	step, ok := info["step"]
 	if !ok {
-		log.Printf("Error converting step for Payload (%s) - data ID (%s): %s", id, dataid, err)
+		slog.Warn("invalid step", "payload", id, "data", dataid, "err", err)
 		step = "1"
 	}
 	freq, err := strconv.ParseFloat(info["frequency"], 64)
 	if err != nil {
-		log.Printf("Error converting frequency for Payload (%s) - data ID (%s): %s", id, dataid, err)
+		slog.Warn("invalid frequency", "payload", id, "data", dataid, "err", err)
 		freq = config.FREQ_DEFAULT
 	}
 	return &dbExtract{
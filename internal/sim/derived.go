@@ -0,0 +1,281 @@
+package sim
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sapper177/datagensim/pkg/database"
+	"github.com/Sapper177/datagensim/pkg/engine"
+)
+
+// loadDerived returns the expressions of the derived data points among ids,
+// keyed by data id. A data point is derived when its info has an expression.
+func loadDerived(db *database.RedisClient, ids []string) (map[string]*engine.Expr, error) {
+	exprs := make(map[string]*engine.Expr)
+	for _, id := range ids {
+		info, err := db.GetDataInfo(id)
+		if err != nil {
+			return nil, fmt.Errorf("error getting data point info for ID (%s): %s", id, err)
+		}
+		src := strings.TrimSpace(info["expression"])
+		if src == "" {
+			continue
+		}
+		expr, err := engine.ParseExpr(src)
+		if err != nil {
+			return nil, fmt.Errorf("data ID (%s): %w", id, err)
+		}
+		exprs[id] = expr
+	}
+	return exprs, nil
+}
+
+// orderDerived sorts the derived data points so that every one comes after
+// the derived data points it depends on. Dependencies on other data points
+// need no ordering. A dependency cycle is an error.
+func orderDerived(exprs map[string]*engine.Expr) ([]string, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	mark := make(map[string]int, len(exprs))
+	order := make([]string, 0, len(exprs))
+	var path []string
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch mark[id] {
+		case done:
+			return nil
+		case visiting:
+			start := slices.Index(path, id)
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path[start:], " -> "), id)
+		}
+		mark[id] = visiting
+		path = append(path, id)
+		for _, dep := range exprs[id].Vars() {
+			if _, ok := exprs[dep]; ok {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		mark[id] = done
+		order = append(order, id)
+		return nil
+	}
+
+	// sorted so that the order and reported cycle are consistent
+	for _, id := range slices.Sorted(maps.Keys(exprs)) {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// checkDerived verifies the derived data points of every payload on a bus
+// before anything runs: expressions must parse, and there must be no
+// dependency cycle, including across payloads.
+func checkDerived(db *database.RedisClient, payloadIds []string) error {
+	var ids []string
+	for _, pid := range payloadIds {
+		dataIds, err := db.GetPayloadData(pid)
+		if err != nil {
+			return fmt.Errorf("error getting payload data ids for ID (%s): %s", pid, err)
+		}
+		ids = append(ids, dataIds...)
+	}
+	exprs, err := loadDerived(db, ids)
+	if err != nil {
+		return err
+	}
+	_, err = orderDerived(exprs)
+	return err
+}
+
+// updatePlan decides how each data point of a payload, or of the shared
+// bus-level data points, gets its next value. Engines and playbacks go first,
+// then plants, whose inputs see the latest known values so that they can
+// close loops, then expressions in dependency order.
+type updatePlan struct {
+	dps      map[string]dataPoint        // data id -> data point, whose values expressions and plant inputs see
+	shared   *sharedState                // bus-level data points seen besides dps, nil for the shared plan itself
+	external *externalValues             // stored values of the other data points seen
+	order    []string                    // data ids in update order
+	derived  map[string]*engine.Expr     // data id -> expression of derived data points
+	plants   map[string]*engine.Plant    // data id -> plant model
+	records  map[string]*engine.Playback // data id -> recorded data played back
+	resolve  engine.Lookup               // lookup, bound once so that evaluating allocates nothing
+}
+
+// newUpdatePlan loads the expressions, plants and playbacks of dps. Data
+// points driven by a plant or playback are wrapped so that engine parameters
+// reach it. Names the expressions and plant inputs reference outside dps
+// resolve from shared, then from external, which starts tracking them.
+func newUpdatePlan(db *database.RedisClient, dps map[string]dataPoint, shared *sharedState, external *externalValues) (*updatePlan, error) {
+	ids := slices.Sorted(maps.Keys(dps))
+	derived, err := loadDerived(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	order, err := orderDerived(derived)
+	if err != nil {
+		return nil, err
+	}
+
+	plants := make(map[string]*engine.Plant)
+	records := make(map[string]*engine.Playback)
+	var plantIds []string
+	for _, id := range ids {
+		info, err := db.GetDataInfo(id)
+		if err != nil {
+			return nil, fmt.Errorf("error getting data point info for ID (%s): %s", id, err)
+		}
+		_, isDerived := derived[id]
+		sources := 0
+		for _, set := range []bool{isDerived, info["model"] != "", info["playback"] != ""} {
+			if set {
+				sources++
+			}
+		}
+		if sources > 1 {
+			return nil, fmt.Errorf("data ID (%s) has more than one of expression, model and playback", id)
+		}
+
+		switch {
+		case info["model"] != "":
+			plant, err := engine.NewPlant(info["model"], info)
+			if err != nil {
+				return nil, fmt.Errorf("data ID (%s): %w", id, err)
+			}
+			plants[id] = plant
+			plantIds = append(plantIds, id)
+			dps[id] = &drivenPoint{dataPoint: dps[id], driver: plant}
+
+		case info["playback"] != "":
+			rec, err := engine.LoadRecording(info["playback"], info["column"])
+			if err != nil {
+				return nil, fmt.Errorf("data ID (%s): %w", id, err)
+			}
+			pb, err := engine.NewPlayback(rec, info)
+			if err != nil {
+				return nil, fmt.Errorf("data ID (%s): %w", id, err)
+			}
+			records[id] = pb
+			dps[id] = &drivenPoint{dataPoint: dps[id], driver: pb}
+		}
+	}
+
+	engines := make([]string, 0, len(ids))
+	for _, id := range ids {
+		_, isDerived := derived[id]
+		_, isPlant := plants[id]
+		if !isDerived && !isPlant {
+			engines = append(engines, id)
+		}
+	}
+	var names []string
+	for _, expr := range derived {
+		names = append(names, expr.Vars()...)
+	}
+	for _, plant := range plants {
+		names = append(names, plant.Inputs()...)
+	}
+	names = slices.DeleteFunc(names, func(name string) bool {
+		_, ok := dps[name]
+		return ok || shared.has(name)
+	})
+	if err := external.track(db, names); err != nil {
+		return nil, err
+	}
+
+	u := &updatePlan{
+		dps:      dps,
+		shared:   shared,
+		external: external,
+		order:    slices.Concat(engines, plantIds, order),
+		derived:  derived,
+		plants:   plants,
+		records:  records,
+	}
+	u.resolve = u.lookup
+	return u, nil
+}
+
+// next moves data point id to its next value at now, the time of the tick
+func (u *updatePlan) next(id string, dp dataPoint, now time.Time) error {
+	var v float64
+	var err error
+	if pb, ok := u.records[id]; ok {
+		v = pb.Value(now)
+	} else if plant, ok := u.plants[id]; ok {
+		v, err = plant.Step(now, u.resolve)
+	} else if expr, ok := u.derived[id]; ok {
+		v, err = expr.Eval(u.resolve)
+	} else {
+		dp.update()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return dp.setFloat(v)
+}
+
+// keep carries the plant or playback of data point id over from old, for a
+// reload that does not change its definition
+func (u *updatePlan) keep(old *updatePlan, id string) {
+	if plant, ok := old.plants[id]; ok {
+		u.plants[id] = plant
+	}
+	if pb, ok := old.records[id]; ok {
+		u.records[id] = pb
+	}
+}
+
+// lookup resolves a name from the latest values of the data points, of the
+// shared data points or, for data points generated elsewhere, from their
+// values as of the last store sync. It never waits on the store.
+func (u *updatePlan) lookup(name string) (float64, error) {
+	if dp, ok := u.dps[name]; ok {
+		if v, ok := dp.float(); ok {
+			return v, nil
+		}
+		return 0, fmt.Errorf("%s is not numeric: %q", name, dp.appendValue(nil))
+	}
+	if v, ok, err := u.shared.float(name); ok {
+		return v, err
+	}
+	return u.external.float(name)
+}
+
+// drivenPoint is a data point driven by a plant or playback instead of its
+// own engine
+type drivenPoint struct {
+	dataPoint
+	driver interface {
+		SetParam(name string, value string) error
+	}
+}
+
+func (p *drivenPoint) setParam(name string, value string) error {
+	return p.driver.SetParam(name, value)
+}
+
+// storedFloat converts a value as stored for use in expressions. Booleans
+// are 1 or 0.
+func storedFloat(val string) (float64, error) {
+	switch val {
+	case "true":
+		return 1, nil
+	case "false":
+		return 0, nil
+	}
+	return strconv.ParseFloat(val, 64)
+}
@@ -0,0 +1,253 @@
+package sim
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Sapper177/datagensim/ext/definitions"
+)
+
+// Fault kinds, also the keys of <payload_id>_faults in the store. All but
+// delay, jitter and seed are probabilities between 0 and 1.
+const (
+	faultLoss            = "loss"             // drop the packet
+	faultDuplicate       = "duplicate"        // send the packet twice
+	faultReorder         = "reorder"          // hold the packet back until after the next one
+	faultDelay           = "delay"            // added latency, e.g. 5ms
+	faultJitter          = "jitter"           // uniform +/- variation of the added latency
+	faultBitFlipData     = "bitflip_data"     // flip one bit of the data, the checksum no longer matches
+	faultBitFlipChecksum = "bitflip_checksum" // flip one bit of the footer
+	faultTruncate        = "truncate"         // cut the packet short
+	faultBadSequence     = "bad_sequence"     // skip the sequence counter, the checksum still matches
+	faultSeed            = "seed"             // random seed, for repeatable runs
+)
+
+// faultConfig is the impairment configuration of a payload
+type faultConfig struct {
+	loss            float64
+	duplicate       float64
+	reorder         float64
+	delay           time.Duration
+	jitter          time.Duration
+	bitFlipData     float64
+	bitFlipChecksum float64
+	truncate        float64
+	badSequence     float64
+	seed            uint64
+	params          map[string]string // as configured, for reporting
+}
+
+func (c *faultConfig) active() bool {
+	return c.loss > 0 || c.duplicate > 0 || c.reorder > 0 || c.delay > 0 || c.jitter > 0 ||
+		c.bitFlipData > 0 || c.bitFlipChecksum > 0 || c.truncate > 0 || c.badSequence > 0
+}
+
+// set changes a single fault parameter. An empty value clears it.
+func (c *faultConfig) set(name string, value string) error {
+	prob := func(p *float64) error {
+		if value == "" {
+			*p = 0
+			return nil
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v < 0 || v > 1 {
+			return fmt.Errorf("%s must be a probability between 0 and 1, got %q", name, value)
+		}
+		*p = v
+		return nil
+	}
+	dur := func(d *time.Duration) error {
+		if value == "" {
+			*d = 0
+			return nil
+		}
+		v, err := time.ParseDuration(value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("%s must be a positive duration, got %q", name, value)
+		}
+		*d = v
+		return nil
+	}
+
+	var err error
+	switch name {
+	case faultLoss:
+		err = prob(&c.loss)
+	case faultDuplicate:
+		err = prob(&c.duplicate)
+	case faultReorder:
+		err = prob(&c.reorder)
+	case faultDelay:
+		err = dur(&c.delay)
+	case faultJitter:
+		err = dur(&c.jitter)
+	case faultBitFlipData:
+		err = prob(&c.bitFlipData)
+	case faultBitFlipChecksum:
+		err = prob(&c.bitFlipChecksum)
+	case faultTruncate:
+		err = prob(&c.truncate)
+	case faultBadSequence:
+		err = prob(&c.badSequence)
+	case faultSeed:
+		c.seed = 0
+		if value != "" {
+			c.seed, err = strconv.ParseUint(value, 0, 64)
+		}
+	default:
+		return fmt.Errorf("unknown fault: %s", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.params == nil {
+		c.params = make(map[string]string)
+	}
+	if value == "" {
+		delete(c.params, name)
+	} else {
+		c.params[name] = value
+	}
+	return nil
+}
+
+// parseFaults builds a fault configuration from its stored form
+func parseFaults(params map[string]string) (faultConfig, error) {
+	var c faultConfig
+	// sorted so that the first invalid parameter is reported consistently
+	for _, name := range slices.Sorted(maps.Keys(params)) {
+		if err := c.set(name, params[name]); err != nil {
+			return faultConfig{}, err
+		}
+	}
+	return c, nil
+}
+
+// delayedPacket is a packet to be queued after delay, or once due when held
+// by a delayQueue
+type delayedPacket struct {
+	pkt   Packet
+	delay time.Duration
+	due   time.Time
+}
+
+// impairer applies a fault configuration to the packets of one payload. It
+// runs under cs.mu.
+type impairer struct {
+	cfg     faultConfig
+	rng     *rand.Rand
+	held    Packet // packet held back for reordering
+	holding bool
+	out     []delayedPacket // returned by apply, reused
+	buffers *bufferPool     // of dropped and duplicated packets
+	count   func(kind string)
+
+	// payload layout
+	hsize  int
+	size   int
+	seqOff int
+}
+
+func newImpairer(cfg faultConfig, pm *payloadManager, count func(kind string)) *impairer {
+	seed := cfg.seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	return &impairer{
+		cfg:     cfg,
+		rng:     rand.New(rand.NewPCG(seed, uint64(pm.id))),
+		out:     make([]delayedPacket, 0, 3),
+		buffers: pm.cs.buffers,
+		count:   count,
+		hsize:   int(pm.hsize),
+		size:    int(pm.size),
+		seqOff:  pm.seqOff,
+	}
+}
+
+func (f *impairer) hit(p float64) bool {
+	return p > 0 && f.rng.Float64() < p
+}
+
+// apply impairs pkt and returns the packets to queue in order, which may be
+// none if the packet was dropped or held back. The result is valid until the
+// next call.
+func (f *impairer) apply(pkt Packet) []delayedPacket {
+	c := &f.cfg
+	out := f.out[:0]
+	if f.hit(c.loss) {
+		f.buffers.put(pkt.Payload)
+		f.count(faultLoss)
+		return f.release(out)
+	}
+
+	body := f.hsize + f.size
+	if f.hit(c.badSequence) && f.seqOff >= 0 && len(pkt.Payload) >= body {
+		seq := binary.BigEndian.Uint16(pkt.Payload[f.seqOff:])
+		binary.BigEndian.PutUint16(pkt.Payload[f.seqOff:], seq+1+uint16(f.rng.IntN(0xfffe)))
+		// recompute the footer so that only the sequence is wrong
+		writeElements(pkt.Payload[body:], definitions.NewUdpFooter(pkt.Payload[:body]).Elements)
+		f.count(faultBadSequence)
+	}
+	if f.hit(c.bitFlipData) && f.size > 0 {
+		bit := f.rng.IntN(f.size * 8)
+		pkt.Payload[f.hsize+bit/8] ^= 0x80 >> (bit % 8)
+		f.count(faultBitFlipData)
+	}
+	if footer := len(pkt.Payload) - body; f.hit(c.bitFlipChecksum) && footer > 0 {
+		bit := f.rng.IntN(footer * 8)
+		pkt.Payload[body+bit/8] ^= 0x80 >> (bit % 8)
+		f.count(faultBitFlipChecksum)
+	}
+	if f.hit(c.truncate) && len(pkt.Payload) > 1 {
+		pkt.Payload = pkt.Payload[:1+f.rng.IntN(len(pkt.Payload)-1)]
+		f.count(faultTruncate)
+	}
+
+	out = append(out, delayedPacket{pkt: pkt, delay: f.latency()})
+	if f.hit(c.duplicate) {
+		dup := pkt
+		dup.Payload = f.buffers.get(len(pkt.Payload))
+		copy(dup.Payload, pkt.Payload)
+		out = append(out, delayedPacket{pkt: dup, delay: f.latency()})
+		f.count(faultDuplicate)
+	}
+	if !f.holding && f.hit(c.reorder) {
+		f.held, f.holding = out[0].pkt, true
+		f.count(faultReorder)
+		return out[1:]
+	}
+	return f.release(out)
+}
+
+// release appends a held packet, so that it goes out after the current one
+func (f *impairer) release(out []delayedPacket) []delayedPacket {
+	if !f.holding {
+		return out
+	}
+	out = append(out, delayedPacket{pkt: f.held, delay: f.latency()})
+	f.held, f.holding = Packet{}, false
+	return out
+}
+
+// latency returns the added delay of a packet
+func (f *impairer) latency() time.Duration {
+	c := &f.cfg
+	if c.delay == 0 && c.jitter == 0 {
+		return 0
+	}
+	d := c.delay
+	if c.jitter > 0 {
+		d += time.Duration(f.rng.Int64N(int64(2*c.jitter)+1)) - c.jitter
+	}
+	if d > 0 {
+		f.count(faultDelay)
+	}
+	return max(d, 0)
+}
@@ -0,0 +1,311 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"maps"
+	"math/bits"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sapper177/datagensim/ext/definitions"
+)
+
+func TestParseFaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    faultConfig
+		wantErr bool
+	}{
+		{name: "none", params: nil},
+		{
+			name: "all",
+			params: map[string]string{
+				"loss": "0.1", "duplicate": "0.2", "reorder": "0.3", "delay": "5ms", "jitter": "1ms",
+				"bitflip_data": "0.4", "bitflip_checksum": "0.5", "truncate": "0.6", "bad_sequence": "0.7", "seed": "0x2a",
+			},
+			want: faultConfig{
+				loss: 0.1, duplicate: 0.2, reorder: 0.3, delay: 5 * time.Millisecond, jitter: time.Millisecond,
+				bitFlipData: 0.4, bitFlipChecksum: 0.5, truncate: 0.6, badSequence: 0.7, seed: 42,
+			},
+		},
+		{name: "bounds", params: map[string]string{"loss": "0", "duplicate": "1"}, want: faultConfig{duplicate: 1}},
+		{name: "empty clears", params: map[string]string{"loss": "", "delay": ""}},
+		{name: "probability above 1", params: map[string]string{"loss": "1.5"}, wantErr: true},
+		{name: "negative probability", params: map[string]string{"reorder": "-0.1"}, wantErr: true},
+		{name: "probability not a number", params: map[string]string{"truncate": "half"}, wantErr: true},
+		{name: "negative delay", params: map[string]string{"delay": "-5ms"}, wantErr: true},
+		{name: "delay without unit", params: map[string]string{"jitter": "5"}, wantErr: true},
+		{name: "bad seed", params: map[string]string{"seed": "-1"}, wantErr: true},
+		{name: "unknown fault", params: map[string]string{"corrupt": "0.1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFaults(tt.params)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseFaults succeeded with %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			params := got.params
+			got.params = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFaults = %+v, want %+v", got, tt.want)
+			}
+			// reported as configured, without the cleared ones
+			want := maps.Clone(tt.params)
+			maps.DeleteFunc(want, func(_, v string) bool { return v == "" })
+			if len(params) != len(want) || (len(want) > 0 && !maps.Equal(params, want)) {
+				t.Errorf("params = %v, want %v", params, want)
+			}
+		})
+	}
+}
+
+// faultPacket returns a payload of the example header, size data bytes and
+// the CRC footer, and the impairer of a payload with that layout
+func faultPacket(t *testing.T, cfg faultConfig, size int) (Packet, *impairer, map[string]int) {
+	t.Helper()
+	header := definitions.NewUdpHeader(1)
+	hsize, err := calcHeaderSize(*header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seqOff, err := header.SequenceOffset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := int(hsize) + size
+	payload := make([]byte, body+4)
+	if _, err := writeElements(payload, header.Elements); err != nil {
+		t.Fatal(err)
+	}
+	for i := range size {
+		payload[int(hsize)+i] = byte(i + 1)
+	}
+	if _, err := writeElements(payload[body:], definitions.NewUdpFooter(payload[:body]).Elements); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+	pm := &payloadManager{
+		id:     1,
+		hsize:  hsize,
+		size:   uint16(size),
+		seqOff: seqOff,
+		cs:     &PayloadChans{buffers: &bufferPool{}},
+	}
+	f := newImpairer(cfg, pm, func(kind string) { counts[kind]++ })
+	return Packet{Protocol: "udp", Payload: payload}, f, counts
+}
+
+// diffBits returns the bit positions where a and b differ
+func diffBits(a, b []byte) []int {
+	var diff []int
+	for i := range min(len(a), len(b)) {
+		for x := a[i] ^ b[i]; x != 0; x &= x - 1 {
+			diff = append(diff, i*8+bits.LeadingZeros8(x&-x))
+		}
+	}
+	return diff
+}
+
+func TestImpairerApply(t *testing.T) {
+	const size = 16
+	tests := []struct {
+		name  string
+		cfg   map[string]string
+		check func(t *testing.T, orig Packet, out []delayedPacket, f *impairer)
+		count string // fault counted once
+	}{
+		{
+			name: "none",
+			cfg:  map[string]string{"seed": "7"},
+			check: func(t *testing.T, orig Packet, out []delayedPacket, f *impairer) {
+				if len(out) != 1 || !bytes.Equal(out[0].pkt.Payload, orig.Payload) || out[0].delay != 0 {
+					t.Errorf("got %d packets, want the packet unchanged", len(out))
+				}
+			},
+		},
+		{
+			name:  "loss",
+			cfg:   map[string]string{"loss": "1", "seed": "7"},
+			count: faultLoss,
+			check: func(t *testing.T, orig Packet, out []delayedPacket, f *impairer) {
+				if len(out) != 0 {
+					t.Errorf("got %d packets, want none", len(out))
+				}
+			},
+		},
+		{
+			name:  "duplicate",
+			cfg:   map[string]string{"duplicate": "1", "seed": "7"},
+			count: faultDuplicate,
+			check: func(t *testing.T, orig Packet, out []delayedPacket, f *impairer) {
+				if len(out) != 2 {
+					t.Fatalf("got %d packets, want 2", len(out))
+				}
+				if !bytes.Equal(out[0].pkt.Payload, orig.Payload) || !bytes.Equal(out[1].pkt.Payload, orig.Payload) {
+					t.Error("duplicate differs from the packet")
+				}
+				if &out[0].pkt.Payload[0] == &out[1].pkt.Payload[0] {
+					t.Error("duplicate shares the buffer of the packet")
+				}
+			},
+		},
+		{
+			name:  "delay",
+			cfg:   map[string]string{"delay": "5ms", "seed": "7"},
+			count: faultDelay,
+			check: func(t *testing.T, orig Packet, out []delayedPacket, f *impairer) {
+				if len(out) != 1 || out[0].delay != 5*time.Millisecond {
+					t.Errorf("got %+v, want one packet delayed by 5ms", out)
+				}
+			},
+		},
+		{
+			name: "jitter",
+			cfg:  map[string]string{"delay": "5ms", "jitter": "2ms", "seed": "7"},
+			check: func(t *testing.T, orig Packet, out []delayedPacket, f *impairer) {
+				if d := out[0].delay; d < 3*time.Millisecond || d > 7*time.Millisecond {
+					t.Errorf("delay %v, want 5ms +/- 2ms", d)
+				}
+			},
+		},
+		{
+			name:  "bitflip data",
+			cfg:   map[string]string{"bitflip_data": "1", "seed": "7"},
+			count: faultBitFlipData,
+			check: func(t *testing.T, orig Packet, out []delayedPacket, f *impairer) {
+				diff := diffBits(orig.Payload, out[0].pkt.Payload)
+				if len(diff) != 1 || diff[0] < f.hsize*8 || diff[0] >= (f.hsize+f.size)*8 {
+					t.Errorf("flipped bits %v, want one in the data", diff)
+				}
+			},
+		},
+		{
+			name:  "bitflip checksum",
+			cfg:   map[string]string{"bitflip_checksum": "1", "seed": "7"},
+			count: faultBitFlipChecksum,
+			check: func(t *testing.T, orig Packet, out []delayedPacket, f *impairer) {
+				diff := diffBits(orig.Payload, out[0].pkt.Payload)
+				if len(diff) != 1 || diff[0] < (f.hsize+f.size)*8 {
+					t.Errorf("flipped bits %v, want one in the footer", diff)
+				}
+			},
+		},
+		{
+			name:  "truncate",
+			cfg:   map[string]string{"truncate": "1", "seed": "7"},
+			count: faultTruncate,
+			check: func(t *testing.T, orig Packet, out []delayedPacket, f *impairer) {
+				got := out[0].pkt.Payload
+				if len(got) < 1 || len(got) >= len(orig.Payload) || !bytes.Equal(got, orig.Payload[:len(got)]) {
+					t.Errorf("got % x, want a prefix of % x", got, orig.Payload)
+				}
+			},
+		},
+		{
+			name:  "bad sequence",
+			cfg:   map[string]string{"bad_sequence": "1", "seed": "7"},
+			count: faultBadSequence,
+			check: func(t *testing.T, orig Packet, out []delayedPacket, f *impairer) {
+				got := out[0].pkt.Payload
+				if binary.BigEndian.Uint16(got[f.seqOff:]) == binary.BigEndian.Uint16(orig.Payload[f.seqOff:]) {
+					t.Error("sequence unchanged")
+				}
+				body := f.hsize + f.size
+				footer := make([]byte, 4)
+				writeElements(footer, definitions.NewUdpFooter(got[:body]).Elements)
+				if !bytes.Equal(footer, got[body:]) {
+					t.Error("footer does not match the new sequence")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseFaults(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkt, f, counts := faultPacket(t, cfg, size)
+			orig := Packet{Payload: bytes.Clone(pkt.Payload)}
+			out := f.apply(pkt)
+			tt.check(t, orig, out, f)
+			if tt.count != "" && counts[tt.count] != 1 {
+				t.Errorf("counted %v, want one %s", counts, tt.count)
+			}
+		})
+	}
+}
+
+func TestImpairerReorder(t *testing.T) {
+	cfg, err := parseFaults(map[string]string{"reorder": "1", "seed": "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt, f, counts := faultPacket(t, cfg, 4)
+	packet := func(n byte) Packet {
+		p := Packet{Payload: bytes.Clone(pkt.Payload)}
+		p.Payload[0] = n
+		return p
+	}
+	// every packet is held back but one at a time, so they go out in pairs swapped
+	var got []byte
+	for n := range byte(5) {
+		for _, d := range f.apply(packet(n)) {
+			got = append(got, d.pkt.Payload[0])
+		}
+	}
+	if want := []byte{1, 0, 3, 2}; !bytes.Equal(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+	if counts[faultReorder] != 3 {
+		t.Errorf("counted %d reorders, want 3", counts[faultReorder])
+	}
+	// a lost packet lets the held one go
+	f.cfg.loss = 1
+	if out := f.apply(packet(5)); len(out) != 1 || out[0].pkt.Payload[0] != 4 {
+		t.Errorf("got %d packets after a loss, want the held one", len(out))
+	}
+}
+
+func TestImpairerSeed(t *testing.T) {
+	cfg, err := parseFaults(map[string]string{
+		"loss": "0.2", "duplicate": "0.2", "reorder": "0.2", "delay": "5ms", "jitter": "3ms",
+		"bitflip_data": "0.2", "truncate": "0.2", "seed": "7",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same seed impairs the same packets the same way
+	pkt, _, _ := faultPacket(t, cfg, 16)
+	run := func() []string {
+		_, f, _ := faultPacket(t, cfg, 16)
+		var sent []string
+		for n := range byte(50) {
+			p := Packet{Payload: bytes.Clone(pkt.Payload)}
+			p.Payload[0] = n
+			for _, d := range f.apply(p) {
+				sent = append(sent, string(d.pkt.Payload)+d.delay.String())
+			}
+		}
+		return sent
+	}
+	a, b := run(), run()
+	if len(a) == 0 || len(a) != len(b) {
+		t.Fatalf("runs sent %d and %d packets", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("packet %d differs between runs with the same seed", i)
+		}
+	}
+}
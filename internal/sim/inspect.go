@@ -0,0 +1,215 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/Sapper177/datagensim/pkg/config"
+	"github.com/Sapper177/datagensim/pkg/database"
+)
+
+// PayloadLayout is the resolved layout of a payload, with the current values
+// of its data points as stored.
+type PayloadLayout struct {
+	Bus         string            `json:"bus"`
+	Id          string            `json:"id"`
+	PacketType  string            `json:"packet_type"`
+	RateHz      float64           `json:"rate_hz"`
+	HeaderBytes int               `json:"header_bytes"`
+	DataBytes   int               `json:"data_bytes"`
+	FooterBytes int               `json:"footer_bytes"`
+	Faults      map[string]string `json:"faults,omitempty"`
+	Schedule    map[string]string `json:"schedule,omitempty"`
+	Data        []DataLayout      `json:"data"` // by offset
+}
+
+// DataLayout is the placement and source of a data point in a payload.
+type DataLayout struct {
+	Id     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Type   string `json:"type"`
+	Offset int    `json:"offset"` // in bits from the start of the data
+	Bits   int    `json:"bits"`
+	Source string `json:"source"`           // engine, expression, model, playback, optionally shared
+	Detail string `json:"detail,omitempty"` // expression, plant model or recording
+	Value  string `json:"value"`            // as stored, behind a running simulation by up to its store_sync
+}
+
+// Validate loads every payload of every configured bus from the store the
+// way the simulation would, without starting anything, and reports all
+// invalid definitions.
+func Validate(ctx *context.Context, cfg *config.Config) error {
+	db := newDB(ctx, cfg)
+	defer db.Close()
+
+	var errs []error
+	for _, busCfg := range cfg.BusConfigs() {
+		payloadIds, err := db.GetPayloads(busCfg.BusName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("bus %s: %w", busCfg.BusName, err))
+			continue
+		}
+		if len(payloadIds) == 0 {
+			errs = append(errs, fmt.Errorf("bus %s: no payloads", busCfg.BusName))
+		}
+		if err := checkDerived(db, payloadIds); err != nil {
+			errs = append(errs, fmt.Errorf("bus %s: %w", busCfg.BusName, err))
+		}
+		if _, _, err := findShared(db, payloadIds); err != nil {
+			errs = append(errs, fmt.Errorf("bus %s: %w", busCfg.BusName, err))
+		}
+		for _, id := range payloadIds {
+			if _, _, err := loadPayload(db, busCfg, id); err != nil {
+				errs = append(errs, fmt.Errorf("bus %s: %w", busCfg.BusName, err))
+			}
+		}
+		if busCfg.CommandPort != 0 {
+			if _, err := loadCommands(db, busCfg.BusName); err != nil {
+				errs = append(errs, fmt.Errorf("bus %s: commands: %w", busCfg.BusName, err))
+			}
+		}
+	}
+
+	if cfg.Scenario != "" {
+		sc, err := loadScenario(cfg.Scenario)
+		if err == nil {
+			err = sc.checkBuses(busNames(cfg))
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Inspect returns the layouts of the payloads of bus, or of every bus when
+// bus is empty. payloadIds restricts the payloads when not empty.
+func Inspect(ctx *context.Context, cfg *config.Config, bus string, payloadIds []string) ([]PayloadLayout, error) {
+	db := newDB(ctx, cfg)
+	defer db.Close()
+
+	var layouts []PayloadLayout
+	found := bus == ""
+	for _, busCfg := range cfg.BusConfigs() {
+		if bus != "" && busCfg.BusName != bus {
+			continue
+		}
+		found = true
+		ids, err := db.GetPayloads(busCfg.BusName)
+		if err != nil {
+			return nil, fmt.Errorf("bus %s: %w", busCfg.BusName, err)
+		}
+		shared, _, err := findShared(db, ids)
+		if err != nil {
+			return nil, fmt.Errorf("bus %s: %w", busCfg.BusName, err)
+		}
+		for _, id := range ids {
+			if len(payloadIds) > 0 && !slices.Contains(payloadIds, id) {
+				continue
+			}
+			layout, err := inspectPayload(db, busCfg, id, shared)
+			if err != nil {
+				return nil, fmt.Errorf("bus %s: %w", busCfg.BusName, err)
+			}
+			layouts = append(layouts, layout)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("bus %s is not configured", bus)
+	}
+	return layouts, nil
+}
+
+func inspectPayload(db *database.RedisClient, cfg *config.Config, id string, shared map[string]string) (PayloadLayout, error) {
+	pm, pInfo, err := loadPayload(db, cfg, id)
+	if err != nil {
+		return PayloadLayout{}, err
+	}
+	layout := PayloadLayout{
+		Bus:         cfg.BusName,
+		Id:          id,
+		PacketType:  pInfo["packet_type"],
+		RateHz:      float64(time.Second) / float64(pm.freq),
+		HeaderBytes: int(pm.hsize),
+		DataBytes:   int(pm.size),
+		FooterBytes: int(pm.fsize),
+		Faults:      pm.faultCfg.params,
+		Schedule:    pm.sched.params,
+	}
+	for _, dataId := range slices.Sorted(maps.Keys(pm.dpMap)) {
+		dp := pm.dpMap[dataId]
+		d, err := db.GetData(dataId)
+		if err != nil {
+			return layout, fmt.Errorf("payload %s: %w", id, err)
+		}
+		info, err := db.GetDataInfo(dataId)
+		if err != nil {
+			return layout, fmt.Errorf("payload %s: %w", id, err)
+		}
+		dl := DataLayout{
+			Id:     dataId,
+			Name:   d["name"],
+			Type:   d["type"],
+			Offset: int(dp.getOffset()),
+			Bits:   dp.getBits(),
+			Source: "engine",
+			Value:  d["value"],
+		}
+		for _, source := range []string{"expression", "model", "playback"} {
+			if info[source] != "" {
+				dl.Source, dl.Detail = source, info[source]
+			}
+		}
+		if _, ok := shared[dataId]; ok {
+			dl.Source += ", shared"
+		}
+		layout.Data = append(layout.Data, dl)
+	}
+	slices.SortStableFunc(layout.Data, func(a, b DataLayout) int { return a.Offset - b.Offset })
+	return layout, nil
+}
+
+// loadPayload creates the manager of payload id without starting it
+func loadPayload(db *database.RedisClient, cfg *config.Config, id string) (*payloadManager, map[string]string, error) {
+	pInfo, err := db.GetPayloadInfo(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("payload %s: %w", id, err)
+	}
+	_, period, err := parseRate(pInfo["frequency"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("payload %s: invalid frequency: %w", id, err)
+	}
+	pm, err := newPayloadManager(cfg, id, period, db, nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("payload %s: %w", id, err)
+	}
+	if pm.sched, err = parseSchedule(scheduleParams(pInfo)); err != nil {
+		return nil, nil, fmt.Errorf("payload %s: invalid schedule: %w", id, err)
+	}
+	return pm, pInfo, nil
+}
+
+// newDB connects to the store configured by cfg
+func newDB(ctx *context.Context, cfg *config.Config) *database.RedisClient {
+	return database.NewRedisClient(
+		ctx,
+		cfg.DbHost+":"+cfg.DbPort,
+		cfg.DbPassword,
+		cfg.DbNum,
+		cfg.DbReadTimeout,
+		cfg.DbWriteTimeout,
+	)
+}
+
+// busNames returns the names of the configured buses
+func busNames(cfg *config.Config) []string {
+	var names []string
+	for _, busCfg := range cfg.BusConfigs() {
+		names = append(names, busCfg.BusName)
+	}
+	return names
+}
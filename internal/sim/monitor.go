@@ -1,126 +1,285 @@
 package sim
 
 import (
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/Sapper177/datagensim/pkg/config"
+	"github.com/Sapper177/datagensim/api"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// rateAlpha is the smoothing factor of the achieved rate moving average
+const rateAlpha = 0.1
+
 type packetInfo struct {
-	PacketId    uint
+	Bus         string
+	Payload     string // payload id as stored
 	PacketType  pktType
 	PacketSize  int
 	Direction   bool // true = tx, false = rx
 	Error       bool // true = error, false = no error
 	TxTime      time.Time
 	ProcessTime time.Duration
+	Slip        time.Duration // tx time minus scheduled tick time
+	Merged      int           // ticks skipped before this packet
+	Overrun     bool          // build took longer than the payload period
 }
 
-func newPacketInfo(pm payloadManager, dir bool, e bool, procDur time.Duration) *packetInfo {
-	return &packetInfo{
-		PacketId:    pm.id,
+func newPacketInfo(pm *payloadManager, dir bool, e bool, size int, procDur time.Duration) packetInfo {
+	return packetInfo{
+		Bus:         pm.bus,
+		Payload:     pm.key,
 		PacketType:  pm.pktType,
-		PacketSize:  pm.size,
+		PacketSize:  size,
 		Direction:   dir,
 		Error:       e,
-		TxTime:      pm.lastProc,
+		TxTime:      time.Now(),
 		ProcessTime: procDur,
 	}
 }
 
+// payloadKey identifies a payload across buses
+type payloadKey struct {
+	bus     string
+	payload string
+}
+
 type payloadInfo struct {
 	txCount           int
-	txMbs             float64
+	txBytes           int
 	rxCount           int
-	rxMbs             float64
+	rxBytes           int
 	errCount          int
-	errMbs            float64
+	errBytes          int
 	avgProcessingTime time.Duration
+	configuredRate    float64 // Hz
+	achievedRate      float64 // Hz, moving average of tx intervals
+	lastTx            time.Time
+	missedTicks       int
+	overruns          int
+	faults            map[string]int // injected faults by kind
 }
 
-func (p *payloadInfo) updateTxMbs(numBytes int) {
-	p.rxMbs += float64(numBytes / 1000)
-}
-func (p *payloadInfo) updateRxMbs(numBytes int) {
-	p.txMbs += float64(numBytes / 1000)
-}
-func (p *payloadInfo) updateErrMbs(numBytes int) {
-	p.errMbs += float64(numBytes / 1000)
-}
 func (p *payloadInfo) updateAvgProcessingTime(processingTime time.Duration) {
 	p.avgProcessingTime = (processingTime + p.avgProcessingTime) / 2
 }
 
+// updateAchievedRate folds the interval since the previous transmission into the rate average
+func (p *payloadInfo) updateAchievedRate(t time.Time) {
+	if !p.lastTx.IsZero() {
+		if interval := t.Sub(p.lastTx).Seconds(); interval > 0 {
+			rate := 1 / interval
+			if p.achievedRate == 0 {
+				p.achievedRate = rate
+			} else {
+				p.achievedRate += rateAlpha * (rate - p.achievedRate)
+			}
+		}
+	}
+	p.lastTx = t
+}
+
+// busInfo holds the totals of a single bus
+type busInfo struct {
+	txCount  int
+	txBytes  int
+	rxCount  int
+	rxBytes  int
+	errCount int
+	errBytes int
+}
+
 type payloadMonitor struct {
 	mu                  sync.RWMutex
-	payloadMap          map[string]payloadInfo
+	commands            map[commandKey]int
+	scenarioEvents      map[scenarioKey]int
+	payloadMap          map[payloadKey]*payloadInfo
+	busMap              map[string]*busInfo
 	numPayloads         int
 	numTx               int
 	numRx               int
 	numErr              int
-	numMbsTx            float64
-	numMbsRx            float64
-	numErrMbs           float64
+	numBytesTx          int
+	numBytesRx          int
+	numErrBytes         int
 	totalProcessingTime time.Duration
 	processedOperations int
+	latency             *prometheus.HistogramVec // build (tx) and processing (rx) time
+	jitter              *prometheus.SummaryVec   // tx time minus scheduled time
+}
+
+// commandKey identifies a command log counter
+type commandKey struct {
+	opcode uint16
+	name   string
+	status string
+}
+
+// scenarioKey identifies a scenario event counter
+type scenarioKey struct {
+	scenario string
+	kind     string
+	result   string
 }
 
 // newPayloadMonitor creates a new instance of the monitor.
 func newPayloadMonitor() *payloadMonitor {
 	return &payloadMonitor{
-		payloadMap: make(map[string]payloadInfo),
+		payloadMap:     make(map[payloadKey]*payloadInfo),
+		busMap:         make(map[string]*busInfo),
+		commands:       make(map[commandKey]int),
+		scenarioEvents: make(map[scenarioKey]int),
+		latency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "app_payload_monitor_processing_seconds",
+				Help:    "Payload build (tx) and processing (rx) latency in seconds.",
+				Buckets: prometheus.ExponentialBuckets(1e-6, 4, 10), // 1us .. 262ms
+			},
+			[]string{"bus", "payload", "direction"},
+		),
+		jitter: prometheus.NewSummaryVec(
+			prometheus.SummaryOpts{
+				Name:       "app_payload_monitor_schedule_slip_seconds",
+				Help:       "Delay between the scheduled tick and the actual transmission in seconds.",
+				Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001, 0.999: 0.0001},
+				MaxAge:     time.Minute,
+			},
+			[]string{"bus", "payload"},
+		),
 	}
 }
-func (p *payloadMonitor) addInfo(info packetInfo) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	// check if payload exists in map
-	id := string(info.PacketId)
-	payInfo, exists := p.payloadMap[id]
+
+// payload returns the info for a payload, creating it if needed. Caller holds mu.
+func (p *payloadMonitor) payload(key payloadKey) *payloadInfo {
+	payInfo, exists := p.payloadMap[key]
 	if !exists {
-		// if not, create a new PayloadInfo
-		newInfo := payloadInfo{
-			txCount:           0,
-			txMbs:             0,
-			rxCount:           0,
-			rxMbs:             0,
-			errCount:          0,
-			errMbs:            0,
-			avgProcessingTime: 0,
-		}
-		p.payloadMap[id] = newInfo
-		payInfo = newInfo
+		payInfo = &payloadInfo{}
+		p.payloadMap[key] = payInfo
 		p.numPayloads++
 	}
+	if _, exists := p.busMap[key.bus]; !exists {
+		p.busMap[key.bus] = &busInfo{}
+	}
+	return payInfo
+}
+
+// setConfiguredRate records the rate a payload is scheduled to run at
+func (p *payloadMonitor) setConfiguredRate(bus string, payloadId string, hz float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.payload(payloadKey{bus: bus, payload: payloadId}).configuredRate = hz
+}
+
+func (p *payloadMonitor) addInfo(info packetInfo) {
+	key := payloadKey{bus: info.Bus, payload: info.Payload}
+	direction := "tx"
+	if !info.Direction {
+		direction = "rx"
+	}
+	p.latency.WithLabelValues(key.bus, key.payload, direction).Observe(info.ProcessTime.Seconds())
+	if info.Direction && !info.Error {
+		p.jitter.WithLabelValues(key.bus, key.payload).Observe(info.Slip.Seconds())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	payInfo := p.payload(key)
+	bus := p.busMap[key.bus]
+
 	p.totalProcessingTime += info.ProcessTime
 	p.processedOperations++
+	// the ticks were missed and the build ran long whether or not the write failed
+	payInfo.missedTicks += info.Merged
+	if info.Overrun {
+		payInfo.overruns++
+	}
 	if info.Error { // if packet had an error
-		payInfo.updateErrMbs(info.PacketSize)
+		payInfo.errCount++
+		payInfo.errBytes += info.PacketSize
+		bus.errCount++
+		bus.errBytes += info.PacketSize
 		p.numErr++
-		p.numErrMbs += payInfo.errMbs
-	} else {
-		payInfo.updateAvgProcessingTime(info.ProcessTime) // update average processing time in packetInfo
-		if info.Direction {                               // if packet is tx
-			payInfo.updateTxMbs(info.PacketSize)
-			p.numTx++
-			p.numMbsTx += payInfo.txMbs
-		} else { // if packet is rx
-			payInfo.updateRxMbs(info.PacketSize)
-			p.numRx++
-			p.numMbsRx += payInfo.rxMbs
-		}
+		p.numErrBytes += info.PacketSize
+		return
+	}
+
+	payInfo.updateAvgProcessingTime(info.ProcessTime) // update average processing time in packetInfo
+	if info.Direction {                               // if packet is tx
+		payInfo.txCount++
+		payInfo.txBytes += info.PacketSize
+		payInfo.updateAchievedRate(info.TxTime)
+		bus.txCount++
+		bus.txBytes += info.PacketSize
+		p.numTx++
+		p.numBytesTx += info.PacketSize
+	} else { // if packet is rx
+		payInfo.rxCount++
+		payInfo.rxBytes += info.PacketSize
+		bus.rxCount++
+		bus.rxBytes += info.PacketSize
+		p.numRx++
+		p.numBytesRx += info.PacketSize
+	}
+}
+
+// addCommand counts a handled command by opcode, name and response status
+func (p *payloadMonitor) addCommand(opcode uint16, name string, status string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.commands[commandKey{opcode: opcode, name: name, status: status}]++
+}
+
+// addScenarioEvent counts a scenario event by kind and result
+func (p *payloadMonitor) addScenarioEvent(scenario string, kind string, result string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scenarioEvents[scenarioKey{scenario: scenario, kind: kind, result: result}]++
+}
+
+// addFault counts a fault injected into a payload
+func (p *payloadMonitor) addFault(bus string, payloadId string, kind string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := p.payload(payloadKey{bus: bus, payload: payloadId})
+	if info.faults == nil {
+		info.faults = make(map[string]int)
 	}
+	info.faults[kind]++
 }
 
-func procPayloadMon(cfg *config.Config, payloadMon *payloadMonitor, infoChan <-chan packetInfo) {
+func procPayloadMon(payloadMon *payloadMonitor, infoChan <-chan packetInfo) {
 	// process PacketInfos
-	for {
-		select {
-		case info := <-infoChan:
-			payloadMon.addInfo(info)
-		case <-time.After(cfg.MonitorInterval):
-			// do nothing
+	for info := range infoChan {
+		payloadMon.addInfo(info)
+	}
+}
+
+// stats returns the counters of every payload on bus, sorted by payload id
+func (p *payloadMonitor) stats(bus string) []api.PayloadStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	stats := make([]api.PayloadStats, 0, len(p.payloadMap))
+	for key, info := range p.payloadMap {
+		if key.bus != bus {
+			continue
 		}
+		stats = append(stats, api.PayloadStats{
+			Bus:          key.bus,
+			PayloadId:    key.payload,
+			ConfiguredHz: info.configuredRate,
+			AchievedHz:   info.achievedRate,
+			TxCount:      info.txCount,
+			RxCount:      info.rxCount,
+			Errors:       info.errCount,
+			MissedTicks:  info.missedTicks,
+			Overruns:     info.overruns,
+			Faults:       maps.Clone(info.faults),
+		})
 	}
+	slices.SortFunc(stats, func(a, b api.PayloadStats) int { return strings.Compare(a.PayloadId, b.PayloadId) })
+	return stats
 }
@@ -1,10 +1,15 @@
 package sim
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
 	"net/http"
+	"strconv"
 
+	"github.com/Sapper177/datagensim/api"
 	"github.com/Sapper177/datagensim/pkg/config"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -25,8 +30,36 @@ type payloadMonitorCollector struct {
 	errBytes                   *prometheus.Desc // Changed from Mbs to Bytes
 	totalProcessingTimeSeconds *prometheus.Desc // Total time
 	processedOperations        *prometheus.Desc // Total operations processed
+	commands                   *prometheus.Desc // Commands handled by opcode and status
+	scenarioEvents             *prometheus.Desc // Scenario actions, markers and assertions
+
+	// Per bus, labelled by bus
+	busTx       *prometheus.Desc
+	busRx       *prometheus.Desc
+	busErr      *prometheus.Desc
+	busBytesTx  *prometheus.Desc
+	busBytesRx  *prometheus.Desc
+	busErrBytes *prometheus.Desc
+
+	// Per payload, labelled by bus and payload
+	payloadTx             *prometheus.Desc
+	payloadRx             *prometheus.Desc
+	payloadErr            *prometheus.Desc
+	payloadBytesTx        *prometheus.Desc
+	payloadBytesRx        *prometheus.Desc
+	payloadErrBytes       *prometheus.Desc
+	payloadConfiguredRate *prometheus.Desc
+	payloadAchievedRate   *prometheus.Desc
+	payloadMissedTicks    *prometheus.Desc
+	payloadOverruns       *prometheus.Desc
+	payloadFaults         *prometheus.Desc
 }
 
+var (
+	busLabels     = []string{"bus"}
+	payloadLabels = []string{"bus", "payload"}
+)
+
 // newPayloadMonitorCollector creates a new collector for the given monitor.
 func newPayloadMonitorCollector(monitor *payloadMonitor) *payloadMonitorCollector {
 	return &payloadMonitorCollector{
@@ -85,6 +118,120 @@ func newPayloadMonitorCollector(monitor *payloadMonitor) *payloadMonitorCollecto
 			nil,
 			nil,
 		),
+		commands: prometheus.NewDesc(
+			"app_payload_monitor_commands_total", // Convention: _total
+			"Total number of commands handled, by opcode, name and response status.",
+			[]string{"opcode", "name", "status"},
+			nil,
+		),
+		scenarioEvents: prometheus.NewDesc(
+			"app_scenario_events_total",
+			"Total number of scenario events, by scenario, kind (action, revert, mark, assert) and result.",
+			[]string{"scenario", "kind", "result"},
+			nil,
+		),
+		busTx: prometheus.NewDesc(
+			"app_payload_monitor_bus_transmissions_total",
+			"Total number of transmissions per bus.",
+			busLabels,
+			nil,
+		),
+		busRx: prometheus.NewDesc(
+			"app_payload_monitor_bus_receptions_total",
+			"Total number of receptions per bus.",
+			busLabels,
+			nil,
+		),
+		busErr: prometheus.NewDesc(
+			"app_payload_monitor_bus_errors_total",
+			"Total number of errors per bus.",
+			busLabels,
+			nil,
+		),
+		busBytesTx: prometheus.NewDesc(
+			"app_payload_monitor_bus_bytes_transmitted_total",
+			"Total number of bytes transmitted per bus.",
+			busLabels,
+			nil,
+		),
+		busBytesRx: prometheus.NewDesc(
+			"app_payload_monitor_bus_bytes_received_total",
+			"Total number of bytes received per bus.",
+			busLabels,
+			nil,
+		),
+		busErrBytes: prometheus.NewDesc(
+			"app_payload_monitor_bus_error_bytes_total",
+			"Total number of bytes associated with errors per bus.",
+			busLabels,
+			nil,
+		),
+		payloadTx: prometheus.NewDesc(
+			"app_payload_monitor_payload_transmissions_total",
+			"Total number of transmissions per payload.",
+			payloadLabels,
+			nil,
+		),
+		payloadRx: prometheus.NewDesc(
+			"app_payload_monitor_payload_receptions_total",
+			"Total number of receptions per payload.",
+			payloadLabels,
+			nil,
+		),
+		payloadErr: prometheus.NewDesc(
+			"app_payload_monitor_payload_errors_total",
+			"Total number of errors per payload.",
+			payloadLabels,
+			nil,
+		),
+		payloadBytesTx: prometheus.NewDesc(
+			"app_payload_monitor_payload_bytes_transmitted_total",
+			"Total number of bytes transmitted per payload.",
+			payloadLabels,
+			nil,
+		),
+		payloadBytesRx: prometheus.NewDesc(
+			"app_payload_monitor_payload_bytes_received_total",
+			"Total number of bytes received per payload.",
+			payloadLabels,
+			nil,
+		),
+		payloadErrBytes: prometheus.NewDesc(
+			"app_payload_monitor_payload_error_bytes_total",
+			"Total number of bytes associated with errors per payload.",
+			payloadLabels,
+			nil,
+		),
+		payloadConfiguredRate: prometheus.NewDesc(
+			"app_payload_monitor_payload_configured_rate_hz",
+			"Configured transmission rate of the payload in Hz.",
+			payloadLabels,
+			nil,
+		),
+		payloadAchievedRate: prometheus.NewDesc(
+			"app_payload_monitor_payload_achieved_rate_hz",
+			"Achieved transmission rate of the payload in Hz (moving average).",
+			payloadLabels,
+			nil,
+		),
+		payloadMissedTicks: prometheus.NewDesc(
+			"app_payload_monitor_payload_missed_ticks_total",
+			"Total number of scheduled ticks skipped (merged) because the payload fell behind.",
+			payloadLabels,
+			nil,
+		),
+		payloadOverruns: prometheus.NewDesc(
+			"app_payload_monitor_payload_overruns_total",
+			"Total number of payload builds that took longer than the payload period.",
+			payloadLabels,
+			nil,
+		),
+		payloadFaults: prometheus.NewDesc(
+			"app_payload_monitor_payload_faults_total",
+			"Total number of injected faults per payload, by kind.",
+			[]string{"bus", "payload", "fault"},
+			nil,
+		),
 	}
 }
 
@@ -100,6 +247,27 @@ func (collector *payloadMonitorCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- collector.errBytes
 	ch <- collector.totalProcessingTimeSeconds
 	ch <- collector.processedOperations
+	ch <- collector.commands
+	ch <- collector.scenarioEvents
+	ch <- collector.busTx
+	ch <- collector.busRx
+	ch <- collector.busErr
+	ch <- collector.busBytesTx
+	ch <- collector.busBytesRx
+	ch <- collector.busErrBytes
+	ch <- collector.payloadTx
+	ch <- collector.payloadRx
+	ch <- collector.payloadErr
+	ch <- collector.payloadBytesTx
+	ch <- collector.payloadBytesRx
+	ch <- collector.payloadErrBytes
+	ch <- collector.payloadConfiguredRate
+	ch <- collector.payloadAchievedRate
+	ch <- collector.payloadMissedTicks
+	ch <- collector.payloadOverruns
+	ch <- collector.payloadFaults
+	collector.monitor.latency.Describe(ch)
+	collector.monitor.jitter.Describe(ch)
 }
 
 // Collect reads the current state and sends metrics to the provided channel.
@@ -113,12 +281,29 @@ func (collector *payloadMonitorCollector) Collect(ch chan<- prometheus.Metric) {
 	numRx := collector.monitor.numRx
 	numErr := collector.monitor.numErr
 
-	// Convert MB back to Bytes for the metric value
-	bytesTx := collector.monitor.numMbsTx * 1024 * 1024
-	bytesRx := collector.monitor.numMbsRx * 1024 * 1024
-	errBytes := collector.monitor.numErrMbs * 1024 * 1024
+	bytesTx := float64(collector.monitor.numBytesTx)
+	bytesRx := float64(collector.monitor.numBytesRx)
+	errBytes := float64(collector.monitor.numErrBytes)
 	totalProcessingTimeSeconds := collector.monitor.totalProcessingTime.Seconds() // Convert Duration to seconds
 	processedOperations := collector.monitor.processedOperations
+	commands := make(map[commandKey]int, len(collector.monitor.commands))
+	for k, v := range collector.monitor.commands {
+		commands[k] = v
+	}
+	scenarioEvents := make(map[scenarioKey]int, len(collector.monitor.scenarioEvents))
+	for k, v := range collector.monitor.scenarioEvents {
+		scenarioEvents[k] = v
+	}
+	buses := make(map[string]busInfo, len(collector.monitor.busMap))
+	for k, v := range collector.monitor.busMap {
+		buses[k] = *v
+	}
+	payloads := make(map[payloadKey]payloadInfo, len(collector.monitor.payloadMap))
+	for k, v := range collector.monitor.payloadMap {
+		p := *v
+		p.faults = maps.Clone(v.faults)
+		payloads[k] = p
+	}
 	collector.monitor.mu.RUnlock()
 
 	// Send the collected data as Prometheus metrics
@@ -167,20 +352,53 @@ func (collector *payloadMonitorCollector) Collect(ch chan<- prometheus.Metric) {
 		prometheus.CounterValue, // Total operations is cumulative
 		float64(processedOperations),
 	)
+	for k, v := range commands {
+		ch <- prometheus.MustNewConstMetric(
+			collector.commands,
+			prometheus.CounterValue,
+			float64(v),
+			strconv.Itoa(int(k.opcode)), k.name, k.status,
+		)
+	}
+	for k, v := range scenarioEvents {
+		ch <- prometheus.MustNewConstMetric(collector.scenarioEvents, prometheus.CounterValue, float64(v), k.scenario, k.kind, k.result)
+	}
+
+	for bus, b := range buses {
+		ch <- prometheus.MustNewConstMetric(collector.busTx, prometheus.CounterValue, float64(b.txCount), bus)
+		ch <- prometheus.MustNewConstMetric(collector.busRx, prometheus.CounterValue, float64(b.rxCount), bus)
+		ch <- prometheus.MustNewConstMetric(collector.busErr, prometheus.CounterValue, float64(b.errCount), bus)
+		ch <- prometheus.MustNewConstMetric(collector.busBytesTx, prometheus.CounterValue, float64(b.txBytes), bus)
+		ch <- prometheus.MustNewConstMetric(collector.busBytesRx, prometheus.CounterValue, float64(b.rxBytes), bus)
+		ch <- prometheus.MustNewConstMetric(collector.busErrBytes, prometheus.CounterValue, float64(b.errBytes), bus)
+	}
+
+	for k, p := range payloads {
+		ch <- prometheus.MustNewConstMetric(collector.payloadTx, prometheus.CounterValue, float64(p.txCount), k.bus, k.payload)
+		ch <- prometheus.MustNewConstMetric(collector.payloadRx, prometheus.CounterValue, float64(p.rxCount), k.bus, k.payload)
+		ch <- prometheus.MustNewConstMetric(collector.payloadErr, prometheus.CounterValue, float64(p.errCount), k.bus, k.payload)
+		ch <- prometheus.MustNewConstMetric(collector.payloadBytesTx, prometheus.CounterValue, float64(p.txBytes), k.bus, k.payload)
+		ch <- prometheus.MustNewConstMetric(collector.payloadBytesRx, prometheus.CounterValue, float64(p.rxBytes), k.bus, k.payload)
+		ch <- prometheus.MustNewConstMetric(collector.payloadErrBytes, prometheus.CounterValue, float64(p.errBytes), k.bus, k.payload)
+		ch <- prometheus.MustNewConstMetric(collector.payloadConfiguredRate, prometheus.GaugeValue, p.configuredRate, k.bus, k.payload)
+		ch <- prometheus.MustNewConstMetric(collector.payloadAchievedRate, prometheus.GaugeValue, p.achievedRate, k.bus, k.payload)
+		ch <- prometheus.MustNewConstMetric(collector.payloadMissedTicks, prometheus.CounterValue, float64(p.missedTicks), k.bus, k.payload)
+		ch <- prometheus.MustNewConstMetric(collector.payloadOverruns, prometheus.CounterValue, float64(p.overruns), k.bus, k.payload)
+		for kind, n := range p.faults {
+			ch <- prometheus.MustNewConstMetric(collector.payloadFaults, prometheus.CounterValue, float64(n), k.bus, k.payload, kind)
+		}
+	}
+
+	collector.monitor.latency.Collect(ch)
+	collector.monitor.jitter.Collect(ch)
 
 	// Note: To get the average processing time in Grafana, you'd query
 	// `rate(app_payload_monitor_processing_time_seconds_total[5m]) / rate(app_payload_monitor_processed_operations_total[5m])`
 	// (adjusting the time window [5m] as needed)
 }
 
-// Initialize the Prometheus HTTP handler
-func initMonitoring(cfg *config.Config, infoChan <-chan packetInfo) {
-	// Create application's monitor instance
-	monitor := newPayloadMonitor()
-
-	// Start payload monitor
-	go procPayloadMon(cfg, monitor, infoChan)
-
+// Initialize the Prometheus HTTP handler and serve it until ctx is done
+func initMonitoring(ctx *context.Context, cfg *config.Config, monitor *payloadMonitor, ctrl *apiController) error {
 	// Create the Prometheus collector for monitor
 	collector := newPayloadMonitorCollector(monitor)
 
@@ -190,7 +408,8 @@ func initMonitoring(cfg *config.Config, infoChan <-chan packetInfo) {
 
 	// Set up the HTTP server to expose metrics
 	//    Use promhttp.HandlerFor with custom registry
-	http.Handle("/metrics", promhttp.HandlerFor(
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(
 		registry,
 		promhttp.HandlerOpts{
 			// Optional: enable metrics about the handler itself
@@ -198,11 +417,37 @@ func initMonitoring(cfg *config.Config, infoChan <-chan packetInfo) {
 		},
 	))
 
+	// Control API and live view share the metrics server
+	mux.Handle(api.Prefix+"/", api.NewHandler(ctrl))
+	// the server neither waits for nor cancels the hijacked live view
+	// connections on shutdown, they end with it here
+	liveCtx, stopLive := context.WithCancel(*ctx)
+	defer stopLive()
+	live, err := api.NewLiveHandler(liveCtx, ctrl, ctrl, ctrl)
+	if err != nil {
+		return err
+	}
+	mux.Handle(api.UIPrefix, live)
+
 	// Start the HTTP server
 	listenAddr := ":8080"
 	if cfg.MetricsPort != 0 {
 		listenAddr = fmt.Sprintf(":%d", cfg.MetricsPort)
 	}
-	fmt.Printf("Serving metrics on http://localhost%s/metrics\n", listenAddr)
-	log.Fatal(http.ListenAndServe(listenAddr, nil))
+	srv := &http.Server{Addr: listenAddr, Handler: mux}
+	srv.RegisterOnShutdown(stopLive)
+	go func() {
+		<-(*ctx).Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			srv.Close()
+		}
+	}()
+
+	slog.Info("serving metrics, control API and live view", "addr", listenAddr, "api", api.Prefix, "live", api.UIPrefix)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
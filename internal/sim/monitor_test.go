@@ -0,0 +1,88 @@
+package sim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gather collects the metrics of m labelled with payload, by name. Counters
+// and gauges report their value, summaries their sample count and sum.
+func gather(t *testing.T, m *payloadMonitor, payload string) map[string][]float64 {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(newPayloadMonitorCollector(m))
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]float64)
+	for _, f := range families {
+		for _, metric := range f.GetMetric() {
+			for _, l := range metric.GetLabel() {
+				if l.GetName() != "payload" || l.GetValue() != payload {
+					continue
+				}
+				switch {
+				case metric.Counter != nil:
+					got[f.GetName()] = []float64{metric.GetCounter().GetValue()}
+				case metric.Summary != nil:
+					s := metric.GetSummary()
+					got[f.GetName()] = []float64{float64(s.GetSampleCount()), s.GetSampleSum()}
+				}
+			}
+		}
+	}
+	return got
+}
+
+func TestMonitorAddInfo(t *testing.T) {
+	m := newPayloadMonitor()
+	t0 := time.Unix(1000, 0)
+	tx := func(slip time.Duration, merged int, overrun, failed bool) packetInfo {
+		return packetInfo{
+			Bus: "A", Payload: "nav", PacketSize: 10, Direction: true, Error: failed,
+			TxTime: t0, Slip: slip, Merged: merged, Overrun: overrun,
+		}
+	}
+	m.addInfo(tx(time.Millisecond, 0, false, false))
+	// late, two ticks merged into this one
+	m.addInfo(tx(25*time.Millisecond, 2, false, false))
+	// built past its period
+	m.addInfo(tx(3*time.Millisecond, 0, true, false))
+	// a failed write still missed its ticks and overran, but was never sent
+	m.addInfo(tx(time.Second, 1, true, true))
+	// another payload id that would parse to the same number
+	m.addInfo(packetInfo{Bus: "A", Payload: "0x0", Direction: true, Merged: 5})
+
+	got := gather(t, m, "nav")
+	want := map[string][]float64{
+		"app_payload_monitor_payload_transmissions_total": {3},
+		"app_payload_monitor_payload_errors_total":        {1},
+		"app_payload_monitor_payload_missed_ticks_total":  {3},
+		"app_payload_monitor_payload_overruns_total":      {2},
+		"app_payload_monitor_schedule_slip_seconds":       {3, 0.029},
+	}
+	for name, w := range want {
+		g, ok := got[name]
+		if !ok {
+			t.Errorf("%s not collected", name)
+			continue
+		}
+		for i := range w {
+			if d := g[i] - w[i]; d > 1e-9 || d < -1e-9 {
+				t.Errorf("%s = %v, want %v", name, g, w)
+				break
+			}
+		}
+	}
+
+	stats := m.stats("A")
+	if len(stats) != 2 || stats[1].PayloadId != "nav" || stats[1].MissedTicks != 3 || stats[1].Overruns != 2 {
+		t.Errorf("stats = %+v, want nav with 3 missed ticks and 2 overruns", stats)
+	}
+	if stats[0].PayloadId != "0x0" || stats[0].MissedTicks != 5 {
+		t.Errorf("stats = %+v, want 0x0 apart with 5 missed ticks", stats)
+	}
+}
@@ -1,8 +1,13 @@
 package sim
 
+import "time"
+
 type Packet struct {
     Protocol string // "udp" or "tcp"
     SrcPort  int
     DstPort  int
     Payload  []byte
+    BuildTime time.Duration // time taken to build and assemble the payload
+    Scheduled time.Time     // tick slot the payload was built for
+    Merged    int           // ticks skipped since the previous payload
 }
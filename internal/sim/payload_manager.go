@@ -1,18 +1,25 @@
 package sim
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
-	"log"
-	"math"
+	"log/slog"
+	"maps"
 	"net"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Sapper177/datagensim/api"
 	"github.com/Sapper177/datagensim/ext/definitions"
 	"github.com/Sapper177/datagensim/pkg/config"
 	"github.com/Sapper177/datagensim/pkg/database"
 	"github.com/Sapper177/datagensim/pkg/engine"
+	"github.com/Sapper177/datagensim/pkg/pktgen"
 
 	"github.com/google/gopacket/layers"
 )
@@ -33,11 +40,18 @@ type payloadManager struct {
 	srcPort layers.UDPPort
 	dstPort layers.UDPPort
 	srcMAC  net.HardwareAddr
+	sender  pktgen.Sender // shared bus transport
+	proto   string        // packet_type from the payload info
+	ctx     *context.Context
+	db      *database.RedisClient
 
 	// payload info
-	id       uint // payload id
+	bus      string
+	key      string // payload id as stored
+	id       uint   // payload id
 	freq     time.Duration
 	dpMap    map[string]dataPoint // data id -> data point
+	plan     *updatePlan          // how each data point gets its value
 	size     uint16               //payload size
 	pBuf     []byte
 	lastProc time.Time
@@ -56,198 +70,304 @@ type payloadManager struct {
 
 	// combined full payload
 	payload []byte
+
+	// receive state
+	seqOff     int // byte offset of the header sequence counter, -1 if none
+	rxSeq      uint16
+	rxSeqValid bool
+
+	// runtime control
+	cfg    *config.Config
+	state  string            // running, paused or stopped
+	forced map[string]string // data id -> overridden value
+	ticks  *tickTracker
+	tick   []api.Value // values emitted on the current tick, for streaming
+
+	// when the payload emits
+	sched     scheduleConfig
+	sent      int         // scheduled packets emitted since the payload last started
+	burstLeft int         // ticks left in the current burst
+	wake      *time.Timer // starts a delayed payload or the next burst
+	end       *time.Timer // stops the payload after its duration
+
+	// impairments between assembly and the sender
+	faultCfg faultConfig
+	faults   *impairer // nil while no fault is configured
+
+	def payloadDef // stored definition, to tell what a reload changes
+}
+
+// payloadDef is the stored definition a payload manager was built from
+type payloadDef struct {
+	info   map[string]string // payload info
+	faults map[string]string
+	data   map[string]string // data id -> definition of the data point, without its value
+}
+
+func (d payloadDef) equal(o payloadDef) bool {
+	return maps.Equal(d.info, o.info) && maps.Equal(d.faults, o.faults) && maps.Equal(d.data, o.data)
 }
 
-func newPayloadManager(cfg *config.Config, id string, fs time.Duration, db *database.RedisClient) *payloadManager {
+// newPayloadManager loads payload id from the store. Its expressions and plant
+// inputs see the data points of shared, nil for a payload loaded on its own.
+func newPayloadManager(cfg *config.Config, id string, fs time.Duration, db *database.RedisClient, sender pktgen.Sender, shared *sharedState) (*payloadManager, error) {
 	//----- Generate the payload data points -----
 	// Get the list of data ids from the database
 	dataids, err := db.GetPayloadData(id)
 	if err != nil {
-		log.Fatalf("Error getting payload data ids for ID (%s): %s", id, err)
+		return nil, fmt.Errorf("error getting payload data ids for ID (%s): %s", id, err)
 	}
 	// Create a map to hold the data points
 	dps := make(map[string]dataPoint, len(dataids))
+	defs := make(map[string]string, len(dataids))
 
-	for i := range dataids {
-		// Get the data point info from the database
-		dataInfo, err := db.GetData(dataids[i])
+	for _, dataId := range dataids {
+		dp, def, err := newStoredDataPoint(db, id, dataId)
 		if err != nil {
-			log.Fatalf("Error getting data point info for ID (%s): %s", id, err)
+			return nil, err
 		}
-		// Get extra info about data point from the database
-		dInfo, err := db.GetDataInfo(dataids[i])
-		if err != nil {
-			log.Fatalf("Error getting data point info for ID (%s): %s", id, err)
-		}
-		// Extract data from Database
-		dbEx := newDBExtract(id, dataids[i], dataInfo, dInfo)
-
-		dtype := selectDtype(dbEx.dtype)
-
-		switch dtype {
-		case 0:
-			datapoint := newBoolDataPoint(
-				engine.NewBoolEngine(time.Duration(dbEx.freq)),
-				dbEx.offset,
-				dbEx.size,
-			)
-			dps[dataids[i]] = datapoint
-
-		case 1, 2, 3, 4, 5, 6, 7, 8, 9, 10:
-			// Translate the data point info to the correct type
-			min, err := strconv.ParseInt(dbEx.min, 0, 64)
-			if err != nil {
-				log.Printf("Error converting min for Payload (%s) - data ID (%s): %s", id, dataids[i], err)
-			}
-			max, err := strconv.ParseInt(dbEx.max, 0, 64)
-			if err != nil {
-				log.Printf("Error converting max for Payload (%s) - data ID (%s): %s", id, dataids[i], err)
-			}
-			step, err := strconv.ParseInt(dbEx.step, 0, 64)
-			if err != nil {
-				log.Printf("Error converting step for Payload (%s) - data ID (%s): %s", id, dataids[i], err)
-			}
-
-			eng := engine.NewNumEngInt(
-				float32(min),
-				float32(max),
-				float32(step),
-				time.Duration(dbEx.freq),
-				float32(config.PHASE_DEFAULT),
-				"sin",
-			)
-
-			datapoint := newDataPoint32(
-				dtype,
-				eng,
-				dbEx.offset,
-				dbEx.size,
-			)
-			dps[dataids[i]] = datapoint
-
-		case 11, 12:
-			// Translate the data point info to the correct type
-			min, err := strconv.ParseFloat(dbEx.min, 64)
-			if err != nil {
-				log.Printf("Error converting min for Payload (%s) - data ID (%s): %s", id, dataids[i], err)
-			}
-			max, err := strconv.ParseFloat(dbEx.max, 64)
-			if err != nil {
-				log.Printf("Error converting max for Payload (%s) - data ID (%s): %s", id, dataids[i], err)
-			}
-			step, err := strconv.ParseFloat(dbEx.step, 64)
-			if err != nil {
-				log.Printf("Error converting step for Payload (%s) - data ID (%s): %s", id, dataids[i], err)
-			}
+		dps[dataId], defs[dataId] = dp, def
+	}
+	payId, err := strconv.ParseUint(id, 0, 32)
+	if err != nil {
+		slog.Warn("payload id is not a number", "payload", id, "err", err)
+	}
 
-			eng := engine.NewNumEng64(
-				min,
-				max,
-				step,
-				time.Duration(dbEx.freq),
-				config.PHASE_DEFAULT,
-				"sin",
-			)
-
-			datapoint := newDataPointFloat(
-				dtype,
-				eng,
-				dbEx.offset,
-				dbEx.size,
-			)
-			dps[dataids[i]] = datapoint
-
-		case 13, 14:
-			eng := engine.NewStrEngine(dbEx.size, dbEx.freq, config.PHASE_DEFAULT)
-			datapoint := newStrDataPoint(dtype, eng, dbEx.offset, dbEx.size)
-			dps[dataids[i]] = datapoint
+	external := newExternalValues()
+	if shared != nil {
+		external = shared.external
+	}
+	plan, err := newUpdatePlan(db, dps, shared, external)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data points for Payload (%s): %s", id, err)
+	}
 
-		default:
-			log.Printf("Unknown data type for Payload (%s) - data ID (%s): %s", id, dataids[i], dbEx.dtype)
-		}
+	// get optional impairments
+	faultParams, err := db.GetPayloadFaults(id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting faults for Payload (%s): %s", id, err)
 	}
-	payId, err := strconv.ParseUint(id, 0, 32)
+	faultCfg, err := parseFaults(faultParams)
 	if err != nil {
-		log.Printf("Error converting id for Payload (%s): %s", id, err)
+		return nil, fmt.Errorf("invalid faults for Payload (%s): %s", id, err)
 	}
 
-	// get payload data size
-	size := calcPayloadSize(dps)
+	pm := &payloadManager{
+		src:      cfg.SrcHost,
+		dst:      cfg.DestHost,
+		srcPort:  layers.UDPPort(cfg.SrcPort),
+		dstPort:  layers.UDPPort(cfg.DestPort),
+		srcMAC:   cfg.Interface.HardwareAddr,
+		sender:   sender,
+		bus:      cfg.BusName,
+		key:      id,
+		id:       uint(payId),
+		freq:     fs,
+		dpMap:    dps,
+		plan:     plan,
+		state:    payloadRunning,
+		forced:   make(map[string]string),
+		faultCfg: faultCfg,
+		def:      payloadDef{faults: faultParams, data: defs},
+	}
+	pm.layout()
+	return pm, nil
+}
 
-	payloadBuf := make([]byte, size)
+// layout sizes the buffers for the data points and creates the header and
+// footer around the data. Every tick is built into these buffers.
+func (pm *payloadManager) layout() {
+	// get payload data size
+	pm.size = calcPayloadSize(pm.dpMap)
+	pm.pBuf = make([]byte, pm.size)
 
 	// initialize header variables
-	header := definitions.NewUdpHeader(uint32(payId), size )
+	pm.header = definitions.NewUdpHeader(uint32(pm.id))
 
-	hSize, err := calcHeaderSize(*header)
+	hSize, err := calcHeaderSize(*pm.header)
 	if err != nil {
-		log.Printf("Error calculating error size: %s", err)
-	}
-	hBuf := make([]byte, hSize)
-
-	// create tmp payload buffer for footer
-	totalPayloadSize := size + hSize
-
-	
-	// footer := definitions.NewUdpFooter(uint32(payId))
-
-	// hSize, err := calcHeaderSize(*header)
-	// if err != nil {
-	// 	log.Printf("Error calculating error size: %s", err)
-	// }
-	// hBuf := make([]byte, hSize)
-
-	return &payloadManager{
-		src:     cfg.SrcHost,
-		dst:     cfg.DestHost,
-		srcPort: layers.UDPPort(cfg.SrcPort),
-		dstPort: layers.UDPPort(cfg.DestPort),
-		srcMAC:  cfg.Interface.HardwareAddr,
-		id:      uint(payId),
-		freq:    fs,
-		dpMap:   dps,
-		header:  header,
-		size:    size,
-		pBuf:    payloadBuf,
-		hBuf:    hBuf,
+		slog.Error("calculating header size", "payload", pm.key, "err", err)
 	}
+	pm.hsize = hSize
+	pm.hBuf = make([]byte, hSize)
+
+	pm.seqOff, err = pm.header.SequenceOffset()
+	if err != nil {
+		pm.seqOff = -1
+	}
+
+	// initialize footer, its checksum covers the header and data
+	totalPayloadSize := pm.size + hSize
+	fSize, err := calcHeaderSize(definitions.Header(*definitions.NewUdpFooter(nil)))
+	if err != nil {
+		slog.Error("calculating footer size", "payload", pm.key, "err", err)
+	}
+	pm.fsize = fSize
+	pm.payload = make([]byte, int(totalPayloadSize)+int(fSize))
+	pm.footer = definitions.NewUdpFooter(pm.payload[:totalPayloadSize])
+	pm.fBuf = pm.payload[totalPayloadSize:]
 }
 
-func (pm *payloadManager) getHeader() error {
-	if pm.header == nil {
-		return fmt.Errorf("no header found in payload manager - ID: (%d)", pm.id)
+// newStoredDataPoint creates data point dataId of payload id from its
+// definition in the store, which it also returns without the value
+func newStoredDataPoint(db *database.RedisClient, id string, dataId string) (dataPoint, string, error) {
+	// Get the data point info from the database
+	dataInfo, err := db.GetData(dataId)
+	if err != nil {
+		return nil, "", fmt.Errorf("error getting data point info for ID (%s): %s", id, err)
+	}
+	// Get extra info about data point from the database
+	dInfo, err := db.GetDataInfo(dataId)
+	if err != nil {
+		return nil, "", fmt.Errorf("error getting data point info for ID (%s): %s", id, err)
+	}
+	dp, err := buildDataPoint(id, dataId, dataInfo, dInfo)
+	if err != nil {
+		return nil, "", err
+	}
+	// start from the stored value, when the data point can hold it
+	dp.set(dataInfo["value"])
+	return dp, dataDefinition(dataInfo, dInfo), nil
+}
+
+// dataDefinition flattens the stored definition of a data point, without
+// its value, so that definitions compare as strings
+func dataDefinition(data map[string]string, info map[string]string) string {
+	var b strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(data)) {
+		if k != "value" {
+			fmt.Fprintf(&b, "%s=%q ", k, data[k])
+		}
+	}
+	b.WriteString("|")
+	for _, k := range slices.Sorted(maps.Keys(info)) {
+		fmt.Fprintf(&b, " %s=%q", k, info[k])
+	}
+	return b.String()
+}
+
+// buildDataPoint creates data point dataId of payload id from its data and info hashes
+func buildDataPoint(id string, dataId string, dataInfo map[string]string, dInfo map[string]string) (dataPoint, error) {
+	// Extract data from Database
+	dbEx := newDBExtract(id, dataId, dataInfo, dInfo)
+	if dbEx == nil {
+		return nil, fmt.Errorf("invalid data point definition for Payload (%s) - data ID (%s)", id, dataId)
 	}
 
-	// initialize byte index
-	idx := 0
+	dtype := selectDtype(dbEx.dtype)
+
+	switch dtype {
+	case 0:
+		datapoint := newBoolDataPoint(
+			engine.NewBoolEngine(time.Duration(dbEx.freq)),
+			dbEx.offset,
+			dbEx.size,
+		)
+		return datapoint, nil
 
-	// Iterate through each element in the header
-	for i, element := range pm.header.Elements {
-		if element == nil {
-			return fmt.Errorf("header element at index %d is nil", i)
+	case 1, 2, 3, 4, 5, 6, 7, 8, 9, 10:
+		// Translate the data point info to the correct type
+		min, err := strconv.ParseInt(dbEx.min, 0, 64)
+		if err != nil {
+			slog.Warn("invalid min", "payload", id, "data", dataId, "err", err)
 		}
-		// Call the Bytes() method on the element to get its byte representation
-		elementBytes, s, err := element.Bytes()
+		max, err := strconv.ParseInt(dbEx.max, 0, 64)
 		if err != nil {
-			// Return an informative error if getting bytes fails for any element
-			return fmt.Errorf("failed to get bytes for header element at index %d: %w", i, err)
+			slog.Warn("invalid max", "payload", id, "data", dataId, "err", err)
+		}
+		step, err := strconv.ParseInt(dbEx.step, 0, 64)
+		if err != nil {
+			slog.Warn("invalid step", "payload", id, "data", dataId, "err", err)
 		}
 
-		// check if there is enough room for element to be added in
-		add := int(s)
-		if math.MaxUint16-idx < add {
-			return fmt.Errorf("not enough room in buffer for element at index %d", i)
+		eng := engine.NewNumEngInt(
+			float32(min),
+			float32(max),
+			float32(step),
+			time.Duration(dbEx.freq),
+			float32(config.PHASE_DEFAULT),
+			"sin",
+		)
+
+		datapoint := newDataPoint32(
+			dtype,
+			eng,
+			dbEx.offset,
+			dbEx.size,
+		)
+		return datapoint, nil
+
+	case 11, 12:
+		// Translate the data point info to the correct type
+		min, err := strconv.ParseFloat(dbEx.min, 64)
+		if err != nil {
+			slog.Warn("invalid min", "payload", id, "data", dataId, "err", err)
+		}
+		max, err := strconv.ParseFloat(dbEx.max, 64)
+		if err != nil {
+			slog.Warn("invalid max", "payload", id, "data", dataId, "err", err)
+		}
+		step, err := strconv.ParseFloat(dbEx.step, 64)
+		if err != nil {
+			slog.Warn("invalid step", "payload", id, "data", dataId, "err", err)
 		}
 
-		// put data into buffer at current idx
-		copy(pm.hBuf[idx:], elementBytes)
+		eng := engine.NewNumEng64(
+			min,
+			max,
+			step,
+			time.Duration(dbEx.freq),
+			config.PHASE_DEFAULT,
+			"sin",
+		)
+
+		datapoint := newDataPointFloat(
+			dtype,
+			eng,
+			dbEx.offset,
+			dbEx.size,
+		)
+		return datapoint, nil
+
+	case 13, 14:
+		eng := engine.NewStrEngine(dbEx.size, dbEx.freq, config.PHASE_DEFAULT)
+		datapoint := newStrDataPoint(dtype, eng, dbEx.offset, dbEx.size)
+		return datapoint, nil
+
+	default:
+		return nil, fmt.Errorf("unknown data type for Payload (%s) - data ID (%s): %s", id, dataId, dbEx.dtype)
+	}
+}
 
-		idx += add
+func (pm *payloadManager) getHeader() error {
+	if pm.header == nil {
+		return fmt.Errorf("no header found in payload manager - ID: (%d)", pm.id)
+	}
+
+	_, err := writeElements(pm.hBuf, pm.header.Elements)
+	if err != nil {
+		return fmt.Errorf("header: %w", err)
 	}
 	return nil
 }
 
-func (pm *payloadManager) buildPayload(ctx *context.Context, db *database.RedisClient) error {
+// assemblePayload combines the header, data and footer into pm.payload.
+// The footer is written last so that checksums cover the header and data.
+func (pm *payloadManager) assemblePayload() ([]byte, error) {
+	idx := copy(pm.payload, pm.hBuf)
+	copy(pm.payload[idx:], pm.pBuf)
+	if pm.footer != nil {
+		if _, err := writeElements(pm.fBuf, pm.footer.Elements); err != nil {
+			return nil, fmt.Errorf("footer: %w", err)
+		}
+	}
+	return pm.payload, nil
+}
+
+// buildPayload moves every data point to its next value and writes the
+// header and data into their buffers. In steady state it allocates nothing,
+// unless values are being streamed.
+func (pm *payloadManager) buildPayload() error {
 	// clear out buffer
 	for i := range pm.pBuf {
 		pm.pBuf[i] = 0
@@ -256,86 +376,356 @@ func (pm *payloadManager) buildPayload(ctx *context.Context, db *database.RedisC
 	// get payload header
 	err := pm.getHeader()
 	if err != nil {
-		return fmt.Errorf("error retrieving payload header for ID (%s): %s", pm.id, err)
+		return fmt.Errorf("error retrieving payload header for ID (%d): %s", pm.id, err)
 	}
 
+	// collect emitted values only while someone is streaming them
+	pm.tick = pm.tick[:0]
+	stream := pm.cs.values.wants(pm.bus, pm.key)
+
 	// loop through datapoints and append data by offset and size
-	for id, dp := range pm.dpMap {
-		d, err := db.GetData(id)
-		if err != nil {
-			return fmt.Errorf("error getting data point info for ID (%s): %s", pm.id, err)
+	now := time.Now()
+	for _, id := range pm.plan.order {
+		dp := pm.dpMap[id]
+		// bus-level data points take the value of the bus tick, converted to
+		// the layout of this payload
+		if !pm.cs.shared.sample(id, dp) {
+			if err := pm.plan.next(id, dp, now); err != nil {
+				return fmt.Errorf("error updating %s: %s", id, err)
+			}
+		}
+		// forced values override the engine, which carries on from them
+		f, forced := pm.forced[id]
+		if forced {
+			dp.set(f)
+		}
+		if stream {
+			pm.tick = append(pm.tick, api.Value{Id: id, Value: string(dp.appendValue(nil)), Forced: forced})
 		}
-		// get new value
-		oldVal := d["value"]
-		newVal, str := dp.update(oldVal)
-
-		// update db with new value
-		d["value"] = str
-		db.UpdateData(id, d)
 
 		// append data by offset and size
-		err = dp.appendData(pm.pBuf, newVal)
+		err = dp.writeData(pm.pBuf)
 		if err != nil {
-			return fmt.Errorf("error building data for %d: %s", id, err)
+			return fmt.Errorf("error building data for %s: %s", id, err)
 		}
 	}
 	return nil
 }
 
-func manager(ctx *context.Context, cfg *config.Config, cs PayloadChans, id string, pktType string, infoChan chan<- packetInfo) {
+// emit builds and assembles the payload for the scheduled time and queues a
+// copy for sending. merged is the number of ticks skipped before this one.
+func (pm *payloadManager) emit(scheduled time.Time, merged int) error {
+	start := time.Now()
+	pm.cs.shared.at(scheduled)
+	if err := pm.buildPayload(); err != nil {
+		return fmt.Errorf("build: %w", err)
+	}
+	payload, err := pm.assemblePayload()
+	if err != nil {
+		return fmt.Errorf("assemble: %w", err)
+	}
+	// queue a copy in a pooled buffer, the payload buffer is reused on the next tick
+	pkt := Packet{
+		Protocol: pm.proto,
+		SrcPort:  int(pm.srcPort),
+		DstPort:  int(pm.dstPort),
+		Payload:  pm.cs.buffers.get(len(payload)),
+	}
+	copy(pkt.Payload, payload)
+	pkt.BuildTime = time.Since(start)
+	pkt.Scheduled = scheduled
+	pkt.Merged = merged
+	if len(pm.tick) > 0 {
+		pm.publish(start)
+	}
+	if pm.faults == nil {
+		return pm.queue(pkt)
+	}
+
+	// impaired packets may be dropped, duplicated or queued later
+	var qErr error
+	now := time.Now()
+	for _, d := range pm.faults.apply(pkt) {
+		if d.delay > 0 {
+			pm.cs.delayed.push(d.pkt, now.Add(d.delay))
+		} else if err := pm.queue(d.pkt); err != nil {
+			qErr = err
+		}
+	}
+	return qErr
+}
+
+// queue hands a packet to the manager loop for sending without blocking. A
+// packet the write queue has no room for is dropped.
+func (pm *payloadManager) queue(pkt Packet) error {
+	select {
+	case pm.cs.writeChan <- pkt:
+		return nil
+	default:
+		pm.cs.buffers.put(pkt.Payload)
+		return fmt.Errorf("write queue full, dropping payload (%d)", pm.id)
+	}
+}
+
+// releaseDelayed queues the impaired packets that are due. Callers hold cs.mu.
+func (pm *payloadManager) releaseDelayed() {
+	pm.cs.delayed.release(time.Now(), func(pkt Packet) {
+		if err := pm.queue(pkt); err != nil {
+			pm.cs.logger.Error("queueing delayed packet", "err", err)
+		}
+	})
+}
+
+// setFaults replaces the impairment configuration. Runs on the manager goroutine.
+func (pm *payloadManager) setFaults(cfg faultConfig) {
+	pm.faultCfg = cfg
+	if !cfg.active() {
+		pm.faults = nil
+		return
+	}
+	monitor, bus, key := pm.cs.monitor, pm.bus, pm.key
+	pm.faults = newImpairer(cfg, pm, func(kind string) {
+		monitor.addFault(bus, key, kind)
+	})
+}
+
+// publish hands the values collected by buildPayload to stream subscribers
+func (pm *payloadManager) publish(t time.Time) {
+	values := slices.Clone(pm.tick)
+	slices.SortFunc(values, func(a, b api.Value) int { return strings.Compare(a.Id, b.Id) })
+	pm.cs.values.publish(api.Tick{Bus: pm.bus, PayloadId: pm.key, Time: t, Values: values})
+}
+
+// valueSetter writes the values of data points to the store
+type valueSetter interface {
+	SetValues(values map[string]string) error
+}
+
+// processPacket verifies a received payload against this payload definition,
+// decodes every data point and writes the decoded values back into the store,
+// in one round trip once cs.mu is released. Data points that fail to decode
+// and a sequence gap are reported as an error after the rest has been stored.
+// Runs on the manager goroutine.
+func (pm *payloadManager) processPacket(pkt Packet, db valueSetter) error {
+	pm.cs.mu.Lock()
+	values, err := pm.decode(pkt)
+	pm.cs.mu.Unlock()
+	if len(values) == 0 {
+		return err
+	}
+	if serr := db.SetValues(values); serr != nil {
+		return errors.Join(err, fmt.Errorf("payload (%s) storing data: %w", pm.key, serr))
+	}
+	return err
+}
+
+// decode verifies a received payload and returns the values of its data
+// points as stored. Callers hold cs.mu.
+func (pm *payloadManager) decode(pkt Packet) (map[string]string, error) {
+	body := int(pm.hsize) + int(pm.size)
+	if len(pkt.Payload) != body+int(pm.fsize) {
+		return nil, fmt.Errorf("payload (%d) length %d, expected %d", pm.id, len(pkt.Payload), body+int(pm.fsize))
+	}
+
+	// verify the footer against one computed over the received header and data
+	if pm.fsize > 0 {
+		expected := make([]byte, pm.fsize)
+		footer := definitions.NewUdpFooter(pkt.Payload[:body])
+		if _, err := writeElements(expected, footer.Elements); err != nil {
+			return nil, fmt.Errorf("payload (%d) footer: %w", pm.id, err)
+		}
+		if !bytes.Equal(expected, pkt.Payload[body:]) {
+			return nil, fmt.Errorf("payload (%d) checksum mismatch", pm.id)
+		}
+	}
+
+	// verify the sequence number follows the last one received
+	var seqErr error
+	if pm.seqOff >= 0 {
+		seq := binary.BigEndian.Uint16(pkt.Payload[pm.seqOff:])
+		if pm.rxSeqValid && seq != pm.rxSeq+1 {
+			seqErr = fmt.Errorf("payload (%d) sequence gap: expected %d, got %d", pm.id, pm.rxSeq+1, seq)
+		}
+		pm.rxSeq = seq
+		pm.rxSeqValid = true
+	}
+
+	// decode each data point with the same layout used to encode it
+	data := pkt.Payload[pm.hsize:body]
+	values := make(map[string]string, len(pm.dpMap))
+	errs := []error{seqErr}
+	for id, dp := range pm.dpMap {
+		str, err := dp.readData(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("payload (%d) decoding %s: %w", pm.id, id, err))
+			continue
+		}
+		values[id] = str
+	}
+	return values, errors.Join(errs...)
+}
+
+// manager runs payload id until ctx is done, then sends the packets already
+// queued and returns. It returns an error when the payload cannot run. The
+// payload is built on the workers of the scheduler, everything else happens
+// here; either holds cs.mu while it touches the payload.
+func manager(ctx *context.Context, cfg *config.Config, cs *PayloadChans, id string, pktType string, sender pktgen.Sender, infoChan chan<- packetInfo) error {
 	// Set up database interface
-	db := database.NewRedisClient(
-		ctx,
-		cfg.DbHost+":"+cfg.DbPort,
-		cfg.DbPassword,
-		cfg.DbNum,
-		cfg.DbReadTimeout,
-		cfg.DbWriteTimeout,
-	)
+	db := newDB(ctx, cfg)
+	defer db.Close()
 
 	// get payload info
 	payloadInfo, err := db.GetPayloadInfo(id)
 	if err != nil {
-		log.Fatalf("Did not find payload info for ID %s: %s", id, err)
+		return fmt.Errorf("did not find payload info: %w", err)
 	}
 
-	// extract frequency from payload info
-	f, err := strconv.ParseFloat(payloadInfo["frequency"], 64)
+	// extract the tick period from the payload frequency
+	_, fs, err := parseRate(payloadInfo["frequency"])
 	if err != nil {
-		log.Fatalf("Incorrect conversion of payload (%d) frequency %s Hz", id, payloadInfo["frequency"])
+		return fmt.Errorf("invalid payload frequency: %w", err)
 	}
 
-	// convert frequency to time.Duration
-	// 1 Hz = 1 second, so 1/f = seconds
-	fs := time.Duration(1 / f * float64(time.Second))
-
 	// Create new PayloadManager
-	pm := newPayloadManager(cfg, id, fs, db)
+	pm, err := newPayloadManager(cfg, id, fs, db, sender, cs.shared)
+	if err != nil {
+		return fmt.Errorf("unable to create payload: %w", err)
+	}
+	pm.def.info = payloadInfo
+	pm.sched, err = parseSchedule(scheduleParams(payloadInfo))
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	pm.cs = cs
+	pm.proto = pktType
+	pm.ctx = ctx
+	pm.db = db
+	pm.cfg = cfg
+	pm.ticks = newTickTracker(fs, cs.logger)
+	pm.setFaults(pm.faultCfg)
+	pm.wake, pm.end = newStoppedTimer(), newStoppedTimer()
+	defer pm.wake.Stop()
+	defer pm.end.Stop()
+	cs.delayed = newDelayQueue()
+	defer func() {
+		cs.mu.Lock()
+		cs.delayed.stop(cs.buffers)
+		cs.mu.Unlock()
+	}()
+	cs.ticker = cs.sched.ticker(func(scheduled time.Time, merged int) {
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+		pm.onTick(scheduled, merged)
+	})
+	defer cs.ticker.Stop()
+	cs.mu.Lock()
+	pm.begin()
+	cs.mu.Unlock()
 
 	// Start processing
 	for {
 		select {
-		case <-pm.cs.ticker.C:
-			// generate new payload
-			err := pm.buildPayload(ctx, db)
-			if err != nil {
-				pm.info.Error = true
-			}
+		case <-(*ctx).Done():
+			pm.drain(infoChan)
+			return nil
+		case <-pm.wake.C:
+			cs.mu.Lock()
+			pm.wakeUp()
+			cs.mu.Unlock()
+		case <-pm.end.C:
+			cs.mu.Lock()
+			pm.finish()
+			cs.mu.Unlock()
+		case <-cs.delayed.timer.C:
+			cs.mu.Lock()
+			pm.releaseDelayed()
+			cs.mu.Unlock()
+		case msg := <-cs.ctlChan:
+			cs.mu.Lock()
+			err := msg.fn(pm)
+			cs.mu.Unlock()
+			msg.result <- err
 		case pkt := <-cs.writeChan:
-			// Send packet
-			err := pm.sendPacket(pkt)
-			if err != nil {
-				log.Printf("Error sending packet: %s", err)
-				continue
-			}
+			infoChan <- pm.send(pkt)
 
 		case pkt := <-cs.readChan:
 			// Process received packet
-			err := processPacket(pkt)
+			start := time.Now()
+			err := pm.processPacket(pkt, db)
+			cs.mu.Lock()
+			info := newPacketInfo(pm, false, err != nil, len(pkt.Payload), time.Since(start))
+			cs.mu.Unlock()
 			if err != nil {
-				log.Printf("Error processing packet: %s", err)
-				continue
+				cs.logger.Error("processing packet", "err", err)
 			}
+			infoChan <- info
+		}
+	}
+}
+
+// onTick builds the payload for a tick of the scheduler. Runs on a worker
+// under cs.mu.
+func (pm *payloadManager) onTick(scheduled time.Time, merged int) {
+	pm.ticks.tick(time.Now(), merged)
+	if !pm.due() {
+		return
+	}
+	if err := pm.emit(scheduled, merged); err != nil {
+		pm.cs.logger.Error("emitting payload", "err", err)
+	}
+	pm.emitted()
+}
+
+// values adds the values of the data points generated by this payload to
+// dst, as stored. Runs on the manager goroutine.
+func (pm *payloadManager) values(dst map[string]string) {
+	for id, dp := range pm.dpMap {
+		// the shared state has the values of the bus-level data points
+		if !pm.cs.shared.has(id) {
+			dst[id] = string(dp.appendValue(nil))
+		}
+	}
+}
+
+// send writes a queued packet to the bus and returns its report for the
+// monitor. The write may block, on a serial line for one, so it happens
+// outside cs.mu and never holds up the builds of the payload.
+func (pm *payloadManager) send(pkt Packet) packetInfo {
+	pm.cs.mu.Lock()
+	sender := pm.sender
+	info := newPacketInfo(pm, true, false, len(pkt.Payload), pkt.BuildTime)
+	info.Overrun = pm.freq > 0 && pkt.BuildTime > pm.freq
+	pm.cs.mu.Unlock()
+
+	var err error
+	if sender == nil {
+		err = fmt.Errorf("no sender configured for payload (%s)", info.Payload)
+	} else {
+		_, err = sender.Write(pkt.Payload)
+	}
+	pm.cs.buffers.put(pkt.Payload)
+	if errors.Is(err, pktgen.ErrDropped) {
+		// counted as an error, not worth a record on every tick
+		pm.cs.logger.Debug("sending packet", "err", err)
+	} else if err != nil {
+		pm.cs.logger.Error("sending packet", "err", err)
+	}
+	info.Error = err != nil
+	info.TxTime = time.Now()
+	info.Slip = info.TxTime.Sub(pkt.Scheduled)
+	info.Merged = pkt.Merged
+	return info
+}
+
+// drain sends the packets still queued when the payload stops
+func (pm *payloadManager) drain(infoChan chan<- packetInfo) {
+	for {
+		select {
+		case pkt := <-pm.cs.writeChan:
+			infoChan <- pm.send(pkt)
+		default:
+			pm.cs.logger.Debug("payload stopped")
+			return
 		}
 	}
 }
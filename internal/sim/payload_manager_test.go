@@ -0,0 +1,116 @@
+package sim
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/Sapper177/datagensim/ext/definitions"
+)
+
+// fakeStore records the values written to it
+type fakeStore struct {
+	writes []map[string]string
+	err    error
+}
+
+func (f *fakeStore) SetValues(values map[string]string) error {
+	f.writes = append(f.writes, maps.Clone(values))
+	return f.err
+}
+
+// rxPayload returns a payload with an int and a float, and a function that
+// encodes a frame of it the way a peer sends one, with the next sequence number
+func rxPayload(t *testing.T) (*payloadManager, func(alt int64, speed float64) Packet) {
+	t.Helper()
+	alt := newDataPoint32(D_INT32, nil, 0, 32)
+	speed := newDataPointFloat(D_FLOAT32, nil, 32, 32)
+	pm := &payloadManager{
+		id:    7,
+		key:   "7",
+		dpMap: map[string]dataPoint{"alt": alt, "speed": speed},
+		cs:    &PayloadChans{},
+	}
+	pm.layout()
+
+	peer := definitions.NewUdpHeader(7)
+	frame := func(a int64, s float64) Packet {
+		body := int(pm.hsize) + int(pm.size)
+		buf := make([]byte, body+int(pm.fsize))
+		if _, err := writeElements(buf, peer.Elements); err != nil {
+			t.Fatal(err)
+		}
+		alt.val, speed.val = a, s
+		for _, dp := range pm.dpMap {
+			if err := dp.writeData(buf[pm.hsize:body]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := writeElements(buf[body:], definitions.NewUdpFooter(buf[:body]).Elements); err != nil {
+			t.Fatal(err)
+		}
+		alt.val, speed.val = 0, 0
+		return Packet{Protocol: "udp", Payload: buf}
+	}
+	return pm, frame
+}
+
+func TestProcessPacket(t *testing.T) {
+	pm, frame := rxPayload(t)
+	db := &fakeStore{}
+
+	if err := pm.processPacket(frame(-1200, 2.5), db); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"alt": "-1200", "speed": "2.5"}
+	if len(db.writes) != 1 || !maps.Equal(db.writes[0], want) {
+		t.Fatalf("writes = %v, want %v in one write", db.writes, want)
+	}
+
+	// a corrupted frame stores nothing
+	pkt := frame(1, 1)
+	pkt.Payload[pm.hsize] ^= 0xff
+	if err := pm.processPacket(pkt, db); err == nil {
+		t.Error("corrupted frame processed, want a checksum error")
+	}
+	short := frame(1, 1)
+	short.Payload = short.Payload[:len(short.Payload)-1]
+	if err := pm.processPacket(short, db); err == nil {
+		t.Error("short frame processed, want a length error")
+	}
+	if len(db.writes) != 1 {
+		t.Errorf("writes = %v, want nothing after the first", db.writes)
+	}
+
+	// the frames above were skipped, the data of this one is still stored
+	err := pm.processPacket(frame(300, -0.25), db)
+	if err == nil {
+		t.Error("frame after a sequence gap processed, want an error")
+	}
+	want = map[string]string{"alt": "300", "speed": "-0.25"}
+	if len(db.writes) != 2 || !maps.Equal(db.writes[1], want) {
+		t.Errorf("writes = %v, want %v stored despite the gap", db.writes, want)
+	}
+
+	// the store failing is an error
+	db.err = errors.New("store down")
+	if err := pm.processPacket(frame(1, 1), db); !errors.Is(err, db.err) {
+		t.Errorf("processPacket = %v, want the store error", err)
+	}
+}
+
+func TestProcessPacketDecodeError(t *testing.T) {
+	pm, frame := rxPayload(t)
+	// a float the receive path cannot decode, within the payload
+	pm.dpMap["half"] = newDataPointFloat(D_FLOAT32, nil, 0, 16)
+	db := &fakeStore{}
+
+	err := pm.processPacket(frame(42, 1.5), db)
+	if err == nil {
+		t.Error("processPacket succeeded, want the decode error")
+	}
+	want := map[string]string{"alt": "42", "speed": "1.5"}
+	if len(db.writes) != 1 || !maps.Equal(db.writes[0], want) {
+		t.Errorf("writes = %v, want %v without the data point that failed", db.writes, want)
+	}
+}
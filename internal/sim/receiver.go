@@ -0,0 +1,132 @@
+package sim
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Sapper177/datagensim/ext/definitions"
+	"github.com/Sapper177/datagensim/pkg/config"
+	"github.com/Sapper177/datagensim/pkg/pktgen"
+)
+
+const maxDatagramSize = 65535
+
+// newReceiver creates the input transport for the bus, or nil when the
+// receive path is disabled.
+func newReceiver(cfg *config.Config) (pktgen.Receiver, error) {
+	if cfg.ListenPort == 0 {
+		return nil, nil
+	}
+	var iface *net.Interface
+	if cfg.Interface.Name != "" {
+		iface = &cfg.Interface
+	}
+	return pktgen.NewUDPReceiver(cfg.ListenHost, cfg.ListenPort, iface)
+}
+
+// route is the manager a received payload id is handed to
+type route struct {
+	key string // payload id as stored
+	ch  chan<- Packet
+}
+
+// routeTable maps payload ids to the read channels of their managers. It
+// changes while the receive path runs when payloads are reloaded.
+type routeTable struct {
+	mu     sync.RWMutex
+	routes map[uint32]route
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{routes: make(map[uint32]route)}
+}
+
+func (t *routeTable) get(id uint32) (route, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	r, ok := t.routes[id]
+	return r, ok
+}
+
+func (t *routeTable) set(id uint32, key string, ch chan<- Packet) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.routes[id] = route{key: key, ch: ch}
+}
+
+func (t *routeTable) delete(id uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.routes, id)
+}
+
+// listen reads payloads from rcv and routes each one to the read channel of
+// the payload manager whose id matches the PayloadId in its header.
+func listen(ctx *context.Context, bus string, rcv pktgen.Receiver, routes *routeTable, logger *slog.Logger, infoChan chan<- packetInfo) {
+	// close the receiver to unblock Read when the simulation stops
+	go func() {
+		<-(*ctx).Done()
+		rcv.Close()
+	}()
+
+	idOff, err := definitions.NewUdpHeader(0).IdOffset()
+	if err != nil {
+		logger.Error("unable to locate payload id in header, receive path disabled", "err", err)
+		return
+	}
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, err := rcv.Read(buf)
+		if err != nil {
+			if (*ctx).Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.Error("receiving packet", "err", err)
+			continue
+		}
+
+		rxInfo := packetInfo{
+			Bus:        bus,
+			PacketSize: n,
+			Direction:  false,
+			TxTime:     time.Now(),
+		}
+		if n < idOff+4 {
+			logger.Warn("received packet too short for header", "bytes", n)
+			rxInfo.Error = true
+			infoChan <- rxInfo
+			continue
+		}
+		id := binary.BigEndian.Uint32(buf[idOff:])
+
+		r, ok := routes.get(id)
+		if !ok {
+			logger.Warn("received packet for unknown payload", "payload", id)
+			rxInfo.Payload = strconv.FormatUint(uint64(id), 10)
+			rxInfo.Error = true
+			infoChan <- rxInfo
+			continue
+		}
+		rxInfo.Payload = r.key
+
+		// copy out of the receive buffer before handing off
+		pkt := Packet{
+			Protocol: "udp",
+			Payload:  append([]byte(nil), buf[:n]...),
+		}
+		select {
+		case r.ch <- pkt:
+		default:
+			logger.Warn("read queue full, dropping packet", "payload", id)
+			rxInfo.Error = true
+			infoChan <- rxInfo
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package sim
+
+import (
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDiffPayloads(t *testing.T) {
+	tests := []struct {
+		name                 string
+		running, stored      []string
+		added, removed, kept []string
+	}{
+		{"unchanged", []string{"1", "2"}, []string{"1", "2"}, nil, nil, []string{"1", "2"}},
+		{"added", []string{"1"}, []string{"1", "3", "2"}, []string{"3", "2"}, nil, []string{"1"}},
+		{"removed", []string{"1", "2", "3"}, []string{"2"}, nil, []string{"1", "3"}, []string{"2"}},
+		{"replaced", []string{"1", "2"}, []string{"2", "4"}, []string{"4"}, []string{"1"}, []string{"2"}},
+		{"first load", nil, []string{"1"}, []string{"1"}, nil, nil},
+		{"emptied", []string{"1"}, nil, nil, []string{"1"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed, kept := diffPayloads(tt.running, tt.stored)
+			if !slices.Equal(added, tt.added) || !slices.Equal(removed, tt.removed) || !slices.Equal(kept, tt.kept) {
+				t.Errorf("diffPayloads = added %v removed %v kept %v, want %v %v %v",
+					added, removed, kept, tt.added, tt.removed, tt.kept)
+			}
+		})
+	}
+}
+
+// storedData is the data point definitions of a payload as stored, by data id
+type storedData map[string]map[string]string
+
+// reloadData is the stored payload the reload tests start from
+func reloadData() storedData {
+	return storedData{
+		"alt":   {"type": "int32", "offset": "0", "size": "32"},
+		"speed": {"type": "int32", "offset": "32", "size": "32"},
+	}
+}
+
+var reloadInfo = map[string]string{"min": "0", "max": "1000", "step": "1", "frequency": "10"}
+
+// storedPoints builds the data points of data and their definitions the way
+// they are loaded from the store
+func storedPoints(t *testing.T, data storedData) (map[string]dataPoint, map[string]string, *updatePlan) {
+	t.Helper()
+	dps := make(map[string]dataPoint, len(data))
+	defs := make(map[string]string, len(data))
+	for id, fields := range data {
+		dp, err := buildDataPoint("1", id, fields, reloadInfo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dps[id], defs[id] = dp, dataDefinition(fields, reloadInfo)
+	}
+	plan := &updatePlan{dps: dps, order: slices.Sorted(maps.Keys(dps))}
+	plan.resolve = plan.lookup
+	return dps, defs, plan
+}
+
+// storedPayload builds payload 1 from its stored definition, without the store
+func storedPayload(t *testing.T, cs *PayloadChans, hz string, data storedData) *payloadManager {
+	t.Helper()
+	_, period, err := parseRate(hz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dps, defs, plan := storedPoints(t, data)
+	pm := &payloadManager{
+		bus:    "A",
+		key:    "1",
+		id:     1,
+		freq:   period,
+		dpMap:  dps,
+		plan:   plan,
+		state:  payloadRunning,
+		forced: make(map[string]string),
+		wake:   newStoppedTimer(),
+		end:    newStoppedTimer(),
+		cs:     cs,
+		def:    payloadDef{info: map[string]string{"frequency": hz}, data: defs},
+	}
+	pm.layout()
+	return pm
+}
+
+func TestPayloadReload(t *testing.T) {
+	tests := []struct {
+		name    string
+		hz      string
+		change  func(d storedData)
+		changed bool
+		kept    []string // carry on with their value
+		fresh   []string // start over
+		forced  bool     // speed stays forced
+		freq    time.Duration
+	}{
+		{
+			name:   "unchanged",
+			hz:     "10",
+			change: func(storedData) {},
+			kept:   []string{"alt", "speed"}, forced: true, freq: 100 * time.Millisecond,
+		},
+		{
+			name:    "data point changed",
+			hz:      "10",
+			change:  func(d storedData) { d["speed"]["size"] = "16" },
+			changed: true,
+			kept:    []string{"alt"}, fresh: []string{"speed"}, forced: true, freq: 100 * time.Millisecond,
+		},
+		{
+			name:    "data point added",
+			hz:      "10",
+			change:  func(d storedData) { d["temp"] = map[string]string{"type": "int32", "offset": "64", "size": "32"} },
+			changed: true,
+			kept:    []string{"alt", "speed"}, fresh: []string{"temp"}, forced: true, freq: 100 * time.Millisecond,
+		},
+		{
+			name:    "data point removed",
+			hz:      "10",
+			change:  func(d storedData) { delete(d, "speed") },
+			changed: true,
+			kept:    []string{"alt"}, freq: 100 * time.Millisecond,
+		},
+		{
+			name:    "rate changed",
+			hz:      "20",
+			change:  func(storedData) {},
+			changed: true,
+			kept:    []string{"alt", "speed"}, forced: true, freq: 50 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, _ := testScheduler()
+			cs := &PayloadChans{
+				monitor: newPayloadMonitor(),
+				logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			cs.ticker = sched.ticker(func(time.Time, int) {})
+			pm := storedPayload(t, cs, "10", reloadData())
+			for _, dp := range pm.dpMap {
+				if err := dp.set("42"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := pm.force("speed", "7"); err != nil {
+				t.Fatal(err)
+			}
+			old := maps.Clone(pm.dpMap)
+
+			data := reloadData()
+			tt.change(data)
+			next := storedPayload(t, nil, tt.hz, data)
+			_, period, _ := parseRate(tt.hz)
+			changed, err := pm.adopt(next, 1/period.Seconds())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			for _, id := range tt.kept {
+				if pm.dpMap[id] != old[id] {
+					t.Errorf("%s rebuilt, want its state kept", id)
+				}
+			}
+			for _, id := range tt.fresh {
+				if got := string(pm.dpMap[id].appendValue(nil)); pm.dpMap[id] == old[id] || got != "0" {
+					t.Errorf("%s = %s, want it rebuilt from its definition", id, got)
+				}
+			}
+			if len(pm.dpMap) != len(tt.kept)+len(tt.fresh) {
+				t.Errorf("data points %v, want %v and %v", slices.Sorted(maps.Keys(pm.dpMap)), tt.kept, tt.fresh)
+			}
+			if _, ok := pm.forced["speed"]; ok != tt.forced {
+				t.Errorf("speed forced %v, want %v", ok, tt.forced)
+			}
+			if pm.freq != tt.freq || pm.cs != cs {
+				t.Errorf("period %v, want %v on the same channels", pm.freq, tt.freq)
+			}
+		})
+	}
+}
+
+func TestSharedReload(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(d storedData)
+		kept   []string
+		fresh  []string
+	}{
+		{"unchanged", func(storedData) {}, []string{"alt", "speed"}, nil},
+		{"data point changed", func(d storedData) { d["speed"]["offset"] = "64" }, []string{"alt"}, []string{"speed"}},
+		{"data point added", func(d storedData) {
+			d["temp"] = map[string]string{"type": "int32", "offset": "64", "size": "32"}
+		}, []string{"alt", "speed"}, []string{"temp"}},
+		{"data point removed", func(d storedData) { delete(d, "alt") }, []string{"speed"}, nil},
+		{"no longer shared", func(d storedData) { clear(d) }, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, _ := testScheduler()
+			s := newSharedState(nil, "A", sched, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			dps, defs, plan := storedPoints(t, reloadData())
+			if err := s.replace(dps, defs, plan, 10); err != nil {
+				t.Fatal(err)
+			}
+			old := dps
+
+			data := reloadData()
+			tt.change(data)
+			dps, defs, plan = storedPoints(t, data)
+			if err := s.replace(dps, defs, plan, 10); err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range tt.kept {
+				if s.dpMap[id] != old[id] {
+					t.Errorf("%s rebuilt, want its state kept", id)
+				}
+			}
+			for _, id := range tt.fresh {
+				if dp, ok := s.dpMap[id]; !ok || dp == old[id] {
+					t.Errorf("%s not rebuilt from its definition", id)
+				}
+			}
+			n := len(tt.kept) + len(tt.fresh)
+			if len(s.dpMap) != n {
+				t.Errorf("shared %v, want %v and %v", slices.Sorted(maps.Keys(s.dpMap)), tt.kept, tt.fresh)
+			}
+			if wantFreq := 100 * time.Millisecond; n == 0 {
+				if s.freq != 0 {
+					t.Errorf("bus tick %v with nothing shared, want none", s.freq)
+				}
+			} else if s.freq != wantFreq {
+				t.Errorf("bus tick %v, want %v", s.freq, wantFreq)
+			}
+		})
+	}
+}
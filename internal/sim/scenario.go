@@ -0,0 +1,513 @@
+package sim
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sapper177/datagensim/api"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maximum number of scenario events kept in the store
+const eventLogMax = 10000
+
+// how often a pending assertion is re-evaluated
+const assertPoll = 50 * time.Millisecond
+
+// Scenario step actions
+const (
+	actionSet      = "set"       // force data to value
+	actionRelease  = "release"   // return data to its engine
+	actionEngine   = "engine"    // change engine params of data
+	actionStart    = "start"     // start or resume payload
+	actionPause    = "pause"     // pause payload
+	actionStop     = "stop"      // stop payload
+	actionRate     = "rate"      // change payload rate to hz
+	actionFault    = "fault"     // replace injected faults of payload with params
+	actionSchedule = "schedule"  // replace the schedule of payload with params
+	actionSend     = "send"      // emit count packets of payload now
+	actionLinkDown = "link_down" // pause every payload on the bus
+	actionLinkUp   = "link_up"   // resume every payload on the bus
+	actionMark     = "mark"      // log message
+	actionAssert   = "assert"    // check the stored value of data
+)
+
+// scenario is a timed test campaign, loaded from a YAML file:
+//
+//	name: overheat
+//	steps:
+//	  - {at: 10s, action: set, data: temp_1, value: "80"}
+//	  - {at: 30s, action: link_down, for: 5s}
+//	  - {at: 40s, action: fault, payload: "1", params: {loss: "0.2"}, for: 10s}
+//	  - {at: 45s, action: link_down, bus: AuxBus, for: 5s}
+//	  - {at: 50s, action: schedule, payload: "2", params: {burst_count: "10", burst_interval: 1s}, for: 5s}
+//	  - {at: 58s, action: send, payload: "3", count: 2}
+//	  - {at: 60s, action: set, data: mode, value: FAULT}
+//	  - {at: 61s, action: assert, data: mode, equals: FAULT, within: 1s}
+//	  - {at: 90s, action: mark, message: done}
+//
+// Times are offsets from the start of the scenario. A step with "for" is
+// reverted once that duration has passed. An assertion with "within" is
+// retried until then while the later steps run. Payload and link steps act on the
+// first configured bus unless they name another one. A run with failed steps
+// exits non-zero, and with scenario_exit it stops once the scenario has run.
+type scenario struct {
+	Name  string         `yaml:"name"`
+	Steps []scenarioStep `yaml:"steps"`
+}
+
+type scenarioStep struct {
+	At      time.Duration     `yaml:"at"`
+	Action  string            `yaml:"action"`
+	Bus     string            `yaml:"bus,omitempty"`
+	Payload string            `yaml:"payload,omitempty"`
+	Data    string            `yaml:"data,omitempty"`
+	Value   string            `yaml:"value,omitempty"`
+	Hz      float64           `yaml:"hz,omitempty"`
+	Count   int               `yaml:"count,omitempty"`
+	Params  map[string]string `yaml:"params,omitempty"`
+	For     time.Duration     `yaml:"for,omitempty"`
+	Message string            `yaml:"message,omitempty"`
+
+	// assertion on the stored value of Data, optionally retried until Within
+	Equals *string       `yaml:"equals,omitempty"`
+	Min    *float64      `yaml:"min,omitempty"`
+	Max    *float64      `yaml:"max,omitempty"`
+	Within time.Duration `yaml:"within,omitempty"`
+}
+
+// scenarioEvent is a scenario log record, stored in Redis as JSON
+type scenarioEvent struct {
+	Time     time.Time `json:"time"`
+	Offset   string    `json:"offset"` // scenario clock
+	Scenario string    `json:"scenario"`
+	Bus      string    `json:"bus"`
+	Step     int       `json:"step"`
+	Kind     string    `json:"kind"` // action, revert, mark or assert
+	Action   string    `json:"action"`
+	Detail   string    `json:"detail,omitempty"`
+	Result   string    `json:"result"` // ok, failed or error
+	Error    string    `json:"error,omitempty"`
+}
+
+// loadScenario reads and validates a scenario file
+func loadScenario(path string) (*scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	sc := &scenario{}
+	if err := dec.Decode(sc); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", path, err)
+	}
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	for i := range sc.Steps {
+		if err := sc.Steps[i].validate(); err != nil {
+			return nil, fmt.Errorf("scenario %s step %d: %w", path, i, err)
+		}
+	}
+	return sc, nil
+}
+
+// checkBuses verifies that every bus named by a step is configured
+func (sc *scenario) checkBuses(buses []string) error {
+	for i, step := range sc.Steps {
+		if step.Bus != "" && !slices.Contains(buses, step.Bus) {
+			return fmt.Errorf("scenario %s step %d: unknown bus %q", sc.Name, i, step.Bus)
+		}
+	}
+	return nil
+}
+
+func (s *scenarioStep) validate() error {
+	if s.At < 0 || s.For < 0 || s.Within < 0 {
+		return fmt.Errorf("negative duration")
+	}
+	need := func(field string, v string) error {
+		if v == "" {
+			return fmt.Errorf("%s requires %s", s.Action, field)
+		}
+		return nil
+	}
+	switch s.Action {
+	case actionSet:
+		if err := need("data", s.Data); err != nil {
+			return err
+		}
+		return need("value", s.Value)
+	case actionRelease:
+		return need("data", s.Data)
+	case actionEngine:
+		if len(s.Params) == 0 {
+			return fmt.Errorf("engine requires params")
+		}
+		return need("data", s.Data)
+	case actionStart, actionPause, actionStop:
+		return need("payload", s.Payload)
+	case actionRate:
+		if _, err := ratePeriod(s.Hz); err != nil {
+			return fmt.Errorf("rate requires hz: %w", err)
+		}
+		return need("payload", s.Payload)
+	case actionFault, actionSchedule:
+		return need("payload", s.Payload)
+	case actionSend:
+		if s.Count < 0 {
+			return fmt.Errorf("negative count")
+		}
+		return need("payload", s.Payload)
+	case actionLinkDown, actionLinkUp:
+		return nil
+	case actionMark:
+		return need("message", s.Message)
+	case actionAssert:
+		if s.Equals == nil && s.Min == nil && s.Max == nil {
+			return fmt.Errorf("assert requires equals, min or max")
+		}
+		return need("data", s.Data)
+	default:
+		return fmt.Errorf("unknown action: %q", s.Action)
+	}
+}
+
+// scheduledStep is a step, a retry of a pending assertion or the revert of a
+// step, due at an offset
+type scheduledStep struct {
+	at     time.Duration
+	index  int
+	revert func(ctx context.Context) error
+}
+
+// insertStep adds s to a timeline sorted by offset, after the steps due with it
+func insertStep(timeline []scheduledStep, s scheduledStep) []scheduledStep {
+	i, _ := slices.BinarySearchFunc(timeline, s.at, func(t scheduledStep, at time.Duration) int {
+		if t.at <= at {
+			return -1
+		}
+		return 1
+	})
+	return slices.Insert(timeline, i, s)
+}
+
+// scenarioStore is the part of the store a scenario reads values from and
+// logs its events to
+type scenarioStore interface {
+	GetData(dataId string) (map[string]string, error)
+	LogEvent(bus string, entry string, max int64) error
+}
+
+// scenarioClock tells the time of a scenario. Offsets are taken between its
+// readings, which carry the monotonic clock, so wall clock changes do not
+// move steps.
+type scenarioClock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the scenario clock of a simulation
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// scenarioRunner executes a scenario through the control API
+type scenarioRunner struct {
+	sc      *scenario
+	ctl     *apiController
+	db      scenarioStore
+	monitor *payloadMonitor
+	clock   scenarioClock
+	failed  int
+}
+
+func newScenarioRunner(sc *scenario, ctl *apiController) *scenarioRunner {
+	return &scenarioRunner{sc: sc, ctl: ctl, db: ctl.db, monitor: ctl.monitor, clock: systemClock{}}
+}
+
+// run executes every step at its offset from now and returns once the last
+// step and revert are done, or ctx is cancelled. The error reports failed
+// assertions.
+func (r *scenarioRunner) run(ctx context.Context) error {
+	timeline := make([]scheduledStep, len(r.sc.Steps))
+	for i, step := range r.sc.Steps {
+		timeline[i] = scheduledStep{at: step.At, index: i}
+	}
+	slices.SortStableFunc(timeline, func(a, b scheduledStep) int { return cmp.Compare(a.at, b.at) })
+
+	start := r.clock.Now()
+	slog.Info("scenario starting", "scenario", r.sc.Name, "steps", len(r.sc.Steps))
+	for len(timeline) > 0 {
+		next := timeline[0]
+		timeline = timeline[1:]
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.clock.After(start.Add(next.at).Sub(r.clock.Now())):
+		}
+
+		step := &r.sc.Steps[next.index]
+		if next.revert != nil {
+			err := next.revert(ctx)
+			r.event(start, next.index, "revert", step, err)
+			continue
+		}
+		revert, err := r.execute(ctx, step)
+		if _, failed := err.(errAssert); failed {
+			// checked again after a poll, the steps due meanwhile run first
+			deadline := step.At + step.Within
+			if elapsed := r.clock.Now().Sub(start); elapsed < deadline {
+				timeline = insertStep(timeline, scheduledStep{at: min(elapsed+assertPoll, deadline), index: next.index})
+				continue
+			}
+		}
+		kind := "action"
+		switch step.Action {
+		case actionMark, actionAssert:
+			kind = step.Action
+		}
+		r.event(start, next.index, kind, step, err)
+
+		if err == nil && revert != nil && step.For > 0 {
+			timeline = insertStep(timeline, scheduledStep{at: next.at + step.For, index: next.index, revert: revert})
+		}
+	}
+
+	slog.Info("scenario finished", "scenario", r.sc.Name, "elapsed", r.clock.Now().Sub(start).Round(time.Millisecond), "failed", r.failed)
+	if r.failed > 0 {
+		return fmt.Errorf("scenario %s: %d steps failed", r.sc.Name, r.failed)
+	}
+	return nil
+}
+
+// execute applies a step and returns the function that reverts it, if any
+func (r *scenarioRunner) execute(ctx context.Context, s *scenarioStep) (func(context.Context) error, error) {
+	c, bus := r.ctl, r.busOf(s)
+	switch s.Action {
+	case actionSet:
+		err := c.ForceValue(ctx, s.Data, s.Value)
+		return func(ctx context.Context) error { return c.ReleaseValue(ctx, s.Data) }, err
+
+	case actionRelease:
+		return nil, c.ReleaseValue(ctx, s.Data)
+
+	case actionEngine:
+		prev, err := c.db.GetDataInfo(s.Data)
+		if err != nil {
+			return nil, err
+		}
+		undo := make(map[string]string, len(s.Params))
+		for name := range s.Params {
+			if v, ok := prev[name]; ok {
+				undo[name] = v
+			}
+		}
+		err = c.SetEngineParams(ctx, s.Data, s.Params)
+		return func(ctx context.Context) error { return c.SetEngineParams(ctx, s.Data, undo) }, err
+
+	case actionStart, actionPause, actionStop:
+		p, err := c.Payload(ctx, bus, s.Payload)
+		if err != nil {
+			return nil, err
+		}
+		state := map[string]string{actionStart: api.StateRunning, actionPause: api.StatePaused, actionStop: api.StateStopped}[s.Action]
+		err = c.SetPayloadState(ctx, bus, s.Payload, state)
+		return func(ctx context.Context) error { return c.SetPayloadState(ctx, bus, s.Payload, p.State) }, err
+
+	case actionRate:
+		p, err := c.Payload(ctx, bus, s.Payload)
+		if err != nil {
+			return nil, err
+		}
+		err = c.SetPayloadRate(ctx, bus, s.Payload, s.Hz)
+		return func(ctx context.Context) error { return c.SetPayloadRate(ctx, bus, s.Payload, p.RateHz) }, err
+
+	case actionFault:
+		p, err := c.Payload(ctx, bus, s.Payload)
+		if err != nil {
+			return nil, err
+		}
+		err = c.SetPayloadFaults(ctx, bus, s.Payload, s.Params)
+		return func(ctx context.Context) error { return c.SetPayloadFaults(ctx, bus, s.Payload, p.Faults) }, err
+
+	case actionSchedule:
+		p, err := c.Payload(ctx, bus, s.Payload)
+		if err != nil {
+			return nil, err
+		}
+		err = c.SetPayloadSchedule(ctx, bus, s.Payload, s.Params)
+		return func(ctx context.Context) error { return c.SetPayloadSchedule(ctx, bus, s.Payload, p.Schedule) }, err
+
+	case actionSend:
+		return nil, c.SendPayload(ctx, bus, s.Payload, max(s.Count, 1))
+
+	case actionLinkDown, actionLinkUp:
+		payloads, err := c.Payloads(ctx, bus)
+		if err != nil {
+			return nil, err
+		}
+		state := api.StatePaused
+		if s.Action == actionLinkUp {
+			state = api.StateRunning
+		}
+		for _, p := range payloads {
+			if err := c.SetPayloadState(ctx, bus, p.Id, state); err != nil {
+				return nil, err
+			}
+		}
+		// restore each payload to the state it had before
+		return func(ctx context.Context) error {
+			for _, p := range payloads {
+				if err := c.SetPayloadState(ctx, bus, p.Id, p.State); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+
+	case actionMark:
+		return nil, nil
+
+	case actionAssert:
+		return nil, r.assert(ctx, s)
+	}
+	return nil, fmt.Errorf("unknown action: %q", s.Action)
+}
+
+// busOf returns the bus a step acts on, the first bus unless it names one
+func (r *scenarioRunner) busOf(s *scenarioStep) string {
+	if s.Bus != "" {
+		return s.Bus
+	}
+	return r.ctl.buses[0].name
+}
+
+// errAssert marks a failed assertion as opposed to an error evaluating it
+type errAssert struct{ msg string }
+
+func (e errAssert) Error() string { return e.msg }
+
+// assert checks the value of s.Data, run retries it until s.Within has
+// passed. The value is that of the running payload generating s.Data, the
+// stored one for data points no payload generates.
+func (r *scenarioRunner) assert(ctx context.Context, s *scenarioStep) error {
+	value, err := r.value(ctx, s.Data)
+	if err != nil {
+		return err
+	}
+	return s.check(value)
+}
+
+// value returns the latest value of a data point
+func (r *scenarioRunner) value(ctx context.Context, dataId string) (string, error) {
+	value, ok, err := r.ctl.liveValue(ctx, dataId)
+	if err != nil || ok {
+		return value, err
+	}
+	d, err := r.db.GetData(dataId)
+	if err != nil {
+		return "", err
+	}
+	return d["value"], nil
+}
+
+func (s *scenarioStep) check(value string) error {
+	if s.Equals != nil && value != *s.Equals {
+		return errAssert{fmt.Sprintf("%s is %q, expected %q", s.Data, value, *s.Equals)}
+	}
+	if s.Min == nil && s.Max == nil {
+		return nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return errAssert{fmt.Sprintf("%s is %q, expected a number", s.Data, value)}
+	}
+	if s.Min != nil && v < *s.Min {
+		return errAssert{fmt.Sprintf("%s is %v, expected >= %v", s.Data, v, *s.Min)}
+	}
+	if s.Max != nil && v > *s.Max {
+		return errAssert{fmt.Sprintf("%s is %v, expected <= %v", s.Data, v, *s.Max)}
+	}
+	return nil
+}
+
+// detail describes the target of a step for the event log
+func (s *scenarioStep) detail() string {
+	switch s.Action {
+	case actionSet:
+		return s.Data + "=" + s.Value
+	case actionRelease, actionAssert:
+		return s.Data
+	case actionEngine:
+		b, _ := json.Marshal(s.Params)
+		return s.Data + " " + string(b)
+	case actionStart, actionPause, actionStop:
+		return s.Payload
+	case actionFault, actionSchedule:
+		b, _ := json.Marshal(s.Params)
+		return s.Payload + " " + string(b)
+	case actionSend:
+		return fmt.Sprintf("%s x%d", s.Payload, max(s.Count, 1))
+	case actionRate:
+		return fmt.Sprintf("%s %v Hz", s.Payload, s.Hz)
+	case actionMark:
+		return s.Message
+	}
+	return ""
+}
+
+// event logs, counts and stores the outcome of a step
+func (r *scenarioRunner) event(start time.Time, index int, kind string, s *scenarioStep, err error) {
+	now := r.clock.Now()
+	ev := scenarioEvent{
+		Time:     now,
+		Offset:   now.Sub(start).Round(time.Millisecond).String(),
+		Scenario: r.sc.Name,
+		Bus:      r.busOf(s),
+		Step:     index,
+		Kind:     kind,
+		Action:   s.Action,
+		Detail:   s.detail(),
+		Result:   "ok",
+	}
+	if err != nil {
+		ev.Result = "error"
+		if _, ok := err.(errAssert); ok {
+			ev.Result = "failed"
+		}
+		ev.Error = err.Error()
+		r.failed++
+	}
+
+	logger := slog.With("scenario", ev.Scenario, "bus", ev.Bus, "offset", ev.Offset, "step", index, "kind", kind, "action", s.Action, "detail", ev.Detail)
+	if err != nil {
+		logger.Warn("scenario step "+ev.Result, "err", err)
+	} else {
+		logger.Info("scenario step ok")
+	}
+	r.monitor.addScenarioEvent(ev.Scenario, kind, ev.Result)
+
+	b, jerr := json.Marshal(ev)
+	if jerr != nil {
+		logger.Error("encoding scenario event", "err", jerr)
+		return
+	}
+	if err := r.db.LogEvent(ev.Bus, string(b), eventLogMax); err != nil {
+		logger.Error("writing scenario event", "err", err)
+	}
+}
@@ -0,0 +1,101 @@
+package sim
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// stepClock is a scenario clock that jumps to the end of every wait
+type stepClock struct{ now time.Time }
+
+func (c *stepClock) Now() time.Time { return c.now }
+
+func (c *stepClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(max(d, 0))
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// timedValue is a stored value from an offset of the scenario on
+type timedValue struct {
+	from  time.Duration
+	value string
+}
+
+// scenarioTestStore serves values that change on the scenario clock and
+// records the events logged
+type scenarioTestStore struct {
+	clock  *stepClock
+	start  time.Time
+	values map[string][]timedValue
+	events []scenarioEvent
+}
+
+func (s *scenarioTestStore) GetData(dataId string) (map[string]string, error) {
+	var value string
+	for _, v := range s.values[dataId] {
+		if s.clock.now.Sub(s.start) >= v.from {
+			value = v.value
+		}
+	}
+	return map[string]string{"value": value}, nil
+}
+
+func (s *scenarioTestStore) LogEvent(bus string, entry string, max int64) error {
+	var ev scenarioEvent
+	if err := json.Unmarshal([]byte(entry), &ev); err != nil {
+		return err
+	}
+	s.events = append(s.events, ev)
+	return nil
+}
+
+func TestScenarioAssertWithin(t *testing.T) {
+	on, hot := "on", 50.0
+	sc := &scenario{Name: "within", Steps: []scenarioStep{
+		{At: 0, Action: actionAssert, Data: "mode", Equals: &on, Within: 2 * time.Second},
+		{At: 500 * time.Millisecond, Action: actionMark, Message: "meanwhile"},
+		{At: time.Second, Action: actionAssert, Data: "temp", Min: &hot, Within: 300 * time.Millisecond},
+		{At: 1200 * time.Millisecond, Action: actionMark, Message: "later"},
+	}}
+	clock := &stepClock{now: time.Unix(1000, 0)}
+	db := &scenarioTestStore{clock: clock, start: clock.now, values: map[string][]timedValue{
+		"mode": {{0, "off"}, {time.Second, "on"}},
+		"temp": {{0, "20"}},
+	}}
+	monitor := newPayloadMonitor()
+	r := &scenarioRunner{
+		sc:      sc,
+		ctl:     newAPIController([]*bus{{name: "A", ctl: newController(nil)}}, nil, monitor, nil),
+		db:      db,
+		monitor: monitor,
+		clock:   clock,
+	}
+	if err := r.run(context.Background()); err == nil {
+		t.Error("run succeeded, want the temp assertion failed")
+	}
+
+	// a pending assertion holds up none of the steps after it
+	type outcome struct {
+		step   int
+		offset string
+		result string
+	}
+	want := []outcome{
+		{1, "500ms", "ok"},
+		{0, "1s", "ok"},
+		{3, "1.2s", "ok"},
+		{2, "1.3s", "failed"},
+	}
+	var got []outcome
+	for _, ev := range db.events {
+		got = append(got, outcome{ev.Step, ev.Offset, ev.Result})
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,314 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Sapper177/datagensim/api"
+)
+
+// minPeriod is the shortest tick period, bounding payload and burst rates
+const minPeriod = time.Microsecond
+
+// Schedule fields of <payload_id> in the store. A payload without any of
+// them starts right away and runs at its frequency until stopped.
+const (
+	schedEnabled       = "enabled"        // false loads the payload stopped
+	schedStartDelay    = "start_delay"    // wait before starting, e.g. 5s
+	schedCount         = "count"          // stop after this many packets
+	schedDuration      = "duration"       // stop after running this long
+	schedBurstCount    = "burst_count"    // send bursts of this many packets instead of a steady rate
+	schedBurstRate     = "burst_rate"     // rate within a burst in Hz, the payload frequency by default
+	schedBurstInterval = "burst_interval" // time from the start of a burst to the start of the next
+	schedPhase         = "phase"          // offset of the ticks from those of other payloads with the same period
+	schedOverrun       = "overrun"        // skip or catch_up the ticks due while the payload is still building
+)
+
+var scheduleFields = []string{
+	schedEnabled, schedStartDelay, schedCount, schedDuration,
+	schedBurstCount, schedBurstRate, schedBurstInterval, schedPhase, schedOverrun,
+}
+
+// scheduleConfig is when a payload emits. The zero value runs it at its
+// frequency from the moment it is loaded.
+type scheduleConfig struct {
+	disabled      bool
+	startDelay    time.Duration
+	count         int
+	duration      time.Duration
+	burstCount    int
+	burstRate     float64
+	burstInterval time.Duration
+	phase         time.Duration
+	overrun       string
+	params        map[string]string // as configured, for reporting
+}
+
+func (c *scheduleConfig) bursts() bool {
+	return c.burstCount > 0
+}
+
+// set changes a single schedule field. An empty value clears it.
+func (c *scheduleConfig) set(name string, value string) error {
+	dur := func(d *time.Duration) error {
+		if value == "" {
+			*d = 0
+			return nil
+		}
+		v, err := time.ParseDuration(value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("%s must be a positive duration, got %q", name, value)
+		}
+		*d = v
+		return nil
+	}
+	count := func(n *int) error {
+		if value == "" {
+			*n = 0
+			return nil
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("%s must be a positive count, got %q", name, value)
+		}
+		*n = v
+		return nil
+	}
+
+	var err error
+	switch name {
+	case schedEnabled:
+		c.disabled = false
+		if value != "" {
+			var enabled bool
+			if enabled, err = strconv.ParseBool(value); err != nil {
+				err = fmt.Errorf("%s must be true or false, got %q", name, value)
+			}
+			c.disabled = !enabled
+		}
+	case schedStartDelay:
+		err = dur(&c.startDelay)
+	case schedCount:
+		err = count(&c.count)
+	case schedDuration:
+		err = dur(&c.duration)
+	case schedBurstCount:
+		err = count(&c.burstCount)
+	case schedBurstRate:
+		c.burstRate = 0
+		if value != "" {
+			if c.burstRate, _, err = parseRate(value); err != nil {
+				err = fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	case schedBurstInterval:
+		err = dur(&c.burstInterval)
+	case schedPhase:
+		err = dur(&c.phase)
+	case schedOverrun:
+		c.overrun, err = parseOverrun(value)
+	default:
+		return fmt.Errorf("unknown schedule field: %s", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.params == nil {
+		c.params = make(map[string]string)
+	}
+	if value == "" {
+		delete(c.params, name)
+	} else {
+		c.params[name] = value
+	}
+	return nil
+}
+
+// parseSchedule builds a schedule from its fields
+func parseSchedule(params map[string]string) (scheduleConfig, error) {
+	var cfg scheduleConfig
+	for name, value := range params {
+		if err := cfg.set(name, value); err != nil {
+			return cfg, err
+		}
+	}
+	if cfg.bursts() && cfg.burstInterval <= 0 {
+		return cfg, fmt.Errorf("%s requires %s", schedBurstCount, schedBurstInterval)
+	}
+	return cfg, nil
+}
+
+// scheduleParams picks the schedule fields out of the payload info
+func scheduleParams(info map[string]string) map[string]string {
+	params := make(map[string]string)
+	for _, name := range scheduleFields {
+		if v, ok := info[name]; ok {
+			params[name] = v
+		}
+	}
+	return params
+}
+
+// ratePeriod returns the tick period of a rate in Hz. Rates that are not
+// finite, not above 0 or too high for a period of minPeriod are invalid.
+func ratePeriod(hz float64) (time.Duration, error) {
+	if math.IsNaN(hz) || math.IsInf(hz, 0) || hz <= 0 {
+		return 0, fmt.Errorf("rate %v Hz: %w", hz, api.ErrInvalid)
+	}
+	period := time.Duration(float64(time.Second) / hz)
+	if period < minPeriod {
+		return 0, fmt.Errorf("rate %v Hz above %v Hz: %w", hz, float64(time.Second/minPeriod), api.ErrInvalid)
+	}
+	return period, nil
+}
+
+// parseRate parses a rate in Hz, as stored, and returns it with its tick period
+func parseRate(value string) (float64, time.Duration, error) {
+	hz, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("rate %q Hz: %w", value, api.ErrInvalid)
+	}
+	period, err := ratePeriod(hz)
+	return hz, period, err
+}
+
+// period is the ticker period: the burst rate while sending bursts, the
+// payload frequency otherwise
+func (pm *payloadManager) period() time.Duration {
+	if pm.sched.bursts() && pm.sched.burstRate > 0 {
+		return time.Duration(float64(time.Second) / pm.sched.burstRate)
+	}
+	return pm.freq
+}
+
+// begin applies the schedule from the start, as when the payload is loaded.
+// Callers hold cs.mu.
+func (pm *payloadManager) begin() {
+	pm.cs.ticker.setTiming(pm.sched.phase, pm.sched.overrun)
+	pm.halt()
+	switch {
+	case pm.sched.disabled:
+	case pm.sched.startDelay > 0:
+		pm.wake.Reset(pm.sched.startDelay)
+	default:
+		pm.start(payloadRunning)
+	}
+}
+
+// start runs a stopped payload in state, running or paused, from the start
+// of its count and duration. Callers hold cs.mu.
+func (pm *payloadManager) start(state string) {
+	pm.state = state
+	pm.sent = 0
+	pm.wake.Stop()
+	if pm.sched.duration > 0 {
+		pm.end.Reset(pm.sched.duration)
+	}
+	if pm.sched.bursts() {
+		pm.startBurst()
+		return
+	}
+	pm.ticks = newTickTracker(pm.freq, pm.cs.logger)
+	pm.cs.ticker.Reset(pm.freq)
+}
+
+// startBurst ticks burstCount times at the burst rate and arms the next
+// burst. Callers hold cs.mu.
+func (pm *payloadManager) startBurst() {
+	pm.burstLeft = pm.sched.burstCount
+	pm.ticks = newTickTracker(pm.period(), pm.cs.logger)
+	pm.cs.ticker.Reset(pm.period())
+	pm.wake.Reset(pm.sched.burstInterval)
+}
+
+// halt stops the ticker and every schedule timer. Callers hold cs.mu.
+func (pm *payloadManager) halt() {
+	pm.state = payloadStopped
+	pm.burstLeft = 0
+	pm.cs.ticker.Stop()
+	pm.wake.Stop()
+	pm.end.Stop()
+}
+
+// finish stops a payload that reached its count or duration
+func (pm *payloadManager) finish() {
+	pm.halt()
+	pm.cs.logger.Info("payload schedule finished", "sent", pm.sent)
+}
+
+// wakeUp starts a delayed payload, or the next burst of a running one.
+// Callers hold cs.mu.
+func (pm *payloadManager) wakeUp() {
+	if pm.state == payloadStopped {
+		pm.start(payloadRunning)
+		return
+	}
+	pm.startBurst()
+}
+
+// retick restarts the ticker at the current period if it is ticking.
+// Callers hold cs.mu.
+func (pm *payloadManager) retick() {
+	pm.ticks = newTickTracker(pm.period(), pm.cs.logger)
+	if pm.state != payloadStopped && (!pm.sched.bursts() || pm.burstLeft > 0) {
+		pm.cs.ticker.Reset(pm.period())
+	}
+}
+
+// due reports whether a tick emits, using up a slot of the current burst.
+// Callers hold cs.mu.
+func (pm *payloadManager) due() bool {
+	if pm.sched.bursts() {
+		if pm.burstLeft == 0 {
+			return false
+		}
+		pm.burstLeft--
+		if pm.burstLeft == 0 {
+			pm.cs.ticker.Stop()
+		}
+	}
+	return pm.state == payloadRunning
+}
+
+// emitted counts a scheduled packet and stops the payload at its count.
+// Callers hold cs.mu.
+func (pm *payloadManager) emitted() {
+	pm.sent++
+	if pm.sched.count > 0 && pm.sent >= pm.sched.count {
+		pm.finish()
+	}
+}
+
+// setSchedule replaces the schedule and restarts the payload under it.
+// Callers hold cs.mu.
+func (pm *payloadManager) setSchedule(params map[string]string) error {
+	cfg, err := parseSchedule(params)
+	if err != nil {
+		return err
+	}
+	pm.sched = cfg
+	pm.begin()
+	return nil
+}
+
+// sendNow emits n packets right away, whatever the state and schedule of the
+// payload. They do not count towards its count, and n must fit the write
+// queue. Callers hold cs.mu.
+func (pm *payloadManager) sendNow(n int) error {
+	for range n {
+		if err := pm.emit(time.Now(), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newStoppedTimer returns a timer that fires once reset
+func newStoppedTimer() *time.Timer {
+	t := time.NewTimer(time.Hour)
+	t.Stop()
+	return t
+}
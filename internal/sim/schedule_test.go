@@ -0,0 +1,139 @@
+package sim
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sapper177/datagensim/api"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    scheduleConfig
+		wantErr bool
+	}{
+		{name: "none", params: nil},
+		{name: "enabled", params: map[string]string{"enabled": "true"}},
+		{name: "disabled", params: map[string]string{"enabled": "false"}, want: scheduleConfig{disabled: true}},
+		{
+			name:   "delay, count and duration",
+			params: map[string]string{"start_delay": "5s", "count": "100", "duration": "1m30s"},
+			want:   scheduleConfig{startDelay: 5 * time.Second, count: 100, duration: 90 * time.Second},
+		},
+		{
+			name:   "bursts",
+			params: map[string]string{"burst_count": "10", "burst_rate": "1000", "burst_interval": "1s"},
+			want:   scheduleConfig{burstCount: 10, burstRate: 1000, burstInterval: time.Second},
+		},
+		{
+			name:   "bursts at the payload frequency",
+			params: map[string]string{"burst_count": "3", "burst_interval": "250ms"},
+			want:   scheduleConfig{burstCount: 3, burstInterval: 250 * time.Millisecond},
+		},
+		{
+			name:   "phase and overrun",
+			params: map[string]string{"phase": "2ms", "overrun": "catch_up"},
+			want:   scheduleConfig{phase: 2 * time.Millisecond, overrun: overrunCatchUp},
+		},
+		{
+			name:   "empty clears",
+			params: map[string]string{"enabled": "", "count": "", "burst_rate": "", "phase": ""},
+		},
+		{name: "empty overrun skips", params: map[string]string{"overrun": ""}, want: scheduleConfig{overrun: overrunSkip}},
+		{name: "enabled not a boolean", params: map[string]string{"enabled": "sometimes"}, wantErr: true},
+		{name: "negative delay", params: map[string]string{"start_delay": "-1s"}, wantErr: true},
+		{name: "duration without unit", params: map[string]string{"duration": "10"}, wantErr: true},
+		{name: "negative count", params: map[string]string{"count": "-1"}, wantErr: true},
+		{name: "fractional count", params: map[string]string{"count": "1.5"}, wantErr: true},
+		{name: "burst rate zero", params: map[string]string{"burst_rate": "0"}, wantErr: true},
+		{name: "burst rate too high", params: map[string]string{"burst_rate": "2e6"}, wantErr: true},
+		{name: "burst rate not a number", params: map[string]string{"burst_rate": "fast"}, wantErr: true},
+		{name: "bursts without an interval", params: map[string]string{"burst_count": "10"}, wantErr: true},
+		{name: "unknown overrun", params: map[string]string{"overrun": "drop"}, wantErr: true},
+		{name: "unknown field", params: map[string]string{"repeat": "3"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSchedule(tt.params)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseSchedule succeeded with %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			params := got.params
+			got.params = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSchedule = %+v, want %+v", got, tt.want)
+			}
+			// reported as configured, without the cleared ones
+			want := maps.Clone(tt.params)
+			maps.DeleteFunc(want, func(_, v string) bool { return v == "" })
+			if len(params) != len(want) || (len(want) > 0 && !maps.Equal(params, want)) {
+				t.Errorf("params = %v, want %v", params, want)
+			}
+		})
+	}
+}
+
+func TestScheduleParams(t *testing.T) {
+	info := map[string]string{
+		"name": "nav", "frequency": "10", "enabled": "false", "count": "5", "overrun": "", "phase": "1ms",
+	}
+	want := map[string]string{"enabled": "false", "count": "5", "overrun": "", "phase": "1ms"}
+	if got := scheduleParams(info); !maps.Equal(got, want) {
+		t.Errorf("scheduleParams = %v, want %v", got, want)
+	}
+	if got := scheduleParams(nil); got == nil || len(got) != 0 {
+		t.Errorf("scheduleParams(nil) = %#v, want an empty map", got)
+	}
+}
+
+func TestRatePeriod(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"1", time.Second, false},
+		{"0.5", 2 * time.Second, false},
+		{"1000", time.Millisecond, false},
+		{"3", 333333333 * time.Nanosecond, false},
+		{"1e6", minPeriod, false},
+		{"1.5e6", 0, true},
+		{"0", 0, true},
+		{"-10", 0, true},
+		{"NaN", 0, true},
+		{"+Inf", 0, true},
+		{"fast", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			hz, got, err := parseRate(tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, api.ErrInvalid) {
+					t.Errorf("parseRate = %v, %v, want an invalid argument error", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("period = %v, want %v", got, tt.want)
+			}
+			if p, err := ratePeriod(hz); err != nil || p != got {
+				t.Errorf("ratePeriod(%v) = %v, %v, want %v", hz, p, err, got)
+			}
+		})
+	}
+}
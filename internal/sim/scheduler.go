@@ -0,0 +1,306 @@
+package sim
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// catch-up ticks a payload may owe, further ones are skipped
+const maxCatchUp = 100
+
+// Overrun policies, what a payload does with the ticks that fall due while
+// it is still building
+const (
+	overrunSkip    = "skip"     // merge them into the next build
+	overrunCatchUp = "catch_up" // build them back to back once it is done
+)
+
+// scheduler drives the ticks of every payload of a simulation from a single
+// earliest-deadline heap and hands the builds to a pool of workers. Ticks are
+// aligned on a common epoch, so payloads with the same period and phase tick
+// together.
+type scheduler struct {
+	mu    sync.Mutex
+	epoch time.Time
+	queue tickerQueue   // scheduled tickers by deadline
+	wake  chan struct{} // the earliest deadline changed
+	jobs  chan tickJob
+}
+
+// tickJob is a tick handed to a worker
+type tickJob struct {
+	t         *ticker
+	gen       uint64
+	scheduled time.Time
+	merged    int
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		epoch: time.Now(),
+		wake:  make(chan struct{}, 1),
+		jobs:  make(chan tickJob),
+	}
+}
+
+// run dispatches ticks to a pool of workers until ctx is done. workers is the
+// size of the pool, 0 for one per CPU. Within spin of the next deadline the
+// scheduler spins on its processor instead of sleeping, since timers do not
+// wake up reliably within a millisecond; 0 always sleeps. It never spins on a
+// single processor, where spinning keeps the network poller, and with it the
+// store and every socket, from running.
+func (s *scheduler) run(ctx context.Context, workers int, spin time.Duration) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range s.jobs {
+				s.work(job)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(s.jobs)
+
+	if runtime.GOMAXPROCS(0) == 1 {
+		spin = 0
+	}
+	timer := newStoppedTimer()
+	defer timer.Stop()
+	for {
+		job, wait, ok := s.next(time.Now())
+		if ok {
+			select {
+			case s.jobs <- job:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		if wait > 0 && wait <= spin {
+			runtime.Gosched()
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer.Reset(wait - spin)
+			timeout = timer.C
+		}
+		select {
+		case <-timeout:
+		case <-s.wake:
+		case <-ctx.Done():
+			return
+		}
+		timer.Stop()
+	}
+}
+
+// next pops the earliest due tick. When none is due it returns how long until
+// one is, or 0 when nothing is scheduled.
+func (s *scheduler) next(now time.Time) (tickJob, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) > 0 {
+		t := s.queue[0]
+		if wait := t.next.Sub(now); wait > 0 {
+			return tickJob{}, wait, false
+		}
+
+		// deadlines the scheduler itself fell behind on are missed ticks too
+		scheduled := t.next
+		missed := int(now.Sub(scheduled) / t.period)
+		t.next = scheduled.Add(time.Duration(missed+1) * t.period)
+		heap.Fix(&s.queue, 0)
+
+		if t.busy {
+			// overrun, the previous build is still going
+			if t.policy == overrunCatchUp && t.owed < maxCatchUp {
+				t.owed = min(t.owed+1+missed, maxCatchUp)
+			} else {
+				t.merged += 1 + missed
+			}
+			continue
+		}
+		t.busy = true
+		job := tickJob{t: t, gen: t.gen, scheduled: scheduled, merged: t.merged + missed}
+		t.last, t.merged = scheduled, 0
+		return job, 0, true
+	}
+	return tickJob{}, 0, false
+}
+
+// work runs a tick and the ticks its payload owes under the catch-up policy
+func (s *scheduler) work(job tickJob) {
+	t := job.t
+	for {
+		s.mu.Lock()
+		stale := t.gen != job.gen
+		if stale {
+			t.idle(job.gen)
+		}
+		s.mu.Unlock()
+		if stale {
+			return
+		}
+		t.fire(job.scheduled, job.merged)
+
+		s.mu.Lock()
+		if t.gen != job.gen || t.owed == 0 {
+			t.idle(job.gen)
+			s.mu.Unlock()
+			return
+		}
+		t.owed--
+		t.last = t.last.Add(t.period)
+		job.scheduled, job.merged = t.last, 0
+		s.mu.Unlock()
+	}
+}
+
+// notify wakes the dispatch loop up to look at the earliest deadline again
+func (s *scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// align returns the first tick after now of a period and phase
+func (s *scheduler) align(now time.Time, period, phase time.Duration) time.Time {
+	origin := s.epoch.Add(phase)
+	if now.Before(origin) {
+		return origin
+	}
+	return origin.Add((now.Sub(origin)/period + 1) * period)
+}
+
+// last returns the deadline of the last tick at or before t of a period and
+// phase
+func (s *scheduler) last(t time.Time, period, phase time.Duration) time.Time {
+	origin := s.epoch.Add(phase)
+	n := t.Sub(origin) / period
+	if t.Before(origin.Add(n * period)) {
+		n--
+	}
+	return origin.Add(n * period)
+}
+
+// ticker is the tick source of a payload. Its ticks are built by fire on a
+// worker of the scheduler, never two at a time.
+type ticker struct {
+	s    *scheduler
+	fire func(scheduled time.Time, merged int)
+
+	// guarded by s.mu
+	period time.Duration
+	phase  time.Duration
+	policy string
+	next   time.Time // deadline of the next tick
+	last   time.Time // deadline of the last tick handed to a worker
+	index  int       // in the queue, -1 while stopped
+	gen    uint64    // bumped when stopped or reset, to drop owed ticks
+	busy   bool      // a worker is building a tick
+	owed   int       // catch-up ticks to build once done
+	merged int       // ticks skipped since the last build
+}
+
+// ticker returns a stopped ticker that calls fire on every tick
+func (s *scheduler) ticker(fire func(scheduled time.Time, merged int)) *ticker {
+	return &ticker{s: s, fire: fire, policy: overrunSkip, index: -1}
+}
+
+// setTiming changes the phase and overrun policy, from the next Reset
+func (t *ticker) setTiming(phase time.Duration, policy string) {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	t.phase, t.policy = phase, policy
+}
+
+// Reset (re)starts the ticker with period d, from the next aligned tick
+func (t *ticker) Reset(d time.Duration) {
+	s := t.s
+	s.mu.Lock()
+	t.period = d
+	t.gen++
+	t.owed, t.merged = 0, 0
+	t.next = s.align(time.Now(), d, t.phase)
+	if t.index < 0 {
+		heap.Push(&s.queue, t)
+	} else {
+		heap.Fix(&s.queue, t.index)
+	}
+	s.mu.Unlock()
+	s.notify()
+}
+
+// idle marks the ticker done building. Ticks that fell due after a Reset
+// during the build are skipped rather than caught up, the catch-up would place
+// them after the last deadline of the previous timing. Callers hold s.mu.
+func (t *ticker) idle(gen uint64) {
+	if t.gen != gen {
+		t.merged += t.owed
+		t.owed = 0
+	}
+	t.busy = false
+}
+
+// Stop stops the ticker. A tick a worker is already building completes.
+func (t *ticker) Stop() {
+	s := t.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.gen++
+	t.owed, t.merged = 0, 0
+	if t.index >= 0 {
+		heap.Remove(&s.queue, t.index)
+	}
+}
+
+// tickerQueue is a heap of tickers by deadline
+type tickerQueue []*ticker
+
+func (q tickerQueue) Len() int           { return len(q) }
+func (q tickerQueue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+func (q tickerQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+
+func (q *tickerQueue) Push(x any) {
+	t := x.(*ticker)
+	t.index = len(*q)
+	*q = append(*q, t)
+}
+
+func (q *tickerQueue) Pop() any {
+	old := *q
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*q = old[:len(old)-1]
+	return t
+}
+
+// parseOverrun checks an overrun policy, empty for the default
+func parseOverrun(policy string) (string, error) {
+	switch policy {
+	case "":
+		return overrunSkip, nil
+	case overrunSkip, overrunCatchUp:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown overrun policy %q, expected %s or %s", policy, overrunSkip, overrunCatchUp)
+}
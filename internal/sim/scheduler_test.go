@@ -0,0 +1,287 @@
+package sim
+
+import (
+	"testing"
+	"time"
+)
+
+// fired is a tick built by a test ticker
+type fired struct {
+	at     time.Duration // after the epoch
+	merged int
+}
+
+// testScheduler returns a scheduler whose epoch lies ahead, so that tickers
+// reset now first tick at exactly epoch+phase
+func testScheduler() (*scheduler, time.Time) {
+	s := newScheduler()
+	s.epoch = time.Now().Add(time.Hour)
+	return s, s.epoch
+}
+
+// catchUp returns n ticks built back to back from the epoch
+func catchUp(n int) []fired {
+	ticks := make([]fired, n)
+	for i := range ticks {
+		ticks[i].at = time.Duration(i) * 10 * time.Millisecond
+	}
+	return ticks
+}
+
+// testTicker starts a ticker that records its ticks
+func testTicker(s *scheduler, period, phase time.Duration, policy string) (*ticker, *[]fired) {
+	var ticks []fired
+	t := s.ticker(func(scheduled time.Time, merged int) {
+		ticks = append(ticks, fired{scheduled.Sub(s.epoch), merged})
+	})
+	t.setTiming(phase, policy)
+	t.Reset(period)
+	return t, &ticks
+}
+
+func TestSchedulerAlign(t *testing.T) {
+	s := newScheduler()
+	t0 := s.epoch
+	ms := time.Millisecond
+	tests := []struct {
+		name          string
+		now           time.Duration
+		period, phase time.Duration
+		want          time.Duration
+	}{
+		{"at the epoch", 0, 10 * ms, 0, 10 * ms},
+		{"between ticks", 25 * ms, 10 * ms, 0, 30 * ms},
+		{"on a tick", 30 * ms, 10 * ms, 0, 40 * ms},
+		{"phase", 25 * ms, 10 * ms, 3 * ms, 33 * ms},
+		{"before the phase", 1 * ms, 10 * ms, 3 * ms, 3 * ms},
+		{"phase past the period", 25 * ms, 10 * ms, 14 * ms, 34 * ms},
+		{"before the epoch", -5 * ms, 10 * ms, 0, 0},
+		{"long period", 2500 * ms, time.Second, 250 * ms, 3250 * ms},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.align(t0.Add(tt.now), tt.period, tt.phase).Sub(t0)
+			if got != tt.want {
+				t.Errorf("align = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerLast(t *testing.T) {
+	s := newScheduler()
+	t0 := s.epoch
+	ms := time.Millisecond
+	tests := []struct {
+		name          string
+		now           time.Duration
+		period, phase time.Duration
+		want          time.Duration
+	}{
+		{"at the epoch", 0, 10 * ms, 0, 0},
+		{"between ticks", 25 * ms, 10 * ms, 0, 20 * ms},
+		{"on a tick", 30 * ms, 10 * ms, 0, 30 * ms},
+		{"phase", 25 * ms, 10 * ms, 3 * ms, 23 * ms},
+		{"before the phase", 1 * ms, 10 * ms, 3 * ms, -7 * ms},
+		{"before the epoch", -5 * ms, 10 * ms, 0, -10 * ms},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.last(t0.Add(tt.now), tt.period, tt.phase).Sub(t0)
+			if got != tt.want {
+				t.Errorf("last = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerNext(t *testing.T) {
+	s, t0 := testScheduler()
+	ms := time.Millisecond
+	// same period, so they tick together but for the phase
+	a, _ := testTicker(s, 10*ms, 0, overrunSkip)
+	b, _ := testTicker(s, 10*ms, 4*ms, overrunSkip)
+
+	if _, wait, ok := s.next(t0.Add(-2 * ms)); ok || wait != 2*ms {
+		t.Errorf("next before the first tick = %v, %v, want a wait of 2ms", wait, ok)
+	}
+	steps := []struct {
+		now   time.Duration
+		t     *ticker
+		at    time.Duration
+		merge int
+	}{
+		{0, a, 0, 0},
+		{4 * ms, b, 4 * ms, 0},
+		// the scheduler fell behind on both, a tick carries the first deadline
+		// it missed and merges the others
+		{35 * ms, a, 10 * ms, 2},
+		{35 * ms, b, 14 * ms, 2},
+	}
+	for _, st := range steps {
+		job, _, ok := s.next(t0.Add(st.now))
+		if !ok || job.t != st.t || job.scheduled.Sub(t0) != st.at || job.merged != st.merge {
+			t.Fatalf("next at %v = %v %v merged %d, want the tick at %v merged %d",
+				st.now, ok, job.scheduled.Sub(t0), job.merged, st.at, st.merge)
+		}
+		job.t.busy = false
+	}
+	if _, wait, ok := s.next(t0.Add(35 * ms)); ok || wait != 5*ms {
+		t.Errorf("next = %v, %v, want a wait of 5ms until the next tick", wait, ok)
+	}
+
+	a.Stop()
+	b.Stop()
+	if _, wait, ok := s.next(t0.Add(time.Hour)); ok || wait != 0 {
+		t.Errorf("next with every ticker stopped = %v, %v, want nothing", wait, ok)
+	}
+}
+
+func TestSchedulerOverrun(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name   string
+		policy string
+		busy   []time.Duration // the scheduler runs at these times while the first tick builds
+		stop   bool            // the ticker is stopped during the first build
+		fired  []fired
+		next   fired // the tick after the build
+	}{
+		{
+			name:   "skip",
+			policy: overrunSkip,
+			busy:   []time.Duration{10 * ms, 20 * ms},
+			fired:  []fired{{0, 0}},
+			next:   fired{30 * ms, 2},
+		},
+		{
+			name:   "catch up",
+			policy: overrunCatchUp,
+			busy:   []time.Duration{10 * ms, 20 * ms},
+			fired:  []fired{{0, 0}, {10 * ms, 0}, {20 * ms, 0}},
+			next:   fired{30 * ms, 0},
+		},
+		{
+			name:   "catch up missed deadlines",
+			policy: overrunCatchUp,
+			busy:   []time.Duration{25 * ms},
+			fired:  []fired{{0, 0}, {10 * ms, 0}, {20 * ms, 0}},
+			next:   fired{30 * ms, 0},
+		},
+		{
+			name:   "catch up bounded",
+			policy: overrunCatchUp,
+			busy:   []time.Duration{(maxCatchUp + 5) * 10 * ms, (maxCatchUp + 6) * 10 * ms},
+			fired:  catchUp(1 + maxCatchUp),
+			next:   fired{(maxCatchUp + 7) * 10 * ms, 1},
+		},
+		{
+			name:   "stopped drops the owed ticks",
+			policy: overrunCatchUp,
+			busy:   []time.Duration{10 * ms, 20 * ms},
+			stop:   true,
+			fired:  []fired{{0, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, t0 := testScheduler()
+			tk, ticks := testTicker(s, 10*ms, 0, tt.policy)
+			job, _, ok := s.next(t0)
+			if !ok {
+				t.Fatal("no tick at the epoch")
+			}
+			for _, now := range tt.busy {
+				if _, _, ok := s.next(t0.Add(now)); ok {
+					t.Fatalf("tick at %v handed out while the previous one builds", now)
+				}
+			}
+			if tt.stop {
+				fire := tk.fire
+				tk.fire = func(scheduled time.Time, merged int) {
+					fire(scheduled, merged)
+					tk.Stop()
+				}
+			}
+			s.work(job)
+			if tk.busy {
+				t.Error("still busy after the build")
+			}
+
+			got := *ticks
+			if len(got) != len(tt.fired) {
+				t.Fatalf("fired %d ticks, want %d", len(got), len(tt.fired))
+			}
+			for i := range got {
+				if got[i] != tt.fired[i] {
+					t.Errorf("tick %d = %+v, want %+v", i, got[i], tt.fired[i])
+				}
+			}
+
+			job, _, ok = s.next(t0.Add(time.Hour))
+			if tt.stop {
+				if ok {
+					t.Errorf("stopped ticker ticked at %v", job.scheduled.Sub(t0))
+				}
+				return
+			}
+			// far behind by now, what is merged past the next deadline is not of interest
+			if !ok || job.scheduled.Sub(t0) != tt.next.at || job.merged < tt.next.merged {
+				t.Errorf("next tick at %v merged %d, want %v merged %d",
+					job.scheduled.Sub(t0), job.merged, tt.next.at, tt.next.merged)
+			}
+		})
+	}
+}
+
+func TestTickerReset(t *testing.T) {
+	s, t0 := testScheduler()
+	ms := time.Millisecond
+	tk, _ := testTicker(s, 10*ms, 2*ms, overrunSkip)
+	job, _, _ := s.next(t0.Add(2 * ms))
+
+	// a new timing applies from its next aligned tick, even during a build
+	tk.setTiming(5*ms, overrunCatchUp)
+	tk.Reset(20 * ms)
+	if tk.next.Sub(t0) != 5*ms || tk.period != 20*ms || tk.policy != overrunCatchUp {
+		t.Errorf("after Reset next %v period %v policy %s, want 5ms, 20ms and catch_up",
+			tk.next.Sub(t0), tk.period, tk.policy)
+	}
+	if _, _, ok := s.next(t0.Add(45 * ms)); ok {
+		t.Error("tick handed out while the previous one builds")
+	}
+	if tk.owed != 3 {
+		t.Errorf("owed %d, want 3", tk.owed)
+	}
+
+	// the build of the previous timing is stale, the ticks due under the new
+	// one while it ran are skipped
+	s.work(job)
+	if tk.busy || tk.owed != 0 {
+		t.Errorf("after a stale build busy %v owed %d, want idle and nothing owed", tk.busy, tk.owed)
+	}
+	job, _, ok := s.next(t0.Add(65 * ms))
+	if !ok || job.scheduled.Sub(t0) != 65*ms || job.merged != 3 {
+		t.Errorf("next tick at %v merged %d, want 65ms merged 3", job.scheduled.Sub(t0), job.merged)
+	}
+}
+
+func TestParseOverrun(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    string
+		wantErr bool
+	}{
+		{"", overrunSkip, false},
+		{"skip", overrunSkip, false},
+		{"catch_up", overrunCatchUp, false},
+		{"Skip", "", true},
+		{"drop", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseOverrun(tt.policy)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseOverrun(%q) = %q, %v, want %q, error %v", tt.policy, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package sim
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/Sapper177/datagensim/pkg/config"
+	"github.com/Sapper177/datagensim/pkg/pktgen"
+)
+
+// newSender creates the output transport for the bus described by cfg.
+// For network buses IPv4 or IPv6 is selected from the configured source and destination hosts.
+func newSender(cfg *config.Config) (pktgen.Sender, error) {
+	if cfg.BusType != "serial" {
+		if _, err := cfg.IPVersion(); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []pktgen.PacketOption{}
+	if cfg.HopLimit != 0 {
+		opts = append(opts, pktgen.WithHopLimit(cfg.HopLimit))
+	}
+	if cfg.FlowLabel != 0 {
+		opts = append(opts, pktgen.WithFlowLabel(cfg.FlowLabel))
+	}
+	if cfg.DestHost.IsMulticast() {
+		var iface *net.Interface
+		if cfg.Interface.Name != "" {
+			iface = &cfg.Interface
+		}
+		opts = append(opts, pktgen.WithMulticast(iface, cfg.MulticastTTL, cfg.MulticastLoop))
+	}
+	if cfg.Broadcast || pktgen.IsBroadcast(cfg.DestHost, &cfg.Interface) {
+		opts = append(opts, pktgen.WithBroadcast())
+	}
+
+	switch cfg.BusType {
+	case "", "udp":
+		opts = append(opts,
+			pktgen.WithIpLayer(cfg.SrcHost, cfg.DestHost),
+			pktgen.WithUdpLayer(cfg.SrcPort, cfg.DestPort),
+		)
+		return pktgen.NewUDPSender(opts...)
+	case "af_xdp", "xdp":
+		group := cfg.DestHost.IsMulticast() || cfg.Broadcast
+		if cfg.Interface.Name == "" || (cfg.DestMAC == nil && !group) {
+			return nil, fmt.Errorf("bus %s: af_xdp requires an interface and destination MAC", cfg.BusName)
+		}
+		// raw frames carry the source address, the system does not fill it in
+		if cfg.SrcHost == nil || cfg.SrcHost.IsUnspecified() {
+			return nil, fmt.Errorf("bus %s: af_xdp requires a source host", cfg.BusName)
+		}
+		return pktgen.NewAFXdpSender(
+			cfg.Interface.Name,
+			cfg.SrcHost,
+			cfg.DestHost,
+			cfg.SrcPort,
+			cfg.DestPort,
+			0,
+			cfg.Interface.HardwareAddr,
+			cfg.DestMAC,
+			0,
+			opts...,
+		), nil
+	case "serial":
+		framing, err := pktgen.ParseFraming(cfg.SerialFraming)
+		if err != nil {
+			return nil, fmt.Errorf("bus %s: %w", cfg.BusName, err)
+		}
+		baud := cfg.SerialBaud
+		if baud == 0 {
+			baud = config.SERIAL_BAUD_DEFAULT
+		}
+		s, err := pktgen.NewSerialSender(cfg.SerialDevice, baud, framing, cfg.SerialGap)
+		if err != nil {
+			return nil, fmt.Errorf("bus %s: %w", cfg.BusName, err)
+		}
+		if p := s.SlavePath(); p != "" {
+			slog.Info("serial output on pty", "bus", cfg.BusName, "pty", p)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("bus %s: unknown bus type %q", cfg.BusName, cfg.BusType)
+	}
+}
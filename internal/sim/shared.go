@@ -0,0 +1,268 @@
+package sim
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Sapper177/datagensim/pkg/database"
+)
+
+// sharedState generates the bus-level data points. A data point is shared
+// when more than one payload of the bus contains it, or when its info sets
+// shared to true; shared set to false keeps a generator per payload. Shared
+// data points are updated once per bus tick and every payload samples the
+// same value, converted to its own layout. Bus ticks are scheduler ticks, a
+// payload ticking before the bus tick of the same deadline brings it forward.
+type sharedState struct {
+	mu     sync.RWMutex
+	db     *database.RedisClient
+	bus    string
+	dpMap  map[string]dataPoint // data id -> data point, holding the value of the current bus tick
+	plan   *updatePlan          // how each data point gets its value
+	defs   map[string]string    // data id -> stored definition, kept across reloads
+	freq   time.Duration        // bus tick, the period of the fastest payload sharing data
+	tick   time.Time            // deadline of the bus tick the values belong to
+	sched  *scheduler
+	ticker *ticker
+	logger *slog.Logger
+
+	// stored values of the data points the plans of the bus reference but do
+	// not generate, refreshed by the store sync
+	external *externalValues
+}
+
+func newSharedState(db *database.RedisClient, bus string, sched *scheduler, logger *slog.Logger) *sharedState {
+	s := &sharedState{
+		db:       db,
+		bus:      bus,
+		dpMap:    make(map[string]dataPoint),
+		plan:     &updatePlan{},
+		sched:    sched,
+		logger:   logger,
+		external: newExternalValues(),
+	}
+	s.ticker = sched.ticker(func(scheduled time.Time, _ int) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.advance(scheduled)
+	})
+	return s
+}
+
+// load finds the shared data points of the payloads and (re)creates them
+// from the store. Their first values are generated before load returns.
+func (s *sharedState) load(payloadIds []string) error {
+	owners, hz, err := findShared(s.db, payloadIds)
+	if err != nil {
+		return err
+	}
+	dps := make(map[string]dataPoint, len(owners))
+	defs := make(map[string]string, len(owners))
+	for _, dataId := range slices.Sorted(maps.Keys(owners)) {
+		dp, def, err := newStoredDataPoint(s.db, owners[dataId], dataId)
+		if err != nil {
+			return err
+		}
+		dps[dataId], defs[dataId] = dp, def
+	}
+
+	plan, err := newUpdatePlan(s.db, dps, nil, s.external)
+	if err != nil {
+		return err
+	}
+	return s.replace(dps, defs, plan, hz)
+}
+
+// replace swaps in the shared data points dps of definitions defs, updated by
+// plan on a bus tick of hz. Those whose definition did not change carry on.
+func (s *sharedState) replace(dps map[string]dataPoint, defs map[string]string, plan *updatePlan, hz float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// data points a reload does not change keep their engine state and value
+	for id, def := range defs {
+		if old, ok := s.defs[id]; ok && old == def {
+			dps[id] = s.dpMap[id]
+			plan.keep(s.plan, id)
+		}
+	}
+	s.dpMap, s.plan, s.defs = dps, plan, defs
+	s.ticker.Stop()
+	s.freq = 0
+	if len(dps) == 0 {
+		return nil
+	}
+	period, err := ratePeriod(hz)
+	if err != nil {
+		return fmt.Errorf("no payload rate for the shared data points of Bus %s: %w", s.bus, err)
+	}
+	s.freq = period
+	s.tick = s.sched.last(time.Now(), s.freq, 0)
+	s.ticker.Reset(s.freq)
+	s.update()
+	s.logger.Info("shared data points loaded", "count", len(dps), "period", s.freq)
+	return nil
+}
+
+// findShared returns the shared data points of the payloads, mapped to the
+// first payload containing each, and the rate of the fastest payload
+// sharing data in Hz
+func findShared(db *database.RedisClient, payloadIds []string) (map[string]string, float64, error) {
+	owners := make(map[string][]string) // data id -> payload ids
+	rates := make(map[string]float64)   // payload id -> Hz
+	for _, pid := range payloadIds {
+		dataIds, err := db.GetPayloadData(pid)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error getting payload data ids for ID (%s): %s", pid, err)
+		}
+		for _, dataId := range dataIds {
+			owners[dataId] = append(owners[dataId], pid)
+		}
+		pInfo, err := db.GetPayloadInfo(pid)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error getting payload info for ID (%s): %s", pid, err)
+		}
+		if rates[pid], _, err = parseRate(pInfo["frequency"]); err != nil {
+			return nil, 0, fmt.Errorf("invalid frequency for payload ID (%s): %w", pid, err)
+		}
+	}
+
+	shared := make(map[string]string)
+	hz := 0.0
+	for _, dataId := range slices.Sorted(maps.Keys(owners)) {
+		info, err := db.GetDataInfo(dataId)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error getting data point info for ID (%s): %s", dataId, err)
+		}
+		isShared := len(owners[dataId]) > 1
+		if v, ok := info["shared"]; ok {
+			if isShared, err = strconv.ParseBool(v); err != nil {
+				return nil, 0, fmt.Errorf("invalid shared flag for data ID (%s): %q", dataId, v)
+			}
+		}
+		if !isShared {
+			continue
+		}
+		shared[dataId] = owners[dataId][0]
+		for _, pid := range owners[dataId] {
+			hz = max(hz, rates[pid])
+		}
+	}
+	return shared, hz, nil
+}
+
+// at brings the shared data points to the bus tick of a payload tick
+// scheduled at t, unless they are there already. Payloads ticking within one
+// bus tick sample the same values, whichever worker runs first.
+func (s *sharedState) at(t time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.RLock()
+	current := s.freq == 0 || !s.sched.last(t, s.freq, 0).After(s.tick)
+	s.mu.RUnlock()
+	if current {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.freq > 0 {
+		s.advance(s.sched.last(t, s.freq, 0))
+	}
+}
+
+// advance moves the shared data points on to the bus tick of deadline tick,
+// skipping ticks other payloads already brought them to. Callers hold s.mu.
+func (s *sharedState) advance(tick time.Time) {
+	if !tick.After(s.tick) {
+		return
+	}
+	s.tick = tick
+	s.update()
+}
+
+// stop stops the bus ticks
+func (s *sharedState) stop() {
+	s.ticker.Stop()
+}
+
+// update generates the next value of every shared data point. Callers hold s.mu.
+func (s *sharedState) update() {
+	now := time.Now()
+	for _, id := range s.plan.order {
+		if err := s.plan.next(id, s.dpMap[id], now); err != nil {
+			s.logger.Error("updating shared data point", "data", id, "err", err)
+		}
+	}
+}
+
+// values adds the values of the shared data points to dst, as stored
+func (s *sharedState) values(dst map[string]string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for id, dp := range s.dpMap {
+		dst[id] = string(dp.appendValue(nil))
+	}
+}
+
+// has reports whether dataId is a shared data point
+func (s *sharedState) has(dataId string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.dpMap[dataId]
+	return ok
+}
+
+// float returns the value of the current bus tick of a shared data point as
+// expressions see it, and reports whether dataId is shared
+func (s *sharedState) float(dataId string) (float64, bool, error) {
+	if s == nil {
+		return 0, false, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dp, ok := s.dpMap[dataId]
+	if !ok {
+		return 0, false, nil
+	}
+	if v, ok := dp.float(); ok {
+		return v, true, nil
+	}
+	return 0, true, fmt.Errorf("%s is not numeric: %q", dataId, dp.appendValue(nil))
+}
+
+// sample loads the value of the current bus tick of a shared data point into
+// dp, and reports whether dataId is shared
+func (s *sharedState) sample(dataId string, dp dataPoint) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	src, ok := s.dpMap[dataId]
+	if ok {
+		dp.load(src)
+	}
+	return ok
+}
+
+// callData runs fn on a shared data point; a no-op for other data ids
+func (s *sharedState) callData(dataId string, fn func(dp dataPoint) error) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dp, ok := s.dpMap[dataId]
+	if !ok {
+		return nil
+	}
+	return fn(dp)
+}
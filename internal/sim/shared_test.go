@@ -0,0 +1,74 @@
+package sim
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Sapper177/datagensim/pkg/engine"
+)
+
+func TestSharedSample(t *testing.T) {
+	sched, t0 := testScheduler()
+	ms := time.Millisecond
+	newDp := func(offset string) dataPoint {
+		t.Helper()
+		dp, err := buildDataPoint("1", "count", map[string]string{"type": "int32", "offset": offset, "size": "32"},
+			map[string]string{"min": "0", "max": "100", "step": "1", "frequency": "10"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dp
+	}
+
+	// a counter stepping once per bus tick
+	src := newDp("0")
+	expr, err := engine.ParseExpr("count + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSharedState(nil, "A", sched, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.dpMap = map[string]dataPoint{"count": src}
+	s.plan = &updatePlan{
+		dps:     s.dpMap,
+		order:   []string{"count"},
+		derived: map[string]*engine.Expr{"count": expr},
+	}
+	s.plan.resolve = s.plan.lookup
+	s.freq, s.tick = 10*ms, t0
+
+	// two payloads of the bus, each holding the value in its own layout
+	a, b := newDp("0"), newDp("32")
+	sample := func(p dataPoint, at time.Duration) string {
+		s.at(t0.Add(at))
+		if !s.sample("count", p) {
+			t.Fatal("count not shared")
+		}
+		return string(p.appendValue(nil))
+	}
+	steps := []struct {
+		name string
+		p    dataPoint
+		at   time.Duration
+		bus  bool // the bus tick fires before the payload
+		want string
+	}{
+		{"first payload brings the bus tick forward", a, 10 * ms, false, "1"},
+		{"second payload, same bus tick", b, 14 * ms, false, "1"},
+		{"bus tick fires late", a, 10 * ms, true, "1"},
+		{"second payload first on the next tick", b, 20 * ms, false, "2"},
+		{"first payload, same bus tick", a, 20 * ms, false, "2"},
+		{"bus tick fires first", a, 30 * ms, true, "3"},
+		{"second payload after the bus tick", b, 39 * ms, false, "3"},
+		{"ticks missed in between step once", a, 65 * ms, false, "4"},
+	}
+	for _, st := range steps {
+		if st.bus {
+			s.ticker.fire(t0.Add(st.at), 0)
+		}
+		if got := sample(st.p, st.at); got != st.want {
+			t.Errorf("%s: sampled %s at %v, want %s", st.name, got, st.at, st.want)
+		}
+	}
+}
@@ -2,118 +2,162 @@ package sim
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"time"
+	"log/slog"
+	"os"
+	"sync"
 
 	"github.com/Sapper177/datagensim/pkg/config"
-	"github.com/Sapper177/datagensim/pkg/database"
 )
 
 type PayloadChans struct {
+	mu        sync.Mutex  // held while the payload is built, controlled or sent
 	writeChan chan Packet // push to write
 	readChan  chan Packet // pull from read
-	ticker    *time.Ticker
+	ctlChan   chan controlMsg
+	sched     *scheduler      // drives ticker
+	ticker    *ticker         // created by the manager
+	buffers   *bufferPool     // payload buffers of queued packets
+	delayed   *delayQueue     // impaired packets held back, created by the manager
+	values    *valueHub       // per-tick values for stream subscribers
+	monitor   *payloadMonitor // fault counters
+	shared    *sharedState    // bus-level data points
+	logger    *slog.Logger    // labels records with the bus and payload
 }
 
-func Sim(ctx *context.Context, cfg *config.Config) {
-	// Set up Bus
-	// bus := Bus{Name: cfg.BusName, Interface: cfg.Interface.Name, IP: cfg.SrcHost.String()}
+// Option changes how Sim runs
+type Option func(*options)
 
-	// Set up database interface
-	db := database.NewRedisClient(
-		ctx,
-		cfg.DbHost+":"+cfg.DbPort,
-		cfg.DbPassword,
-		cfg.DbNum,
-		cfg.DbReadTimeout,
-		cfg.DbWriteTimeout,
-	)
-
-	// Get payload configs from database
-	payloadIds, err := db.GetPayloads(cfg.BusName)
-	if err != nil {
-		log.Fatalf("Did not find payload IDs for Bus %s: %s", cfg.BusName, err)
-	}
-
-	// Create channel that will be used contain sent packet data
-	infoChan := make(chan packetInfo, 100)
-
-	// initialize payload routines
-	initPayloads(ctx, cfg, payloadIds, db, infoChan)
+type options struct {
+	capture *Capture         // copy of every payload sent
+	reload  <-chan os.Signal // reloads every bus
+}
 
-	// initialize payload monitoring
-	go initMonitoring(cfg, infoChan)
+// WithCapture writes every payload sent on any bus to c.
+func WithCapture(c *Capture) Option {
+	return func(o *options) { o.capture = c }
+}
 
-	// Run Simulation
-	// go sim(payloadManagers, infoChan)
+// WithReload reloads the payloads of every bus from the store whenever a
+// signal arrives on c, typically SIGHUP.
+func WithReload(c <-chan os.Signal) Option {
+	return func(o *options) { o.reload = c }
 }
 
-func initPayloads(ctx *context.Context, cfg *config.Config, payloadIds []string, db *database.RedisClient, infoChan chan<- packetInfo) {
+// Sim runs the simulation until ctx is done or a part of it fails, or until
+// the scenario has run with cfg.ScenarioExit. The payloads then stop and send
+// the packets they have queued, and Sim returns the first error, that of a
+// scenario with failed steps otherwise, or nil after a clean stop.
+func Sim(ctx *context.Context, cfg *config.Config, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// Load the scenario up front so that a bad file fails before anything runs
+	var sc *scenario
+	if cfg.Scenario != "" {
+		var err error
+		if sc, err = loadScenario(cfg.Scenario); err != nil {
+			return fmt.Errorf("unable to load scenario: %w", err)
+		}
+		if err := sc.checkBuses(busNames(cfg)); err != nil {
+			return fmt.Errorf("unable to load scenario: %w", err)
+		}
+	}
 
-	// Spawn thread for each payload
-	for i := range payloadIds {
+	// Set up database interface
+	db := newDB(ctx, cfg)
+	defer db.Close()
 
-		// get payload info
-		pInfo, err := db.GetPayloadInfo(payloadIds[i])
+	// Create channel that will be used contain sent packet data, the monitor
+	// reads it until every payload has stopped
+	infoChan := make(chan packetInfo, 100)
+	monitor := newPayloadMonitor()
+	monitorDone := make(chan struct{})
+	go func() {
+		procPayloadMon(monitor, infoChan)
+		close(monitorDone)
+	}()
+
+	// start every bus, monitoring, streaming and scheduling are shared
+	sup := newSupervisor(*ctx)
+	var scenarioErr error // read once the scenario runner has returned
+	values := newValueHub()
+	sched := newScheduler()
+	sup.run("scheduler", func() error {
+		sched.run(sup.ctx, cfg.Workers, cfg.SchedSpin)
+		return nil
+	})
+	var buses []*bus
+	for _, busCfg := range cfg.BusConfigs() {
+		b, err := startBus(sup, busCfg, db, monitor, values, sched, infoChan, &o)
+		if b != nil {
+			buses = append(buses, b)
+		}
 		if err != nil {
-			log.Printf("No info found for ID: %s -> %s", pInfo, err)
+			sup.fail(fmt.Errorf("bus %s: %w", busCfg.BusName, err))
+			break
 		}
-		var freq time.Duration                     // hz
-		fmt.Sscanf(pInfo["frequency"], "%d", freq) // get Hz in float
-		freq = 1000000 / freq * time.Microsecond   // in microseconds
-
-		ticker := time.NewTicker(freq)
-		defer ticker.Stop()
+	}
 
-		// Create channels for i/o
-		cs := PayloadChans{
-			writeChan: make(chan Packet, 3*len(payloadIds)),
-			readChan:  make(chan Packet, len(payloadIds)),
-			ticker:    ticker,
+	if sup.ctx.Err() == nil {
+		// initialize control APIs and payload monitoring
+		apiCtl := newAPIController(buses, db, monitor, values)
+		if cfg.GRPCPort != 0 {
+			sup.run("gRPC server", func() error { return initGRPC(&sup.ctx, cfg, apiCtl) })
 		}
+		sup.run("metrics server", func() error { return initMonitoring(&sup.ctx, cfg, monitor, apiCtl) })
+
+		// Run scenario, its outcome is the exit status of a run that did not fail otherwise
+		if sc != nil {
+			sup.run("scenario "+sc.Name, func() error {
+				err := newScenarioRunner(sc, apiCtl).run(sup.ctx)
+				if errors.Is(err, context.Canceled) {
+					return nil
+				}
+				if cfg.ScenarioExit {
+					if err == nil {
+						sup.cancel()
+					}
+					return err
+				}
+				if err != nil {
+					slog.Error("scenario failed", "scenario", sc.Name, "err", err)
+					scenarioErr = err
+				}
+				return nil
+			})
+		}
+		slog.Info("simulation running", "buses", len(buses))
+	}
 
-		// spawn go routine for each payload
-		go manager(ctx, cfg, cs, payloadIds[i], pInfo["packet_type"], infoChan)
+	// stop on cancellation or the first error, once the payloads are drained
+	for stopped := false; !stopped; {
+		select {
+		case <-sup.ctx.Done():
+			stopped = true
+		case <-o.reload:
+			for _, b := range buses {
+				if _, err := b.reload(sup.ctx); err != nil {
+					b.logger.Error("reload failed", "err", err)
+				}
+			}
+		}
+	}
+	slog.Info("stopping simulation")
+	err := sup.wait()
+	for _, b := range buses {
+		b.close()
+	}
+	close(infoChan)
+	<-monitorDone
+	if err == nil {
+		err = scenarioErr
+	}
+	if err == nil {
+		slog.Info("simulation stopped")
 	}
+	return err
 }
-
-// func sim(payloadManagers []*payloadManager, infoChan chan<- packetInfo) {
-// 	for _, pm := range payloadManagers {
-
-// 		// check if pm is ready to send from frequency
-// 		if pm != nil {
-
-// 			if time.Since(pm.lastProc) >= pm.freq {
-// 				// generate packet
-// 				pkt, err := pm.generatePacket(infoChan)
-// 				intId, err1 := strconv.Atoi(pm.id)
-// 				if err1 != nil {
-// 					log.Printf("Error converting payload ID to int: %s", err)
-// 				}
-
-// 				if err != nil {
-// 					log.Printf("Error generating packet: %s", err)
-
-// 				} else {
-// 					pm.cs.writeChan <- pkt   // send packet to write channel
-// 					pm.lastProc = time.Now() // update last processed time
-// 					// send packet info to info channel
-// 					infoChan <- packetInfo{
-// 						PacketId:    intId,
-// 						PacketType:  pm.pktType,
-// 						PacketSize:  pkt.Size(),
-// 						Direction:   true,
-// 						Error:       false,
-// 						TxTime:      time.Now(),
-// 						ProcessTime: procTime,
-// 					}
-// 				}
-
-// 			} // wait until process time is up
-
-// 		} else {
-// 			log.Printf("Payload manager is nil for ID: %s", pm.id)
-// 		}
-// 	}
-// }
@@ -0,0 +1,143 @@
+package sim
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/Sapper177/datagensim/pkg/database"
+)
+
+// storedValues keeps the store in step with the values of the data points,
+// every store_sync. Between syncs the store lags behind the data points, so
+// readers within the simulation ask the payloads for the latest values. A
+// value changed in the store since the last sync, by a command or another
+// client, is taken over by the data points instead of overwritten.
+type storedValues struct {
+	db     *database.RedisClient
+	synced map[string]string // data id -> value in the store as of the last sync
+}
+
+func newStoredValues(db *database.RedisClient) *storedValues {
+	return &storedValues{db: db, synced: make(map[string]string)}
+}
+
+// sync writes current, the values of the data points by data id, to the
+// store. It returns the values changed in the store since the last sync
+// instead, for their data points to take over.
+func (v *storedValues) sync(current map[string]string) (map[string]string, error) {
+	if len(current) == 0 {
+		return nil, nil
+	}
+	ids := slices.Sorted(maps.Keys(current))
+	stored, err := v.db.GetValues(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]string)
+	writes := make(map[string]string, len(ids))
+	for i, id := range ids {
+		if last, ok := v.synced[id]; ok && stored[i] != last {
+			changed[id] = stored[i]
+		} else if stored[i] != current[id] {
+			writes[id] = current[id]
+		}
+		// a value a data point cannot take is overwritten on the next sync
+		v.synced[id] = stored[i]
+	}
+	if len(writes) == 0 {
+		return changed, nil
+	}
+	if err := v.db.SetValues(writes); err != nil {
+		return changed, err
+	}
+	maps.Copy(v.synced, writes)
+	return changed, nil
+}
+
+// externalValues holds the values of the data points that expressions and
+// plant inputs reference but that no plan of the bus generates, as of the
+// last store sync, so that ticks never wait on the store. Data ids stay
+// tracked until the bus stops.
+type externalValues struct {
+	mu     sync.RWMutex
+	values map[string]string // data id -> value, empty when the store has none
+}
+
+func newExternalValues() *externalValues {
+	return &externalValues{values: make(map[string]string)}
+}
+
+// track starts tracking the data ids not tracked yet, with their values in
+// the store
+func (e *externalValues) track(db *database.RedisClient, ids []string) error {
+	e.mu.RLock()
+	ids = slices.DeleteFunc(slices.Clone(ids), func(id string) bool {
+		_, ok := e.values[id]
+		return ok
+	})
+	e.mu.RUnlock()
+	if len(ids) == 0 {
+		return nil
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	stored, err := db.GetValues(ids)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, id := range ids {
+		e.values[id] = stored[i]
+	}
+	return nil
+}
+
+// refresh updates the tracked values, from current, the values of the data
+// points of the bus by data id, and from the store for the others
+func (e *externalValues) refresh(db *database.RedisClient, current map[string]string) error {
+	e.mu.RLock()
+	ids := slices.Sorted(maps.Keys(e.values))
+	e.mu.RUnlock()
+
+	values := make(map[string]string, len(ids))
+	var stored []string
+	for _, id := range ids {
+		if v, ok := current[id]; ok {
+			values[id] = v
+		} else {
+			stored = append(stored, id)
+		}
+	}
+	var err error
+	if len(stored) > 0 {
+		var vals []string
+		if vals, err = db.GetValues(stored); err == nil {
+			for i, id := range stored {
+				values[id] = vals[i]
+			}
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	maps.Copy(e.values, values)
+	return err
+}
+
+// float returns the value of a tracked data id as expressions see it
+func (e *externalValues) float(dataId string) (float64, error) {
+	e.mu.RLock()
+	val, ok := e.values[dataId]
+	e.mu.RUnlock()
+	if !ok || val == "" {
+		return 0, fmt.Errorf("unknown data id %s", dataId)
+	}
+	v, err := storedFloat(val)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not numeric: %q", dataId, val)
+	}
+	return v, nil
+}
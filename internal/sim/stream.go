@@ -0,0 +1,132 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Sapper177/datagensim/api"
+	"github.com/Sapper177/datagensim/pkg/config"
+
+	"google.golang.org/grpc"
+)
+
+// subscriber buffer, ticks beyond this are dropped for that subscriber
+const streamBuffer = 64
+
+// valueSub is a single Subscribe call
+type valueSub struct {
+	bus      string
+	payloads map[string]bool // empty = every payload on bus
+	ch       chan api.Tick
+}
+
+func (s *valueSub) wants(bus string, payloadId string) bool {
+	return s.bus == bus && (len(s.payloads) == 0 || s.payloads[payloadId])
+}
+
+// valueHub fans out the values emitted by payload managers to subscribers.
+// Payload managers never block on it, slow subscribers lose ticks.
+type valueHub struct {
+	mu   sync.RWMutex
+	subs map[*valueSub]struct{}
+}
+
+func newValueHub() *valueHub {
+	return &valueHub{subs: make(map[*valueSub]struct{})}
+}
+
+// wants reports whether any subscriber is interested in a payload, so that
+// managers only collect values while someone is listening
+func (h *valueHub) wants(bus string, payloadId string) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if sub.wants(bus, payloadId) {
+			return true
+		}
+	}
+	return false
+}
+
+// publish hands a tick to every interested subscriber without blocking
+func (h *valueHub) publish(tick api.Tick) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if !sub.wants(tick.Bus, tick.PayloadId) {
+			continue
+		}
+		select {
+		case sub.ch <- tick:
+		default:
+		}
+	}
+}
+
+// subscribe registers a subscriber until ctx is done
+func (h *valueHub) subscribe(ctx context.Context, bus string, payloadIds []string) <-chan api.Tick {
+	sub := &valueSub{
+		bus:      bus,
+		payloads: make(map[string]bool, len(payloadIds)),
+		ch:       make(chan api.Tick, streamBuffer),
+	}
+	for _, id := range payloadIds {
+		sub.payloads[id] = true
+	}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.subs, sub)
+		h.mu.Unlock()
+		close(sub.ch)
+	}()
+	return sub.ch
+}
+
+// Subscribe implements api.Streamer
+func (a *apiController) Subscribe(ctx context.Context, bus string, payloadIds []string) (<-chan api.Tick, error) {
+	b, err := a.bus(bus)
+	if err != nil {
+		return nil, err
+	}
+	known := b.ctl.ids()
+	for _, id := range payloadIds {
+		if !slices.Contains(known, id) {
+			return nil, fmt.Errorf("payload %s/%s: %w", bus, id, api.ErrNotFound)
+		}
+	}
+	return a.values.subscribe(ctx, bus, payloadIds), nil
+}
+
+// initGRPC serves the gRPC API until ctx is done. Open streams get
+// drainTimeout to finish.
+func initGRPC(ctx *context.Context, cfg *config.Config, apiCtl *apiController) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+	if err != nil {
+		return err
+	}
+	srv := grpc.NewServer()
+	api.RegisterGRPC(srv, apiCtl, apiCtl)
+	go func() {
+		<-(*ctx).Done()
+		force := time.AfterFunc(drainTimeout, srv.Stop)
+		srv.GracefulStop()
+		force.Stop()
+	}()
+
+	slog.Info("serving gRPC API", "addr", lis.Addr().String())
+	return srv.Serve(lis)
+}
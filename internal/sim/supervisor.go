@@ -0,0 +1,69 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// drainTimeout bounds how long servers wait for open requests on shutdown
+const drainTimeout = 5 * time.Second
+
+// supervisor owns the goroutines of a simulation. They run under its
+// context, which is cancelled when the parent context is or when the first
+// of them fails; that first error is the one wait returns.
+type supervisor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+func newSupervisor(parent context.Context) *supervisor {
+	s := &supervisor{}
+	s.ctx, s.cancel = context.WithCancel(parent)
+	return s
+}
+
+// run starts fn on its own goroutine. An error or panic in fn stops the
+// simulation.
+func (s *supervisor) run(name string, fn func() error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("panic", "worker", name, "panic", r, "stack", string(debug.Stack()))
+				s.fail(fmt.Errorf("%s: panic: %v", name, r))
+			}
+		}()
+		if err := fn(); err != nil {
+			s.fail(fmt.Errorf("%s: %w", name, err))
+		}
+	}()
+}
+
+// fail records err, unless an earlier error was, and stops the simulation
+func (s *supervisor) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+		slog.Error("stopping simulation", "err", err)
+	}
+	s.mu.Unlock()
+	s.cancel()
+}
+
+// wait waits for every goroutine to return and returns the first error
+func (s *supervisor) wait() error {
+	s.wg.Wait()
+	s.cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
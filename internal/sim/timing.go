@@ -0,0 +1,64 @@
+package sim
+
+import (
+	"log/slog"
+	"time"
+)
+
+const (
+	timingWindow      = 10 * time.Second // evaluation window for rate warnings
+	timingMissRatio   = 0.05             // fraction of missed ticks that fails a window
+	timingSlowWindows = 3                // consecutive failing windows before warning
+)
+
+// tickTracker measures how closely a payload follows its tick schedule from
+// the ticks the scheduler had to skip because the payload was still building.
+type tickTracker struct {
+	logger *slog.Logger
+	period time.Duration
+
+	windowStart  time.Time
+	windowTicks  int
+	windowMissed int
+	slowWindows  int
+}
+
+func newTickTracker(period time.Duration, logger *slog.Logger) *tickTracker {
+	return &tickTracker{
+		logger: logger,
+		period: period,
+	}
+}
+
+// tick records a tick built at now, merged being the ticks skipped before it
+func (t *tickTracker) tick(now time.Time, merged int) {
+	if t.windowStart.IsZero() {
+		t.windowStart = now
+	}
+	t.windowTicks++
+	t.windowMissed += merged
+	t.evaluate(now)
+}
+
+// evaluate closes the current window and warns when the payload has
+// consistently failed to keep up with its configured frequency
+func (t *tickTracker) evaluate(now time.Time) {
+	if now.Sub(t.windowStart) < timingWindow {
+		return
+	}
+	total := t.windowTicks + t.windowMissed
+	ratio := float64(t.windowMissed) / float64(total)
+	if ratio > timingMissRatio {
+		t.slowWindows++
+	} else {
+		t.slowWindows = 0
+	}
+	if t.slowWindows >= timingSlowWindows {
+		t.logger.Warn("payload cannot meet its configured frequency",
+			"hz", 1/t.period.Seconds(), "missed_pct", ratio*100, "window", now.Sub(t.windowStart).Round(time.Millisecond))
+		t.slowWindows = 0
+	}
+	t.windowStart = now
+	t.windowTicks = 0
+	t.windowMissed = 0
+}
@@ -25,46 +25,29 @@ func convertDtype(strType string) (types.Type, error) {
 	return tObj.Type(), nil
 }
 
-func writeBits(buf []byte, offset int, val any, size int) error {
-	var uval uint64
-	switch v := val.(type) {
-	case int8:
-		uval = uint64(v)
-	case int16:
-		uval = uint64(v)
-	case int32:
-		uval = uint64(v)
-	case int64:
-		uval = uint64(v)
-	case int:
-		uval = uint64(v)
-	case uint8:
-		uval = uint64(v)
-	case uint16:
-		uval = uint64(v)
-	case uint32:
-		uval = uint64(v)
-	case uint64:
-		uval = v
-	case float32:
-		uval = uint64(math.Float32bits(v))
-	case float64:
-		uval = math.Float64bits(v)
-	case bool:
-		uval = 0
-		if v {
-			uval = 1
-		}
-	default:
-		return fmt.Errorf("unable to write value to bits - type not allowed")
+// writeBits writes the low size bits of uval big-endian at the bit offset.
+// Floats are written as their IEEE 754 bits, booleans as 0 or 1.
+func writeBits(buf []byte, offset int, uval uint64, size int) error {
+	// whole bytes at a byte boundary, the usual layout
+	if offset%8 == 0 && size%8 == 0 {
+		start, n := offset/8, size/8
+		if start+n > len(buf) {
+			return fmt.Errorf("buffer overflow - byte %d not in %d size buf", start+n-1, len(buf))
+		}
+		for i := n - 1; i >= 0; i-- {
+			buf[start+i] = byte(uval)
+			uval >>= 8
+		}
+		return nil
 	}
+
 	for i := 0; i < size; i++ {
 		bit := (uval >> (size - i - 1)) & 1
 		byteIndex := (offset + i) / 8
 		bitIndex := 7 - ((offset + i) % 8)
 
-		if bitIndex >= len(buf) {
-			return fmt.Errorf("buffer overflow - %d bit not in %d size buf", bitIndex, len(buf))
+		if byteIndex >= len(buf) {
+			return fmt.Errorf("buffer overflow - byte %d not in %d size buf", byteIndex, len(buf))
 		}
 		if bit == 1 {
 			buf[byteIndex] |= (1 << bitIndex)
@@ -75,9 +58,25 @@ func writeBits(buf []byte, offset int, val any, size int) error {
 	return nil
 }
 
-func writeBitsStr(buf []byte, offset int, value string, size int) error {
-	for i, r := range value {
-		err := writeBits(buf, offset+i*size, r, size)
+// writeBitsStr writes up to length bytes of value as 8 bit characters,
+// padding with zeros when value is shorter than length.
+func writeBitsStr(buf []byte, offset int, value []byte, length int) error {
+	if offset%8 == 0 {
+		start := offset / 8
+		if start+length > len(buf) {
+			return fmt.Errorf("buffer overflow - byte %d not in %d size buf", start+length-1, len(buf))
+		}
+		n := copy(buf[start:start+length], value)
+		clear(buf[start+n : start+length])
+		return nil
+	}
+
+	for i := 0; i < length; i++ {
+		var c uint8
+		if i < len(value) {
+			c = value[i]
+		}
+		err := writeBits(buf, offset+i*8, uint64(c), 8)
 		if err != nil {
 			return err
 		}
@@ -85,6 +84,46 @@ func writeBitsStr(buf []byte, offset int, value string, size int) error {
 	return nil
 }
 
+// readBits reads size bits (at most 64) starting at the bit offset into an unsigned value.
+func readBits(buf []byte, offset int, size int) (uint64, error) {
+	if size > 64 {
+		return 0, fmt.Errorf("unable to read %d bits into 64 bit value", size)
+	}
+	var uval uint64
+	for i := 0; i < size; i++ {
+		byteIndex := (offset + i) / 8
+		bitIndex := 7 - ((offset + i) % 8)
+
+		if byteIndex >= len(buf) {
+			return 0, fmt.Errorf("buffer underflow - byte %d not in %d size buf", byteIndex, len(buf))
+		}
+		uval = uval<<1 | uint64(buf[byteIndex]>>bitIndex)&1
+	}
+	return uval, nil
+}
+
+// readBitsStr reads length 8 bit characters, dropping trailing zero padding.
+func readBitsStr(buf []byte, offset int, length int) (string, error) {
+	b := make([]byte, 0, length)
+	for i := 0; i < length; i++ {
+		c, err := readBits(buf, offset+i*8, 8)
+		if err != nil {
+			return "", err
+		}
+		b = append(b, byte(c))
+	}
+	return strings.TrimRight(string(b), "\x00"), nil
+}
+
+// signExtend interprets the low size bits of v as a two's complement number.
+func signExtend(v uint64, size int) int64 {
+	if size <= 0 || size >= 64 {
+		return int64(v)
+	}
+	shift := 64 - size
+	return int64(v<<shift) >> shift
+}
+
 func writeBitsArr(buf []byte, offset int, value []uint64, size int) error {
 	for i, v := range value {
 		err := writeBits(buf, offset+i*size, v, size)
@@ -119,20 +158,32 @@ func htond(d *uint64) []byte {
 	return netBytes
 }
 
+// calcPayloadSize returns the number of bytes needed to hold every data point
+// at its bit offset.
 func calcPayloadSize(dpMap map[string]dataPoint) uint16 {
-	var size uint16
+	var bits int
 	for _, dp := range dpMap {
-		size += dp.getSize()
-	} 
-	return size
+		if end := int(dp.getOffset()) + dp.getBits(); end > bits {
+			bits = end
+		}
+	}
+	return uint16((bits + 7) / 8)
 }
 
 func calcHeaderSize(h definitions.Header) (uint16, error) {
 	var size uint16
 	for i, e := range h.Elements {
 
-		// get the bytes of element
-		_, s, err := (e.Bytes())
+		// get the size of element, without producing bytes when it is known
+		var s uint16
+		var err error
+		if sz, ok := e.(definitions.Sizer); ok {
+			s = sz.Size()
+		} else {
+			var b []byte
+			b, err = e.AppendBytes(nil)
+			s = uint16(min(len(b), math.MaxUint16))
+		}
 		if err != nil {
 			return math.MaxUint16, fmt.Errorf("calc header size failed to get bytes for header element at index %d: %w", i, err)
 		}
@@ -150,4 +201,27 @@ func calcHeaderSize(h definitions.Header) (uint16, error) {
 		size += uSize
 	}
 	return size, nil
-}
\ No newline at end of file
+}
+
+// writeElements writes the bytes of each element into buf in order and
+// returns the number of bytes written. Elements append straight into buf, so
+// nothing is allocated while buf has room for them.
+func writeElements(buf []byte, elements []definitions.ByteSource) (int, error) {
+	idx := 0
+	for i, element := range elements {
+		if element == nil {
+			return idx, fmt.Errorf("element at index %d is nil", i)
+		}
+		// capped at the end of buf so that an element too large for it
+		// cannot write past it
+		out, err := element.AppendBytes(buf[idx:idx:len(buf)])
+		if err != nil {
+			return idx, fmt.Errorf("failed to get bytes for element at index %d: %w", i, err)
+		}
+		if len(buf)-idx < len(out) {
+			return idx, fmt.Errorf("not enough room in buffer for element at index %d", i)
+		}
+		idx += len(out)
+	}
+	return idx, nil
+}
@@ -0,0 +1,200 @@
+package sim
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestWriteBits(t *testing.T) {
+	tests := []struct {
+		name    string
+		buf     []byte
+		offset  int
+		val     uint64
+		size    int
+		want    []byte
+		wantErr bool
+	}{
+		{name: "byte", buf: make([]byte, 2), offset: 8, val: 0xab, size: 8, want: []byte{0, 0xab}},
+		{name: "big endian", buf: make([]byte, 4), val: 0x01020304, size: 32, want: []byte{1, 2, 3, 4}},
+		{name: "64 bits", buf: make([]byte, 8), val: math.MaxUint64 - 1, size: 64, want: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}},
+		{name: "truncated to size", buf: make([]byte, 2), val: 0x1234, size: 8, want: []byte{0x34, 0}},
+		{name: "high nibble", buf: []byte{0x0f}, val: 0xa, size: 4, want: []byte{0xaf}},
+		{name: "low nibble", buf: []byte{0xf0}, offset: 4, val: 0x5, size: 4, want: []byte{0xf5}},
+		{name: "clears bits", buf: []byte{0xff}, offset: 2, val: 0, size: 3, want: []byte{0xc7}},
+		{name: "single bit", buf: []byte{0x00, 0x00}, offset: 9, val: 1, size: 1, want: []byte{0x00, 0x40}},
+		{name: "across bytes", buf: []byte{0xff, 0xff}, offset: 4, val: 0x00, size: 8, want: []byte{0xf0, 0x0f}},
+		{name: "unaligned 12 bits", buf: make([]byte, 3), offset: 6, val: 0xabc, size: 12, want: []byte{0x02, 0xaf, 0x00}},
+		{name: "past the end", buf: make([]byte, 2), offset: 8, val: 1, size: 16, wantErr: true},
+		{name: "unaligned past the end", buf: make([]byte, 2), offset: 12, val: 1, size: 5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := writeBits(tt.buf, tt.offset, tt.val, tt.size)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("writeBits succeeded with % x, want an error", tt.buf)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(tt.buf, tt.want) {
+				t.Errorf("writeBits = % x, want % x", tt.buf, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadBits(t *testing.T) {
+	buf := []byte{0xa5, 0x0f, 0x80, 0x01}
+	tests := []struct {
+		name    string
+		offset  int
+		size    int
+		want    uint64
+		wantErr bool
+	}{
+		{name: "byte", offset: 8, size: 8, want: 0x0f},
+		{name: "word", size: 32, want: 0xa50f8001},
+		{name: "nothing", offset: 32, size: 0, want: 0},
+		{name: "bit set", offset: 16, size: 1, want: 1},
+		{name: "bit clear", offset: 17, size: 1, want: 0},
+		{name: "nibble", offset: 4, size: 4, want: 0x5},
+		{name: "across bytes", offset: 4, size: 8, want: 0x50},
+		{name: "unaligned 13 bits", offset: 13, size: 13, want: 0x1e00},
+		{name: "past the end", offset: 24, size: 9, wantErr: true},
+		{name: "too wide", size: 65, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readBits(buf, tt.offset, tt.size)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readBits = %#x, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("readBits = %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+// ones returns a value with the low n bits set
+func ones(n int) uint64 {
+	if n >= 64 {
+		return math.MaxUint64
+	}
+	return 1<<n - 1
+}
+
+func TestBitsRoundTrip(t *testing.T) {
+	const val = 0xfedcba9876543210
+	for _, fill := range []byte{0x00, 0xff} {
+		for offset := range 16 {
+			for size := 1; size <= 64; size++ {
+				buf := bytes.Repeat([]byte{fill}, 10)
+				if err := writeBits(buf, offset, val, size); err != nil {
+					t.Fatalf("offset %d size %d: %v", offset, size, err)
+				}
+				want := val & ones(size)
+				got, err := readBits(buf, offset, size)
+				if err != nil {
+					t.Fatalf("offset %d size %d: %v", offset, size, err)
+				}
+				if got != want {
+					t.Errorf("offset %d size %d: read %#x, want %#x", offset, size, got, want)
+				}
+				// the bits around the value are left alone
+				rest := min(len(buf)*8-offset-size, 64)
+				before, _ := readBits(buf, 0, offset)
+				after, _ := readBits(buf, offset+size, rest)
+				if fill == 0xff && (before != ones(offset) || after != ones(rest)) ||
+					fill == 0x00 && (before != 0 || after != 0) {
+					t.Errorf("offset %d size %d on %#x: neighbouring bits changed to % x", offset, size, fill, buf)
+				}
+			}
+		}
+	}
+}
+
+func TestSignExtend(t *testing.T) {
+	tests := []struct {
+		v    uint64
+		size int
+		want int64
+	}{
+		{0x7, 4, 7},
+		{0x8, 4, -8},
+		{0xf, 4, -1},
+		{0xff, 8, -1},
+		{0x7f, 8, 127},
+		{0x80, 8, -128},
+		{0x1ff, 8, -1}, // bits above size are ignored
+		{0x1, 1, -1},
+		{0x0, 1, 0},
+		{0x8000, 16, math.MinInt16},
+		{0x7fffffff, 32, math.MaxInt32},
+		{0x80000000, 32, math.MinInt32},
+		{math.MaxUint64, 64, -1},
+		{0x80, 0, 0x80}, // no size, as is
+	}
+	for _, tt := range tests {
+		if got := signExtend(tt.v, tt.size); got != tt.want {
+			t.Errorf("signExtend(%#x, %d) = %d, want %d", tt.v, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestBitsStr(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int
+		value  string
+		length int
+		want   string
+		buf    []byte // after writing, nil to skip the check
+	}{
+		{name: "aligned", offset: 8, value: "ab", length: 2, want: "ab", buf: []byte{0, 'a', 'b', 0}},
+		{name: "padded", offset: 8, value: "a", length: 3, want: "a", buf: []byte{0, 'a', 0, 0}},
+		{name: "cut", value: "abcdef", length: 4, want: "abcd", buf: []byte{'a', 'b', 'c', 'd'}},
+		{name: "unaligned", offset: 4, value: "hi", length: 2, want: "hi", buf: []byte{0x06, 0x86, 0x90, 0x00}},
+		{name: "unaligned padded", offset: 3, value: "h", length: 3, want: "h"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := make([]byte, 4)
+			if err := writeBitsStr(buf, tt.offset, []byte(tt.value), tt.length); err != nil {
+				t.Fatal(err)
+			}
+			if tt.buf != nil && !bytes.Equal(buf, tt.buf) {
+				t.Errorf("writeBitsStr = % x, want % x", buf, tt.buf)
+			}
+			got, err := readBitsStr(buf, tt.offset, tt.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("readBitsStr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	buf := make([]byte, 4)
+	if err := writeBitsStr(buf, 16, []byte("abc"), 3); err == nil {
+		t.Error("writeBitsStr past the end succeeded, want an error")
+	}
+	if err := writeBitsStr(buf, 12, []byte("abc"), 3); err == nil {
+		t.Error("unaligned writeBitsStr past the end succeeded, want an error")
+	}
+	if _, err := readBitsStr(buf, 20, 2); err == nil {
+		t.Error("readBitsStr past the end succeeded, want an error")
+	}
+}
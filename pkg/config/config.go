@@ -2,14 +2,18 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"net"
 	"time"
 )
 
 const FREQ_DEFAULT float64 = 1000.0 // default 1000 ms or 1 Hz
 const PHASE_DEFAULT float64 = 0.0   // default phase shift
+const SERIAL_BAUD_DEFAULT int = 115200
 
-type Config struct {
+// Bus is the configuration of a single simulated bus: its transport,
+// addresses and ports.
+type Bus struct {
 	BusName		string
 	Interface   net.Interface
 	BusType		string
@@ -17,6 +21,26 @@ type Config struct {
 	DestHost	net.IP
 	SrcPort		int
 	DestPort	int
+	DestMAC		net.HardwareAddr // next hop MAC, required by raw (af_xdp) buses
+	HopLimit	int              // IPv4 TTL or IPv6 hop limit, 0 = system default
+	FlowLabel	uint32           // IPv6 flow label, ignored for IPv4, af_xdp buses only
+	MulticastTTL	int          // TTL/hop limit for multicast DestHost, 0 = 1
+	MulticastLoop	bool         // loop multicast back to local listeners
+	Broadcast	bool             // DestHost is a subnet broadcast address
+
+	ListenHost	net.IP // receive address, multicast groups are joined on Interface
+	ListenPort	int    // receive port, 0 = receive path disabled
+	CommandPort	int    // command port on ListenHost, 0 = commands disabled
+
+	SerialDevice	string        // serial device path, or "pty" for a pty pair
+	SerialBaud	int           // line rate, 0 = SERIAL_BAUD_DEFAULT
+	SerialFraming	string        // none, slip, cobs or hdlc
+	SerialGap	time.Duration // minimum idle time between frames
+}
+
+type Config struct {
+	Bus		// bus simulated when Buses is empty, and the defaults of each entry in Buses
+	Buses	[]Bus // buses simulated together in one process
 
 	DbHost		string
 	DbPort		string
@@ -24,12 +48,54 @@ type Config struct {
 	DbNum		int
 	DbReadTimeout time.Duration
 	DbWriteTimeout time.Duration
+	StoreSync	time.Duration // how often data values are exchanged with the store, which lags by up to this much
 
-	LogFile		string
+	LogFile		string // log file path, or stderr, stdout or syslog
 	LogLevel	string
+	LogFormat	string // text or json
 
 	MonitorInterval time.Duration
 	MetricsPort int
+	GRPCPort	int // gRPC API port, 0 = gRPC disabled
+
+	Workers	int // payload build workers, 0 = one per CPU
+	SchedSpin	time.Duration // spin this close to a tick instead of sleeping, 0 = never spin
+
+	Scenario	string // scenario file run once the payloads are up
+	ScenarioExit	bool // stop once the scenario has run, failing when a step failed
+}
+
+// IPVersion returns the IP version (4 or 6) selected by the configured
+// source and destination addresses. An unset or unspecified source adopts the
+// family of the destination.
+func (c *Bus) IPVersion() (int, error) {
+	if c.DestHost == nil {
+		return 0, fmt.Errorf("no destination host configured")
+	}
+	dst6 := c.DestHost.To4() == nil
+	if c.SrcHost != nil && !c.SrcHost.IsUnspecified() && (c.SrcHost.To4() == nil) != dst6 {
+		return 0, fmt.Errorf("source %s and destination %s are different IP versions", c.SrcHost, c.DestHost)
+	}
+	if dst6 {
+		return 6, nil
+	}
+	return 4, nil
+}
+
+// BusConfigs returns one config per simulated bus, each with the process-wide
+// settings of c and the Bus replaced by an entry of Buses.
+func (c *Config) BusConfigs() []*Config {
+	if len(c.Buses) == 0 {
+		return []*Config{c}
+	}
+	cfgs := make([]*Config, len(c.Buses))
+	for i, b := range c.Buses {
+		cfg := *c
+		cfg.Bus = b
+		cfg.Buses = nil
+		cfgs[i] = &cfg
+	}
+	return cfgs
 }
 
 type LogLevels int
@@ -56,6 +122,20 @@ func (l LogLevels) String() string {
 	}
 }
 
+// Level returns the slog level of l
+func (l LogLevels) Level() slog.Level {
+	switch l {
+	case Debug:
+		return slog.LevelDebug
+	case Warn:
+		return slog.LevelWarn
+	case Error:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
 func (l *LogLevels) Set(value string) error {
 	switch value {
 	case "debug":
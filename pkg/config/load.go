@@ -0,0 +1,547 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variable of every setting, e.g.
+// DATAGENSIM_DB_HOST for db_host
+const EnvPrefix = "DATAGENSIM_"
+
+// Default returns the configuration used when nothing else is set.
+func Default() *Config {
+	return &Config{
+		Bus: Bus{
+			BusName:  "MainBus",
+			BusType:  "udp",
+			DestHost: net.IPv4(127, 0, 0, 1),
+		},
+		DbHost:          "localhost",
+		DbPort:          "6379",
+		DbReadTimeout:   3 * time.Second,
+		DbWriteTimeout:  3 * time.Second,
+		StoreSync:       100 * time.Millisecond,
+		LogFile:         "/var/tmp/log",
+		LogLevel:        "info",
+		LogFormat:       "text",
+		MonitorInterval: time.Second,
+		MetricsPort:     8080,
+	}
+}
+
+// setting is one configuration field as it appears in files, the environment
+// and on the command line
+type setting struct {
+	key   string   // file key, also the environment variable after EnvPrefix
+	flags []string // command line flags
+	usage string
+	bool  bool // flag takes no value
+	set   func(c *Config, v string) error
+	get   func(c *Config) string
+}
+
+func (s *setting) env() string {
+	return EnvPrefix + strings.ToUpper(s.key)
+}
+
+func strSetting(key string, field func(c *Config) *string, usage string, flags ...string) setting {
+	return setting{key: key, flags: flags, usage: usage,
+		set: func(c *Config, v string) error { *field(c) = v; return nil },
+		get: func(c *Config) string { return *field(c) },
+	}
+}
+
+func intSetting(key string, field func(c *Config) *int, usage string, flags ...string) setting {
+	return setting{key: key, flags: flags, usage: usage,
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("not an integer: %q", v)
+			}
+			*field(c) = n
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Itoa(*field(c)) },
+	}
+}
+
+func boolSetting(key string, field func(c *Config) *bool, usage string, flags ...string) setting {
+	return setting{key: key, flags: flags, usage: usage, bool: true,
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("not a boolean: %q", v)
+			}
+			*field(c) = b
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatBool(*field(c)) },
+	}
+}
+
+func durSetting(key string, field func(c *Config) *time.Duration, usage string, flags ...string) setting {
+	return setting{key: key, flags: flags, usage: usage,
+		set: func(c *Config, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("not a duration: %q", v)
+			}
+			*field(c) = d
+			return nil
+		},
+		get: func(c *Config) string { return field(c).String() },
+	}
+}
+
+func ipSetting(key string, field func(c *Config) *net.IP, usage string, flags ...string) setting {
+	return setting{key: key, flags: flags, usage: usage,
+		set: func(c *Config, v string) error {
+			if v == "" {
+				*field(c) = nil
+				return nil
+			}
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return fmt.Errorf("not an IP address: %q", v)
+			}
+			*field(c) = ip
+			return nil
+		},
+		get: func(c *Config) string {
+			if *field(c) == nil {
+				return ""
+			}
+			return field(c).String()
+		},
+	}
+}
+
+// busSettings are the fields of a Bus, which may also be set per entry of
+// the buses list of a configuration file
+var busSettings = []setting{
+	strSetting("bus_name", func(c *Config) *string { return &c.BusName }, "Bus name", "b", "bus-name"),
+	{key: "interface", flags: []string{"interface"}, usage: "Network interface for multicast, broadcast and af_xdp",
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.Interface = net.Interface{}
+				return nil
+			}
+			iface, err := net.InterfaceByName(v)
+			if err != nil {
+				return err
+			}
+			c.Interface = *iface
+			return nil
+		},
+		get: func(c *Config) string { return c.Interface.Name },
+	},
+	strSetting("bus_type", func(c *Config) *string { return &c.BusType }, "Bus type (udp, af_xdp, serial)", "bus-type"),
+	ipSetting("src_host", func(c *Config) *net.IP { return &c.SrcHost }, "Source IP address, chosen by the system when unset (required by af_xdp buses)", "sh", "src-host"),
+	ipSetting("dest_host", func(c *Config) *net.IP { return &c.DestHost }, "Destination IP address", "dh", "dest-host"),
+	intSetting("src_port", func(c *Config) *int { return &c.SrcPort }, "Source port", "sp", "src-port"),
+	intSetting("dest_port", func(c *Config) *int { return &c.DestPort }, "Destination port", "dp", "dest-port"),
+	{key: "dest_mac", flags: []string{"dest-mac"}, usage: "Next hop MAC address, required by af_xdp buses",
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.DestMAC = nil
+				return nil
+			}
+			mac, err := net.ParseMAC(v)
+			if err != nil {
+				return err
+			}
+			c.DestMAC = mac
+			return nil
+		},
+		get: func(c *Config) string { return c.DestMAC.String() },
+	},
+	intSetting("hop_limit", func(c *Config) *int { return &c.HopLimit }, "IPv4 TTL or IPv6 hop limit, 0 for the system default", "hop-limit"),
+	{key: "flow_label", flags: []string{"flow-label"}, usage: "IPv6 flow label, af_xdp buses only",
+		set: func(c *Config, v string) error {
+			n, err := strconv.ParseUint(v, 0, 32)
+			if err != nil {
+				return fmt.Errorf("not an unsigned integer: %q", v)
+			}
+			c.FlowLabel = uint32(n)
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatUint(uint64(c.FlowLabel), 10) },
+	},
+	intSetting("multicast_ttl", func(c *Config) *int { return &c.MulticastTTL }, "TTL or hop limit for multicast destinations, 0 for 1", "multicast-ttl"),
+	boolSetting("multicast_loop", func(c *Config) *bool { return &c.MulticastLoop }, "Loop multicast back to local listeners", "multicast-loop"),
+	boolSetting("broadcast", func(c *Config) *bool { return &c.Broadcast }, "Destination is a subnet broadcast address", "broadcast"),
+	ipSetting("listen_host", func(c *Config) *net.IP { return &c.ListenHost }, "Receive address", "listen-host"),
+	intSetting("listen_port", func(c *Config) *int { return &c.ListenPort }, "Receive port, 0 disables the receive path", "listen-port"),
+	intSetting("command_port", func(c *Config) *int { return &c.CommandPort }, "Command port, 0 disables commands", "command-port"),
+	strSetting("serial_device", func(c *Config) *string { return &c.SerialDevice }, "Serial device path, or pty for a pty pair", "serial-device"),
+	intSetting("serial_baud", func(c *Config) *int { return &c.SerialBaud }, "Serial line rate, 0 for 115200", "serial-baud"),
+	strSetting("serial_framing", func(c *Config) *string { return &c.SerialFraming }, "Serial framing (none, slip, cobs, hdlc)", "serial-framing"),
+	durSetting("serial_gap", func(c *Config) *time.Duration { return &c.SerialGap }, "Minimum idle time between serial frames", "serial-gap"),
+}
+
+// settings lists every Config field but Buses
+var settings = append(slices.Clip(busSettings), []setting{
+	strSetting("db_host", func(c *Config) *string { return &c.DbHost }, "Redis host", "db-host"),
+	strSetting("db_port", func(c *Config) *string { return &c.DbPort }, "Redis port", "db-port"),
+	strSetting("db_password", func(c *Config) *string { return &c.DbPassword }, "Redis password", "db-password"),
+	intSetting("db_num", func(c *Config) *int { return &c.DbNum }, "Redis database number", "db-num"),
+	durSetting("db_read_timeout", func(c *Config) *time.Duration { return &c.DbReadTimeout }, "Redis read timeout", "db-read-timeout"),
+	durSetting("db_write_timeout", func(c *Config) *time.Duration { return &c.DbWriteTimeout }, "Redis write timeout", "db-write-timeout"),
+	durSetting("store_sync", func(c *Config) *time.Duration { return &c.StoreSync }, "How often data values are exchanged with Redis, values read from Redis lag by up to this much", "store-sync"),
+	strSetting("log_file", func(c *Config) *string { return &c.LogFile }, "Log file path, or stderr, stdout or syslog", "l", "log-file"),
+	strSetting("log_level", func(c *Config) *string { return &c.LogLevel }, "Log level (debug, info, warn, error)", "ll", "log-level"),
+	strSetting("log_format", func(c *Config) *string { return &c.LogFormat }, "Log format (text, json)", "log-format"),
+	durSetting("monitor_interval", func(c *Config) *time.Duration { return &c.MonitorInterval }, "Payload monitor interval", "monitor-interval"),
+	intSetting("metrics_port", func(c *Config) *int { return &c.MetricsPort }, "Metrics, control API and live view port", "metrics-port"),
+	intSetting("grpc_port", func(c *Config) *int { return &c.GRPCPort }, "gRPC API port, 0 disables gRPC", "grpc-port"),
+	intSetting("workers", func(c *Config) *int { return &c.Workers }, "Payload build workers, 0 for one per CPU", "workers"),
+	durSetting("sched_spin", func(c *Config) *time.Duration { return &c.SchedSpin }, "Spin this close to a tick instead of sleeping, for rates above 1 kHz, 0 never spins", "sched-spin"),
+	strSetting("scenario", func(c *Config) *string { return &c.Scenario }, "Scenario file to run", "scenario"),
+	boolSetting("scenario_exit", func(c *Config) *bool { return &c.ScenarioExit }, "Stop once the scenario has run, failing when a step failed", "scenario-exit"),
+}...)
+
+// Loader builds a Config from, in increasing precedence, the defaults, a
+// YAML or TOML file, DATAGENSIM_* environment variables and command line
+// flags. Several buses are configured with a buses list in the file; each
+// entry starts from the bus settings of the other layers and overrides them.
+type Loader struct {
+	file   string
+	print  bool
+	flags  []flagValue // in command line order
+	lookup func(key string) (string, bool)
+}
+
+type flagValue struct {
+	s     *setting
+	name  string
+	value string
+}
+
+// NewLoader registers a flag for every setting on fs, as well as -config
+// for the configuration file and -print-config.
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{lookup: os.LookupEnv}
+	fs.StringVar(&l.file, "config", "", "Configuration file (.yaml, .yml or .toml), also "+EnvPrefix+"CONFIG")
+	fs.BoolVar(&l.print, "print-config", false, "Print the effective configuration and exit")
+
+	for i := range settings {
+		s := &settings[i]
+		for _, name := range s.flags {
+			// checked on a scratch config so that bad values fail the parse
+			record := func(v string) error {
+				if err := s.set(Default(), v); err != nil {
+					return err
+				}
+				l.flags = append(l.flags, flagValue{s: s, name: name, value: v})
+				return nil
+			}
+			usage := fmt.Sprintf("%s (%s)", s.usage, s.env())
+			if s.bool {
+				fs.BoolFunc(name, usage, func(v string) error { return record(v) })
+			} else {
+				fs.Func(name, usage, record)
+			}
+		}
+	}
+	return l
+}
+
+// PrintConfig reports whether -print-config was given.
+func (l *Loader) PrintConfig() bool {
+	return l.print
+}
+
+// Load returns the configuration after the flag set has been parsed. All
+// invalid settings are reported together.
+func (l *Loader) Load() (*Config, error) {
+	c := Default()
+
+	file := l.file
+	if file == "" {
+		file, _ = l.lookup(EnvPrefix + "CONFIG")
+	}
+	var buses []map[string]any
+	if file != "" {
+		var err error
+		if buses, err = c.loadFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for i := range settings {
+		s := &settings[i]
+		if v, ok := l.lookup(s.env()); ok {
+			if err := s.set(c, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env(), err))
+			}
+		}
+	}
+	for _, f := range l.flags {
+		if err := f.s.set(c, f.value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", f.name, err))
+		}
+	}
+	for i, entry := range buses {
+		b := *c
+		errs = append(errs, applyValues(&b, busSettings, entry, fmt.Sprintf("config file %s: buses[%d]", file, i))...)
+		c.Buses = append(c.Buses, b.Bus)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadFile applies the settings of a YAML or TOML file, chosen by extension.
+// Keys are the setting names, e.g. db_host. The entries of the buses list
+// are returned to be applied once the other layers are.
+func (c *Config) loadFile(path string) ([]map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	values := make(map[string]any)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("config file %s: unknown format %q, expected .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	var buses []map[string]any
+	var errs []error
+	if v, ok := values["buses"]; ok {
+		delete(values, "buses")
+		if buses, err = busEntries(v); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: buses: %w", path, err))
+		}
+	}
+	errs = append(errs, applyValues(c, settings, values, "config file "+path)...)
+	return buses, errors.Join(errs...)
+}
+
+// applyValues sets the decoded file values of the given settings on c.
+// where prefixes the errors.
+func applyValues(c *Config, settings []setting, values map[string]any, where string) []error {
+	var errs []error
+	for _, s := range settings {
+		v, ok := values[s.key]
+		if !ok {
+			continue
+		}
+		str, err := fileValue(v)
+		if err == nil {
+			err = s.set(c, str)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", where, s.key, err))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if !slices.ContainsFunc(settings, func(s setting) bool { return s.key == key }) {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q", where, key))
+		}
+	}
+	return errs
+}
+
+// busEntries converts the decoded buses list, YAML and TOML decode it differently
+func busEntries(v any) ([]map[string]any, error) {
+	switch v := v.(type) {
+	case []map[string]any:
+		return v, nil
+	case []any:
+		entries := make([]map[string]any, len(v))
+		for i, e := range v {
+			m, ok := e.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("entry %d is not a table of settings", i)
+			}
+			entries[i] = m
+		}
+		return entries, nil
+	}
+	return nil, fmt.Errorf("expected a list of buses")
+}
+
+// fileValue converts a decoded file value to its command line form
+func fileValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int, int64, uint64, float64, bool:
+		return fmt.Sprint(v), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+// Validate checks the configuration as a whole and reports every problem.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if len(c.Buses) == 0 {
+		errs = append(errs, c.Bus.validate()...)
+	}
+	names := make(map[string]bool, len(c.Buses))
+	ports := make(map[string]string, 2*len(c.Buses)) // receive address -> bus
+	for i := range c.Buses {
+		b := &c.Buses[i]
+		for _, err := range b.validate() {
+			errs = append(errs, fmt.Errorf("buses[%d] (%s): %w", i, b.BusName, err))
+		}
+		check(!names[b.BusName], "buses[%d]: duplicate bus name %q", i, b.BusName)
+		names[b.BusName] = true
+		for _, port := range []int{b.ListenPort, b.CommandPort} {
+			if port == 0 {
+				continue
+			}
+			host := ""
+			if b.ListenHost != nil {
+				host = b.ListenHost.String()
+			}
+			addr := net.JoinHostPort(host, strconv.Itoa(port))
+			if other, ok := ports[addr]; ok {
+				errs = append(errs, fmt.Errorf("buses[%d] (%s): %s already used by bus %s", i, b.BusName, addr, other))
+			}
+			ports[addr] = b.BusName
+		}
+	}
+	check(c.MetricsPort >= 0 && c.MetricsPort <= 65535, "metrics_port: %d is not a port", c.MetricsPort)
+	check(c.GRPCPort >= 0 && c.GRPCPort <= 65535, "grpc_port: %d is not a port", c.GRPCPort)
+	check(c.Workers >= 0, "workers: must not be negative")
+	check(c.SchedSpin >= 0 && c.SchedSpin <= time.Second, "sched_spin: must be between 0 and 1s")
+
+	check(c.DbHost != "", "db_host: must not be empty")
+	if port, err := strconv.Atoi(c.DbPort); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("db_port: %q is not a port", c.DbPort))
+	}
+	check(c.DbNum >= 0, "db_num: must not be negative")
+	check(c.DbReadTimeout > 0, "db_read_timeout: must be positive")
+	check(c.DbWriteTimeout > 0, "db_write_timeout: must be positive")
+
+	var level LogLevels
+	if err := level.Set(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+	check(c.LogFile != "", "log_file: must not be empty")
+	check(c.LogFormat == "text" || c.LogFormat == "json", "log_format: %q is not text or json", c.LogFormat)
+	check(c.MonitorInterval > 0, "monitor_interval: must be positive")
+	check(c.StoreSync >= 10*time.Millisecond && c.StoreSync <= time.Minute, "store_sync: must be between 10ms and 1m")
+	if c.Scenario != "" {
+		if _, err := os.Stat(c.Scenario); err != nil {
+			errs = append(errs, fmt.Errorf("scenario: %w", err))
+		}
+	}
+	check(!c.ScenarioExit || c.Scenario != "", "scenario_exit: requires a scenario")
+	return errors.Join(errs...)
+}
+
+// validate checks the settings of a single bus
+func (b *Bus) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(b.BusName != "", "bus_name: must not be empty")
+	switch b.BusType {
+	case "", "udp", "af_xdp", "xdp":
+		if _, err := b.IPVersion(); err != nil {
+			errs = append(errs, err)
+		}
+	case "serial":
+		check(b.SerialDevice != "", "serial_device: required by serial buses")
+	default:
+		errs = append(errs, fmt.Errorf("bus_type: unknown bus type %q", b.BusType))
+	}
+	for _, p := range []struct {
+		key  string
+		port int
+	}{
+		{"src_port", b.SrcPort}, {"dest_port", b.DestPort}, {"listen_port", b.ListenPort}, {"command_port", b.CommandPort},
+	} {
+		check(p.port >= 0 && p.port <= 65535, "%s: %d is not a port", p.key, p.port)
+	}
+	check(b.HopLimit >= 0 && b.HopLimit <= 255, "hop_limit: must be between 0 and 255")
+	check(b.MulticastTTL >= 0 && b.MulticastTTL <= 255, "multicast_ttl: must be between 0 and 255")
+	check(b.FlowLabel < 1<<20, "flow_label: must fit in 20 bits")
+	check(b.FlowLabel == 0 || b.BusType == "af_xdp" || b.BusType == "xdp", "flow_label: only applied by af_xdp buses")
+	check(b.SerialBaud >= 0, "serial_baud: must not be negative")
+	check(b.SerialGap >= 0, "serial_gap: must not be negative")
+	switch strings.ToLower(strings.TrimSpace(b.SerialFraming)) {
+	case "", "none", "slip", "cobs", "hdlc":
+	default:
+		errs = append(errs, fmt.Errorf("serial_framing: unknown framing %q", b.SerialFraming))
+	}
+	return errs
+}
+
+// Write prints the configuration as a YAML file that Loader accepts. The
+// database password is masked.
+func (c *Config) Write(w io.Writer) error {
+	doc := settingsNode(c, settings)
+	if len(c.Buses) > 0 {
+		buses := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, b := range c.Buses {
+			bc := *c
+			bc.Bus = b
+			buses.Content = append(buses.Content, settingsNode(&bc, busSettings))
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "buses"}, buses)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// settingsNode returns the given settings of c as a YAML mapping
+func settingsNode(c *Config, settings []setting) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range settings {
+		v := s.get(c)
+		if s.key == "db_password" && v != "" {
+			v = "********"
+		}
+		val := &yaml.Node{Kind: yaml.ScalarNode, Value: v}
+		if s.bool {
+			val.Tag = "!!bool"
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: s.key}, val)
+	}
+	return node
+}
@@ -0,0 +1,425 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes a configuration file into a temporary directory
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// load runs a Loader over args with env as the environment
+func load(t *testing.T, env map[string]string, args ...string) (*Loader, *Config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("sim", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	l := NewLoader(fs)
+	l.lookup = func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	if err := fs.Parse(args); err != nil {
+		return l, nil, err
+	}
+	c, err := l.Load()
+	return l, c, err
+}
+
+// get returns a setting of c in its command line form
+func get(t *testing.T, c *Config, key string) string {
+	t.Helper()
+	for _, s := range settings {
+		if s.key == key {
+			return s.get(c)
+		}
+	}
+	t.Fatalf("no setting %s", key)
+	return ""
+}
+
+func TestLoaderPrecedence(t *testing.T) {
+	yamlFile := writeFile(t, "sim.yaml", `
+db_host: file
+db_port: 6380
+metrics_port: 9000
+store_sync: 50ms
+multicast_loop: true
+dest_host: 192.0.2.1
+`)
+	tomlFile := writeFile(t, "sim.toml", `
+db_host = "file"
+metrics_port = 9000
+store_sync = "50ms"
+multicast_loop = true
+`)
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want map[string]string
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{"db_host": "localhost", "db_port": "6379", "metrics_port": "8080", "store_sync": "100ms", "multicast_loop": "false", "src_host": "", "dest_host": "127.0.0.1"},
+		},
+		{
+			name: "yaml file",
+			args: []string{"-config", yamlFile},
+			want: map[string]string{"db_host": "file", "db_port": "6380", "metrics_port": "9000", "store_sync": "50ms", "multicast_loop": "true", "dest_host": "192.0.2.1"},
+		},
+		{
+			name: "toml file",
+			args: []string{"-config", tomlFile},
+			want: map[string]string{"db_host": "file", "db_port": "6379", "metrics_port": "9000", "store_sync": "50ms", "multicast_loop": "true"},
+		},
+		{
+			name: "file from the environment",
+			env:  map[string]string{"DATAGENSIM_CONFIG": tomlFile},
+			want: map[string]string{"db_host": "file", "metrics_port": "9000"},
+		},
+		{
+			name: "flag file over the environment",
+			env:  map[string]string{"DATAGENSIM_CONFIG": tomlFile},
+			args: []string{"-config", yamlFile},
+			want: map[string]string{"db_port": "6380"},
+		},
+		{
+			name: "environment over the file",
+			env:  map[string]string{"DATAGENSIM_DB_HOST": "env", "DATAGENSIM_MULTICAST_LOOP": "false"},
+			args: []string{"-config", yamlFile},
+			want: map[string]string{"db_host": "env", "metrics_port": "9000", "multicast_loop": "false"},
+		},
+		{
+			name: "flags over the environment",
+			env:  map[string]string{"DATAGENSIM_DB_HOST": "env", "DATAGENSIM_METRICS_PORT": "9100"},
+			args: []string{"-config", yamlFile, "-db-host", "flag"},
+			want: map[string]string{"db_host": "flag", "metrics_port": "9100", "db_port": "6380"},
+		},
+		{
+			name: "last flag wins",
+			args: []string{"-src-port", "1", "-sp", "2", "-b", "Bus1", "-bus-name", "Bus2"},
+			want: map[string]string{"src_port": "2", "bus_name": "Bus2"},
+		},
+		{
+			name: "boolean flags",
+			env:  map[string]string{"DATAGENSIM_BROADCAST": "true"},
+			args: []string{"-multicast-loop", "-broadcast=false"},
+			want: map[string]string{"multicast_loop": "true", "broadcast": "false"},
+		},
+		{
+			name: "empty address clears",
+			args: []string{"-src-host", "10.0.0.1", "-src-host", ""},
+			want: map[string]string{"src_host": ""},
+		},
+		{
+			name: "ipv6 destination without a source",
+			args: []string{"-dh", "::1"},
+			want: map[string]string{"src_host": "", "dest_host": "::1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, c, err := load(t, tt.env, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range tt.want {
+				if got := get(t, c, key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+
+	// without a source the destination selects the IP version
+	_, c, err := load(t, nil, "-dh", "::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.IPVersion(); err != nil || v != 6 {
+		t.Errorf("IP version %d, %v, want 6", v, err)
+	}
+}
+
+func TestLoaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string // name and content, separated by a newline
+		env  map[string]string
+		args []string
+		want []string // in the error, all reported together
+	}{
+		{name: "missing file", args: []string{"-config", "/nonexistent/sim.yaml"}, want: []string{"config file"}},
+		{name: "unknown format", file: "sim.json\n{}", want: []string{`unknown format ".json"`}},
+		{name: "bad yaml", file: "sim.yaml\ndb_host: [", want: []string{"sim.yaml"}},
+		{name: "unknown key", file: "sim.yaml\ndb_hots: redis", want: []string{`unknown setting "db_hots"`}},
+		{name: "unsupported value", file: "sim.yaml\ndb_host: {a: 1}", want: []string{"db_host: unsupported value"}},
+		{
+			name: "every invalid file setting",
+			file: "sim.yaml\nmetrics_port: many\nserial_gap: 2\nbuses: 3",
+			want: []string{"metrics_port: not an integer", "serial_gap: not a duration", "buses: expected a list"},
+		},
+		{
+			name: "every invalid environment variable",
+			env:  map[string]string{"DATAGENSIM_STORE_SYNC": "often", "DATAGENSIM_SRC_HOST": "here"},
+			want: []string{"DATAGENSIM_STORE_SYNC: not a duration", "DATAGENSIM_SRC_HOST: not an IP address"},
+		},
+		{name: "invalid flag", args: []string{"-workers", "all"}, want: []string{"-workers", "not an integer"}},
+		{name: "unknown flag", args: []string{"-db-hots", "redis"}, want: []string{"db-hots"}},
+		{
+			name: "validation",
+			env:  map[string]string{"DATAGENSIM_LOG_LEVEL": "loud", "DATAGENSIM_STORE_SYNC": "1ms"},
+			args: []string{"-metrics-port", "70000", "-db-port", "redis"},
+			want: []string{"log_level", "store_sync: must be between 10ms and 1m", "metrics_port: 70000 is not a port", `db_port: "redis" is not a port`},
+		},
+		{name: "scenario exit without a scenario", args: []string{"-scenario-exit"}, want: []string{"scenario_exit: requires a scenario"}},
+		{name: "missing scenario", args: []string{"-scenario", "/nonexistent/scenario.yaml"}, want: []string{"scenario:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				name, content, _ := strings.Cut(tt.file, "\n")
+				args = append([]string{"-config", writeFile(t, name, content)}, args...)
+			}
+			_, c, err := load(t, tt.env, args...)
+			if err == nil {
+				t.Fatalf("Load = %+v, want an error", c)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoaderPrintConfig(t *testing.T) {
+	l, c, err := load(t, map[string]string{"DATAGENSIM_DB_PASSWORD": "secret"},
+		"-print-config", "-db-host", "redis", "-dest-host", "2001:db8::2", "-src-host", "::", "-serial-gap", "2ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.PrintConfig() {
+		t.Error("PrintConfig = false, want true")
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "secret") || !strings.Contains(out, "db_password: '********'") {
+		t.Errorf("password not masked:\n%s", out)
+	}
+
+	// the printed configuration loads back to the same one, but the password
+	_, back, err := load(t, nil, "-config", writeFile(t, "printed.yaml", out))
+	if err != nil {
+		t.Fatalf("loading the printed configuration: %v\n%s", err, out)
+	}
+	for _, s := range settings {
+		if s.key == "db_password" {
+			continue
+		}
+		if got, want := s.get(back), s.get(c); got != want {
+			t.Errorf("%s = %q after printing, want %q", s.key, got, want)
+		}
+	}
+}
+
+func TestLoaderBuses(t *testing.T) {
+	yamlFile := writeFile(t, "buses.yaml", `
+dest_port: 5000
+src_port: 4000
+buses:
+  - bus_name: Left
+    dest_host: 192.0.2.10
+  - bus_name: Right
+    src_host: 2001:db8::1
+    dest_host: 2001:db8::2
+    dest_port: 5001
+    listen_port: 6000
+`)
+	tomlFile := writeFile(t, "buses.toml", `
+dest_port = 5000
+src_port = 4000
+
+[[buses]]
+bus_name = "Left"
+dest_host = "192.0.2.10"
+
+[[buses]]
+bus_name = "Right"
+src_host = "2001:db8::1"
+dest_host = "2001:db8::2"
+dest_port = 5001
+listen_port = 6000
+`)
+	for _, file := range []string{yamlFile, tomlFile} {
+		t.Run(filepath.Ext(file), func(t *testing.T) {
+			// entries start from every other layer and override it
+			_, c, err := load(t, map[string]string{"DATAGENSIM_SRC_PORT": "4100", "DATAGENSIM_DB_HOST": "redis"},
+				"-config", file, "-dest-port", "5100")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(c.Buses) != 2 {
+				t.Fatalf("%d buses, want 2", len(c.Buses))
+			}
+			want := []struct {
+				name    string
+				dst     string
+				src     string
+				srcPort int
+				dstPort int
+				listen  int
+				version int
+			}{
+				{"Left", "192.0.2.10", "", 4100, 5100, 0, 4},
+				{"Right", "2001:db8::2", "2001:db8::1", 4100, 5001, 6000, 6},
+			}
+			for i, w := range want {
+				b := c.Buses[i]
+				src := ""
+				if b.SrcHost != nil {
+					src = b.SrcHost.String()
+				}
+				if b.BusName != w.name || b.DestHost.String() != w.dst || src != w.src ||
+					b.SrcPort != w.srcPort || b.DestPort != w.dstPort || b.ListenPort != w.listen {
+					t.Errorf("buses[%d] = %s %s:%d -> %s:%d listen %d, want %+v", i,
+						b.BusName, b.SrcHost, b.SrcPort, b.DestHost, b.DestPort, b.ListenPort, w)
+				}
+				if v, err := b.IPVersion(); err != nil || v != w.version {
+					t.Errorf("buses[%d] IP version %d, %v, want %d", i, v, err, w.version)
+				}
+			}
+
+			cfgs := c.BusConfigs()
+			if len(cfgs) != 2 {
+				t.Fatalf("%d bus configs, want 2", len(cfgs))
+			}
+			for i, cfg := range cfgs {
+				if cfg.BusName != c.Buses[i].BusName || cfg.Buses != nil || cfg.DbHost != "redis" {
+					t.Errorf("bus config %d = %s with %d buses and db %s, want %s, none and redis",
+						i, cfg.BusName, len(cfg.Buses), cfg.DbHost, c.Buses[i].BusName)
+				}
+			}
+
+			// printed with the list, and loaded back the same
+			var buf bytes.Buffer
+			if err := c.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			_, back, err := load(t, nil, "-config", writeFile(t, "printed.yaml", buf.String()))
+			if err != nil {
+				t.Fatalf("loading the printed configuration: %v\n%s", err, buf.String())
+			}
+			for i := range c.Buses {
+				for _, s := range busSettings {
+					got, want := c.BusConfigs()[i], back.BusConfigs()[i]
+					if s.get(got) != s.get(want) {
+						t.Errorf("buses[%d] %s = %q after printing, want %q", i, s.key, s.get(want), s.get(got))
+					}
+				}
+			}
+		})
+	}
+
+	_, c, err := load(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfgs := c.BusConfigs(); len(cfgs) != 1 || cfgs[0] != c {
+		t.Errorf("BusConfigs without buses = %v, want the config itself", cfgs)
+	}
+}
+
+func TestValidateBuses(t *testing.T) {
+	tests := []struct {
+		name  string
+		buses string // yaml list
+		want  []string
+	}{
+		{
+			name:  "not a list",
+			buses: "{bus_name: A}",
+			want:  []string{"buses: expected a list of buses"},
+		},
+		{
+			name:  "entry not a table",
+			buses: "[A, B]",
+			want:  []string{"entry 0 is not a table of settings"},
+		},
+		{
+			name:  "process setting in an entry",
+			buses: "[{bus_name: A, db_host: redis}]",
+			want:  []string{`buses[0]: unknown setting "db_host"`},
+		},
+		{
+			name:  "invalid value in an entry",
+			buses: "[{bus_name: A}, {bus_name: B, dest_port: far}]",
+			want:  []string{"buses[1]: dest_port: not an integer"},
+		},
+		{
+			name:  "duplicate names",
+			buses: "[{bus_name: A}, {bus_name: B}, {bus_name: A}]",
+			want:  []string{`buses[2]: duplicate bus name "A"`},
+		},
+		{
+			name:  "shared receive port",
+			buses: "[{bus_name: A, listen_port: 6000}, {bus_name: B, command_port: 6000}]",
+			want:  []string{"buses[1] (B): :6000 already used by bus A"},
+		},
+		{
+			name:  "every bus checked",
+			buses: "[{bus_name: A, bus_type: can}, {bus_name: B, bus_type: serial}, {bus_name: C, src_host: 192.0.2.1, dest_host: '2001:db8::2'}]",
+			want: []string{
+				`buses[0] (A): bus_type: unknown bus type "can"`,
+				"buses[1] (B): serial_device: required by serial buses",
+				"buses[2] (C): source 192.0.2.1 and destination 2001:db8::2 are different IP versions",
+			},
+		},
+		{
+			name:  "flow label on udp",
+			buses: "[{bus_name: A, flow_label: 5, src_host: '::', dest_host: 'ff02::1'}, {bus_name: B, bus_type: af_xdp, flow_label: 5}]",
+			want:  []string{"buses[0] (A): flow_label: only applied by af_xdp buses"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, c, err := load(t, nil, "-config", writeFile(t, "sim.yaml", "buses: "+tt.buses))
+			if err == nil {
+				t.Fatalf("Load = %+v, want an error", c.Buses)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q, want it to mention %q", err, want)
+				}
+			}
+			if n := strings.Count(err.Error(), "\n") + 1; n != len(tt.want) {
+				t.Errorf("%d errors, want %d: %v", n, len(tt.want), err)
+			}
+		})
+	}
+
+	// separate listen hosts may share a port
+	_, _, err := load(t, nil, "-config", writeFile(t, "sim.yaml",
+		"buses: [{bus_name: A, listen_host: 192.0.2.1, listen_port: 6000}, {bus_name: B, listen_host: 192.0.2.2, listen_port: 6000}]"))
+	if err != nil {
+		t.Error(err)
+	}
+}
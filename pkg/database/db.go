@@ -74,6 +74,15 @@ func (r* RedisClient) GetPayloadInfo(payloadId string) (map[string]string, error
 	return retrievedMap, HandleDbError(err, payloadId, "retrieve bus payloads")
 }
 
+//	<payload_id>_faults:	// optional impairments, see internal/sim/faults.go
+//		loss: <probability>
+//		delay: <duration>
+//		...
+func (r* RedisClient) GetPayloadFaults(payloadId string) (map[string]string, error) {
+	retrievedMap, err := r.client.HGetAll(r.ctx, payloadId + "_faults").Result()
+	return retrievedMap, HandleDbError(err, payloadId + "_faults", "retrieve payload faults")
+}
+
 //	<payload_id>_data:
 //       - <data_id>
 //       - <data_id>
@@ -101,6 +110,34 @@ func (r* RedisClient) UpdateData(data_id string, data map[string]string) (error)
 	return HandleDbError(err, data_id, "push data")
 }
 
+// GetValues returns the value of each data id in one round trip, empty for
+// data ids without one
+func (r *RedisClient) GetValues(dataIds []string) ([]string, error) {
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(dataIds))
+	for i, id := range dataIds {
+		cmds[i] = pipe.HGet(r.ctx, id, "value")
+	}
+	if _, err := pipe.Exec(r.ctx); err != nil && err != redis.Nil {
+		return nil, HandleDbError(err, "", "retrieve data values")
+	}
+	values := make([]string, len(dataIds))
+	for i, cmd := range cmds {
+		values[i] = cmd.Val()
+	}
+	return values, nil
+}
+
+// SetValues writes the value of each data id in one round trip
+func (r *RedisClient) SetValues(values map[string]string) error {
+	pipe := r.client.Pipeline()
+	for id, v := range values {
+		pipe.HSet(r.ctx, id, "value", v)
+	}
+	_, err := pipe.Exec(r.ctx)
+	return HandleDbError(err, "", "push data values")
+}
+
 // 	<data_id>-info: <- example analog info
 //		min: <value>
 //		max: <value>
@@ -108,6 +145,11 @@ func (r* RedisClient) UpdateData(data_id string, data map[string]string) (error)
 //		frequency: <value>
 //		calibration: <calib_id>
 //		calibration_type: <type>
+//		expression: <expr> <- optional, derive the value from other data ids
+//		shared: <bool> <- optional, one generator for the bus; default true when in several payloads
+//		model: <plant> <- optional, first_order, second_order, integrator or state_space
+//		input: <expr> <- plant input, e.g. a data id; plant parameters gain, bias, tau, wn, zeta, x0, a, b, c, d
+//		playback: <csv file> <- optional, play back recorded data; column, interpolation, loop, speed, time_offset
 func (r* RedisClient) GetDataInfo(data_id string) (map[string]string, error) {
 	retrievedMap, err := r.client.HGetAll(r.ctx, data_id + "_info").Result()
 	return retrievedMap, HandleDbError(err, data_id + "_info", "retrieve data info")
@@ -122,4 +164,69 @@ func (r* RedisClient) GetDataInfo(data_id string) (map[string]string, error) {
 func (r* RedisClient) GetCalibInfo(calib_id string) (map[string]string, error) {
 	retrievedMap, err := r.client.HGetAll(r.ctx, calib_id).Result()
 	return retrievedMap, HandleDbError(err, calib_id, "retrieve data info")
-}
\ No newline at end of file
+}
+
+// 	<data_id>_info: fields changed at runtime are written back
+func (r* RedisClient) UpdateDataInfo(data_id string, info map[string]string) (error) {
+	err := r.client.HSet(r.ctx, data_id + "_info", info).Err()
+	return HandleDbError(err, data_id + "_info", "push data info")
+}
+
+// 	<bus>_commands:
+//		- <opcode>
+//		- <opcode>
+//		...
+func (r *RedisClient) GetCommands(bus string) ([]string, error) {
+	key := bus + "_commands"
+	stringSlice, err := r.client.LRange(r.ctx, key, 0, -1).Result()
+	return stringSlice, HandleDbError(err, key, "retrieve bus commands")
+}
+
+// 	<bus>_cmd_<opcode>:
+//		name: <name>
+//		response: <payload_id>	// optional payload emitted after the command
+func (r* RedisClient) GetCommandInfo(bus string, opcode string) (map[string]string, error) {
+	key := bus + "_cmd_" + opcode
+	retrievedMap, err := r.client.HGetAll(r.ctx, key).Result()
+	return retrievedMap, HandleDbError(err, key, "retrieve command info")
+}
+
+// 	<bus>_cmd_<opcode>_args:
+//		- <arg_id>
+//		...
+//
+// 	<arg_id>:
+//		offset: <value>		// bit offset in the argument bytes
+//		size: <value>		// in bits
+//		type: <type>
+//		target: <data_id>
+//		field: <value|min|max|step|frequency|phase|type>
+//		min: <value>		// optional validation limits
+//		max: <value>
+func (r *RedisClient) GetCommandArgs(bus string, opcode string) ([]string, error) {
+	key := bus + "_cmd_" + opcode + "_args"
+	stringSlice, err := r.client.LRange(r.ctx, key, 0, -1).Result()
+	return stringSlice, HandleDbError(err, key, "retrieve command args")
+}
+
+// 	<bus>_cmd_log:		// newest first, trimmed to max entries
+//		- <entry>
+func (r *RedisClient) LogCommand(bus string, entry string, max int64) error {
+	key := bus + "_cmd_log"
+	pipe := r.client.TxPipeline()
+	pipe.LPush(r.ctx, key, entry)
+	pipe.LTrim(r.ctx, key, 0, max-1)
+	_, err := pipe.Exec(r.ctx)
+	return HandleDbError(err, key, "log command")
+}
+
+// 	<bus>_events:		// scenario events, newest first, trimmed to max entries
+//		- <entry>
+func (r *RedisClient) LogEvent(bus string, entry string, max int64) error {
+	key := bus + "_events"
+	pipe := r.client.TxPipeline()
+	pipe.LPush(r.ctx, key, entry)
+	pipe.LTrim(r.ctx, key, 0, max-1)
+	_, err := pipe.Exec(r.ctx)
+	return HandleDbError(err, key, "log event")
+}
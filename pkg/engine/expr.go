@@ -0,0 +1,456 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed expression over named values, used for data points
+// derived from other data points. Supported syntax, loosest binding first:
+//
+//	c ? a : b
+//	a || b
+//	a && b
+//	a < b, a <= b, a > b, a >= b, a == b, a != b
+//	a + b, a - b
+//	a * b, a / b, a % b
+//	-a, !a
+//	numbers, true, false, names, (a), f(a, ...)
+//
+// Functions are min, max, abs, clamp(x, lo, hi), if(c, a, b), round, floor,
+// ceil and sqrt. Comparisons and logical operators yield 1 or 0, and any
+// non-zero value is true. Names may contain letters, digits, '_' and '.'.
+type Expr struct {
+	src  string
+	root exprNode
+	vars []string
+}
+
+// Lookup returns the current value of a name referenced by an expression
+type Lookup func(name string) (float64, error)
+
+type exprNode interface {
+	eval(lookup Lookup) (float64, error)
+}
+
+// ParseExpr parses src into an expression.
+func ParseExpr(src string) (*Expr, error) {
+	p := &exprParser{src: src}
+	p.next()
+	root, err := p.ternary()
+	if err == nil && p.tok.kind != tokEOF {
+		err = p.errorf("unexpected %q", p.tok.text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+	slices.Sort(p.vars)
+	return &Expr{src: src, root: root, vars: slices.Compact(p.vars)}, nil
+}
+
+// Vars returns the names the expression depends on, sorted.
+func (e *Expr) Vars() []string {
+	return e.vars
+}
+
+// Eval evaluates the expression, resolving names with lookup.
+func (e *Expr) Eval(lookup Lookup) (float64, error) {
+	return e.root.eval(lookup)
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// ----- evaluation -----
+
+type numNode float64
+
+func (n numNode) eval(Lookup) (float64, error) { return float64(n), nil }
+
+type varNode string
+
+func (n varNode) eval(lookup Lookup) (float64, error) { return lookup(string(n)) }
+
+type unaryNode struct {
+	op string
+	x  exprNode
+}
+
+func (n *unaryNode) eval(lookup Lookup) (float64, error) {
+	x, err := n.x.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	if n.op == "!" {
+		return truth(x == 0), nil
+	}
+	return -x, nil
+}
+
+type binaryNode struct {
+	op   string
+	x, y exprNode
+}
+
+func (n *binaryNode) eval(lookup Lookup) (float64, error) {
+	x, err := n.x.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	// short circuit
+	switch {
+	case n.op == "&&" && x == 0:
+		return 0, nil
+	case n.op == "||" && x != 0:
+		return 1, nil
+	}
+	y, err := n.y.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return x / y, nil
+	case "%":
+		if y == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return math.Mod(x, y), nil
+	case "<":
+		return truth(x < y), nil
+	case "<=":
+		return truth(x <= y), nil
+	case ">":
+		return truth(x > y), nil
+	case ">=":
+		return truth(x >= y), nil
+	case "==":
+		return truth(x == y), nil
+	case "!=":
+		return truth(x != y), nil
+	case "&&", "||":
+		return truth(y != 0), nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", n.op)
+}
+
+type condNode struct {
+	cond, then, other exprNode
+}
+
+func (n *condNode) eval(lookup Lookup) (float64, error) {
+	c, err := n.cond.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	if c != 0 {
+		return n.then.eval(lookup)
+	}
+	return n.other.eval(lookup)
+}
+
+type callNode struct {
+	fn   string
+	args []exprNode
+}
+
+// exprFuncs maps function names to their argument count, -1 for one or more
+var exprFuncs = map[string]int{
+	"min": -1, "max": -1, "abs": 1, "clamp": 3, "if": 3,
+	"round": 1, "floor": 1, "ceil": 1, "sqrt": 1,
+}
+
+func (n *callNode) eval(lookup Lookup) (float64, error) {
+	switch n.fn {
+	case "if":
+		return (&condNode{n.args[0], n.args[1], n.args[2]}).eval(lookup)
+	case "min":
+		return n.fold(math.Min, lookup)
+	case "max":
+		return n.fold(math.Max, lookup)
+	}
+	// the other functions take at most three arguments, kept off the heap
+	var args [3]float64
+	for i, a := range n.args {
+		v, err := a.eval(lookup)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	switch n.fn {
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "clamp":
+		return math.Min(math.Max(args[0], args[1]), args[2]), nil
+	case "round":
+		return math.Round(args[0]), nil
+	case "floor":
+		return math.Floor(args[0]), nil
+	case "ceil":
+		return math.Ceil(args[0]), nil
+	case "sqrt":
+		if args[0] < 0 {
+			return 0, fmt.Errorf("sqrt of negative value %v", args[0])
+		}
+		return math.Sqrt(args[0]), nil
+	}
+	return 0, fmt.Errorf("unknown function %s", n.fn)
+}
+
+// fold combines the arguments pairwise with pick, for min and max
+func (n *callNode) fold(pick func(x, y float64) float64, lookup Lookup) (float64, error) {
+	var acc float64
+	for i, a := range n.args {
+		v, err := a.eval(lookup)
+		if err != nil {
+			return 0, err
+		}
+		if i == 0 {
+			acc = v
+		} else {
+			acc = pick(acc, v)
+		}
+	}
+	return acc, nil
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ----- parsing -----
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokName
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type exprParser struct {
+	src  string
+	pos  int
+	tok  token
+	vars []string
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at %d: %s", p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+// next advances to the next token
+func (p *exprParser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE", p.src[p.pos]) >= 0 {
+			// exponent sign
+			if (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') && p.pos+1 < len(p.src) && strings.IndexByte("+-", p.src[p.pos+1]) >= 0 {
+				p.pos++
+			}
+			p.pos++
+		}
+		p.tok = token{kind: tokNum, text: p.src[start:p.pos], pos: start}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || p.src[p.pos] == '.' ||
+			unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		p.tok = token{kind: tokName, text: p.src[start:p.pos], pos: start}
+	default:
+		for _, op := range []string{"<=", ">=", "==", "!=", "&&", "||"} {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				p.pos += 2
+				p.tok = token{kind: tokOp, text: op, pos: start}
+				return
+			}
+		}
+		p.pos++
+		p.tok = token{kind: tokOp, text: string(c), pos: start}
+	}
+}
+
+func (p *exprParser) isOp(ops ...string) bool {
+	return p.tok.kind == tokOp && slices.Contains(ops, p.tok.text)
+}
+
+func (p *exprParser) expect(op string) error {
+	if !p.isOp(op) {
+		if p.tok.kind == tokEOF {
+			return p.errorf("expected %q, got end of expression", op)
+		}
+		return p.errorf("expected %q, got %q", op, p.tok.text)
+	}
+	p.next()
+	return nil
+}
+
+func (p *exprParser) ternary() (exprNode, error) {
+	cond, err := p.binary(0)
+	if err != nil || !p.isOp("?") {
+		return cond, err
+	}
+	p.next()
+	then, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	other, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{cond, then, other}, nil
+}
+
+// binary operators by precedence level, loosest first
+var exprLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"<", "<=", ">", ">=", "==", "!="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) binary(level int) (exprNode, error) {
+	if level == len(exprLevels) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(exprLevels[level]...) {
+		op := p.tok.text
+		p.next()
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{op: op, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	if p.isOp("-", "!", "+") {
+		op := p.tok.text
+		p.next()
+		x, err := p.unary()
+		if err != nil || op == "+" {
+			return x, err
+		}
+		return &unaryNode{op: op, x: x}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNum:
+		p.next()
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok.text)
+		}
+		return numNode(v), nil
+
+	case tokName:
+		p.next()
+		if !p.isOp("(") {
+			switch tok.text {
+			case "true":
+				return numNode(1), nil
+			case "false":
+				return numNode(0), nil
+			}
+			p.vars = append(p.vars, tok.text)
+			return varNode(tok.text), nil
+		}
+		return p.call(tok)
+
+	case tokOp:
+		if tok.text == "(" {
+			p.next()
+			x, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+		return nil, p.errorf("unexpected %q", tok.text)
+	}
+	return nil, p.errorf("unexpected end of expression")
+}
+
+func (p *exprParser) call(name token) (exprNode, error) {
+	want, ok := exprFuncs[name.text]
+	if !ok {
+		return nil, fmt.Errorf("at %d: unknown function %s", name.pos, name.text)
+	}
+	p.next() // (
+	n := &callNode{fn: name.text}
+	for !p.isOp(")") {
+		if len(n.args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		n.args = append(n.args, arg)
+	}
+	p.next() // )
+	if (want < 0 && len(n.args) == 0) || (want >= 0 && len(n.args) != want) {
+		return nil, fmt.Errorf("at %d: %s takes %s arguments, got %d", name.pos, name.text, argCount(want), len(n.args))
+	}
+	return n, nil
+}
+
+func argCount(n int) string {
+	if n < 0 {
+		return "one or more"
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+var errTestLookup = errors.New("lookup failed")
+
+// testValues resolves names from a fixed set, failing on "bad"
+func testValues(name string) (float64, error) {
+	switch name {
+	case "a":
+		return 2, nil
+	case "b":
+		return 3, nil
+	case "zero":
+		return 0, nil
+	case "gps.alt":
+		return 1500, nil
+	}
+	return 0, fmt.Errorf("%s: %w", name, errTestLookup)
+}
+
+func TestExprEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		// precedence and associativity
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"24 / 4 / 2", 3},
+		{"7 % 4 * 2", 6},
+		{"-a * b", -6},
+		{"- -a", 2},
+		{"+a", 2},
+		{"1 + 2 < 4", 1},
+		{"3 > 2 > 1", 0}, // comparisons share a level, left to right
+		{"1 || 0 && 0", 1},
+		{"(1 || 0) && 0", 0},
+		{"!zero + 1", 2},
+		{"!(a > 1)", 0},
+		{"0 ? 1 : 0 ? 2 : 3", 3},
+		{"1 ? 0 ? 4 : 5 : 6", 5},
+		{"a > 1 ? a * 10 : b", 20},
+
+		// operators
+		{"a <= 2", 1},
+		{"a >= 3", 0},
+		{"a != b", 1},
+		{"-7 % 3", -1},
+		{"2.5e1 + 1E-1", 25.1},
+		{".5 + 1.", 1.5},
+		{"true + true", 2},
+		{"false || a", 1},
+		{"a && b", 1},
+
+		// names and functions
+		{"gps.alt / 100", 15},
+		{"min(b, a, 4)", 2},
+		{"max(b)", 3},
+		{"abs(-a)", 2},
+		{"clamp(a * 10, 0, 5)", 5},
+		{"clamp(-a, 0, 5)", 0},
+		{"if(zero, 1, b)", 3},
+		{"round(2.5)", 3},
+		{"floor(-1.5)", -2},
+		{"ceil(1.2)", 2},
+		{"sqrt(a * 8)", 4},
+
+		// short circuit and untaken branches skip failing lookups
+		{"zero && bad", 0},
+		{"a || bad", 1},
+		{"a ? b : bad", 3},
+		{"if(zero, bad, a)", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := ParseExpr(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := e.Eval(testValues)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExprEvalErrors(t *testing.T) {
+	tests := []struct {
+		src    string
+		lookup bool // the lookup error is passed on
+	}{
+		{"a / zero", false},
+		{"a % zero", false},
+		{"sqrt(-a)", false},
+		{"bad + 1", true},
+		{"1 + bad", true},
+		{"-bad", true},
+		{"a ? bad : 1", true},
+		{"zero ? 1 : bad", true},
+		{"min(a, bad)", true},
+		{"abs(bad)", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := ParseExpr(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := e.Eval(testValues)
+			if err == nil {
+				t.Fatalf("%s = %v, want an error", tt.src, got)
+			}
+			if errors.Is(err, errTestLookup) != tt.lookup {
+				t.Errorf("error %v, lookup error %v", err, tt.lookup)
+			}
+		})
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		"1 +",
+		"(1 + 2",
+		"1 + 2)",
+		"a b",
+		"a ? b",
+		"a ? b c",
+		"* 2",
+		"1..2",
+		"1e",
+		"a $ b",
+		"f(1)",
+		"abs()",
+		"abs(1, 2)",
+		"clamp(1, 2)",
+		"min()",
+		"max(1,)",
+		"min(1 2)",
+		"sqrt(1",
+	}
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			if e, err := ParseExpr(src); err == nil {
+				t.Errorf("ParseExpr(%q) = %v, want an error", src, e)
+			}
+		})
+	}
+}
+
+func TestExprVars(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"1 + 2", nil},
+		{"true && false", nil},
+		{"b + a * b", []string{"a", "b"}},
+		{"max(gps.alt, x_1) ? a : -a", []string{"a", "gps.alt", "x_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := ParseExpr(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.Vars(); !slices.Equal(got, tt.want) {
+				t.Errorf("Vars = %q, want %q", got, tt.want)
+			}
+			if e.String() != tt.src {
+				t.Errorf("String = %q, want %q", e.String(), tt.src)
+			}
+		})
+	}
+}
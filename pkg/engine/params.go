@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+// engine types accepted by SetParam("type", ...)
+var engTypes = map[string]bool{
+	"static": true,
+	"sin":    true,
+	"ramp":   true,
+}
+
+// parseFreq converts a frequency parameter in milliseconds to a period
+func parseFreq(value string) (time.Duration, error) {
+	ms, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid frequency %q: %w", value, err)
+	}
+	if ms <= 0 {
+		return 0, fmt.Errorf("invalid frequency %q: must be positive", value)
+	}
+	return time.Duration(ms * float64(time.Millisecond)), nil
+}
+
+// parseType checks an engine type parameter
+func parseType(value string) (string, error) {
+	if !engTypes[value] {
+		return "", fmt.Errorf("unknown engine type: %s", value)
+	}
+	return value, nil
+}
+
+// setParam updates a single numeric engine parameter by name.
+// frequency is given in milliseconds, like the data point info.
+func (s *engData[T]) setParam(name string, value string) error {
+	if name == "frequency" {
+		freq, err := parseFreq(value)
+		if err != nil {
+			return err
+		}
+		s.Frequency = freq
+		s.Hz = T(1 / freq.Seconds())
+		return nil
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	switch name {
+	case "min":
+		s.Min = T(v)
+	case "max":
+		s.Max = T(v)
+	case "step":
+		s.Step = T(v)
+	case "phase":
+		s.Phase = T(v)
+	default:
+		return fmt.Errorf("unknown engine parameter: %s", name)
+	}
+	return nil
+}
+
+// checkRange rejects a parameter change that would leave min above max
+func checkRange[T constraints.Float](s *engData[T]) error {
+	if s.Min > s.Max {
+		return fmt.Errorf("min %v greater than max %v", s.Min, s.Max)
+	}
+	return nil
+}
+
+// SetParam changes an engine parameter (type, min, max, step, frequency or phase) at runtime.
+func (e *NumEngine64) SetParam(name string, value string) error {
+	if name == "type" {
+		t, err := parseType(value)
+		if err != nil {
+			return err
+		}
+		e.engType = t
+		return nil
+	}
+	prev := e.engData
+	if err := e.setParam(name, value); err != nil {
+		return err
+	}
+	if err := checkRange(&e.engData); err != nil {
+		e.engData = prev
+		return err
+	}
+	return nil
+}
+
+// SetParam changes an engine parameter (type, min, max, step, frequency or phase) at runtime.
+func (e *NumEngineInt) SetParam(name string, value string) error {
+	if name == "type" {
+		t, err := parseType(value)
+		if err != nil {
+			return err
+		}
+		e.engType = t
+		return nil
+	}
+	prev := e.engData
+	if err := e.setParam(name, value); err != nil {
+		return err
+	}
+	if err := checkRange(&e.engData); err != nil {
+		e.engData = prev
+		return err
+	}
+	return nil
+}
+
+// SetParam changes the toggle frequency (milliseconds) at runtime.
+func (b *BoolEngine) SetParam(name string, value string) error {
+	if name != "frequency" {
+		return fmt.Errorf("unknown engine parameter: %s", name)
+	}
+	freq, err := parseFreq(value)
+	if err != nil {
+		return err
+	}
+	b.Frequency = freq
+	return nil
+}
+
+// SetParam changes an engine parameter (type, frequency or phase) at runtime.
+func (e *StrEngine) SetParam(name string, value string) error {
+	switch name {
+	case "type":
+		t, err := parseType(value)
+		if err != nil {
+			return err
+		}
+		e.EngType = t
+	case "frequency", "phase":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+		if name == "frequency" {
+			e.Frequency = v
+		} else {
+			e.Phase = v
+		}
+	default:
+		return fmt.Errorf("unknown engine parameter: %s", name)
+	}
+	return nil
+}
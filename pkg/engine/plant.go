@@ -0,0 +1,338 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Plant models, selected with the model parameter. u is the input, x the
+// state and the output is x[0], except for state_space.
+//
+//	first_order:  x' = (gain*u + bias - x) / tau            thermal mass, tank level
+//	second_order: x'' = wn^2*(gain*u + bias - x) - 2*zeta*wn*x'  motor speed with inertia
+//	integrator:   x' = gain*u + bias                        battery charge
+//	state_space:  x' = A x + B u, y = C x + D u              any small linear system
+//
+// min and max bound the state of the first three models and the output of
+// state_space. Matrices are given row by row, rows separated by ';', e.g.
+// a = "0 1; -4 -0.4", b = "0; 4", c = "1 0".
+const (
+	PlantFirstOrder  = "first_order"
+	PlantSecondOrder = "second_order"
+	PlantIntegrator  = "integrator"
+	PlantStateSpace  = "state_space"
+)
+
+const (
+	plantMaxStep     = 5 * time.Millisecond // integration step
+	plantMaxSubsteps = 2000                 // per update, longer gaps take larger steps
+)
+
+// scalar parameters of the plant models and their defaults
+var plantParams = map[string]float64{
+	"gain": 1,
+	"bias": 0,
+	"tau":  1,
+	"wn":   1,
+	"zeta": 1,
+	"x0":   0,
+}
+
+// Plant integrates a small dynamic system on the simulator clock. Its input
+// is an expression, usually over other data points, so plants can be wired
+// into closed loops.
+type Plant struct {
+	model string
+	k     map[string]float64 // scalar parameters
+	a     [][]float64        // state_space matrices
+	b     []float64
+	c     []float64
+	d     float64
+	min   float64
+	max   float64
+
+	input *Expr
+	x     []float64 // state
+	u     float64   // last input
+	last  time.Time // time of the last step
+	work  []float64 // rk4 stages and intermediate state, 5 per state
+}
+
+// NewPlant creates a plant of the given model. params holds the model
+// parameters, the input expression and optional min and max; other keys
+// are ignored so that a data point info can be passed as is.
+func NewPlant(model string, params map[string]string) (*Plant, error) {
+	p := &Plant{
+		model: model,
+		k:     make(map[string]float64, len(plantParams)),
+		min:   math.Inf(-1),
+		max:   math.Inf(1),
+	}
+	for name, v := range plantParams {
+		p.k[name] = v
+	}
+	switch model {
+	case PlantFirstOrder, PlantIntegrator:
+		p.x = make([]float64, 1)
+	case PlantSecondOrder:
+		p.x = make([]float64, 2)
+	case PlantStateSpace:
+		for _, name := range []string{"a", "b", "c"} {
+			if params[name] == "" {
+				return nil, fmt.Errorf("%s plant needs matrix %s", model, name)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown plant model: %s", model)
+	}
+
+	if err := p.SetParam("input", "0"); err != nil {
+		return nil, err
+	}
+	// matrices first, x0 needs the state size
+	for _, name := range []string{"a", "b", "c", "d", "input", "gain", "bias", "tau", "wn", "zeta", "min", "max", "x0"} {
+		if v, ok := params[name]; ok && v != "" {
+			if err := p.SetParam(name, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+// Inputs returns the names the input expression depends on.
+func (p *Plant) Inputs() []string {
+	return p.input.Vars()
+}
+
+// Step advances the plant to now with the current input and returns its
+// output. The first step only starts the clock.
+func (p *Plant) Step(now time.Time, lookup Lookup) (float64, error) {
+	u, err := p.input.Eval(lookup)
+	if err != nil {
+		return p.output(), fmt.Errorf("input %s: %w", p.input, err)
+	}
+	p.u = u
+	if p.last.IsZero() || !now.After(p.last) {
+		p.last = now
+		return p.output(), nil
+	}
+
+	dt := now.Sub(p.last).Seconds()
+	p.last = now
+	n := min(int(math.Ceil(dt/plantMaxStep.Seconds())), plantMaxSubsteps)
+	h := dt / float64(n)
+	for range n {
+		p.rk4(h)
+	}
+	return p.output(), nil
+}
+
+// Output returns the output for the current state without stepping.
+func (p *Plant) Output() float64 {
+	return p.output()
+}
+
+// rk4 advances the state by h seconds
+func (p *Plant) rk4(h float64) {
+	n := len(p.x)
+	if len(p.work) != 5*n {
+		p.work = make([]float64, 5*n)
+	}
+	k1, k2, k3, k4, tmp := p.work[:n], p.work[n:2*n], p.work[2*n:3*n], p.work[3*n:4*n], p.work[4*n:]
+	p.deriv(k1, p.x)
+	for i := range tmp {
+		tmp[i] = p.x[i] + h/2*k1[i]
+	}
+	p.deriv(k2, tmp)
+	for i := range tmp {
+		tmp[i] = p.x[i] + h/2*k2[i]
+	}
+	p.deriv(k3, tmp)
+	for i := range tmp {
+		tmp[i] = p.x[i] + h*k3[i]
+	}
+	p.deriv(k4, tmp)
+	for i := range p.x {
+		p.x[i] += h / 6 * (k1[i] + 2*k2[i] + 2*k3[i] + k4[i])
+	}
+	if p.model != PlantStateSpace {
+		p.x[0] = math.Min(math.Max(p.x[0], p.min), p.max)
+	}
+}
+
+// deriv writes the state derivative at x for the current input into dx
+func (p *Plant) deriv(dx []float64, x []float64) {
+	u, k := p.u, p.k
+	switch p.model {
+	case PlantFirstOrder:
+		dx[0] = (k["gain"]*u + k["bias"] - x[0]) / k["tau"]
+		return
+	case PlantSecondOrder:
+		wn := k["wn"]
+		dx[0], dx[1] = x[1], wn*wn*(k["gain"]*u+k["bias"]-x[0])-2*k["zeta"]*wn*x[1]
+		return
+	case PlantIntegrator:
+		dx[0] = k["gain"]*u + k["bias"]
+		return
+	}
+	for i, row := range p.a {
+		dx[i] = 0
+		for j, a := range row {
+			dx[i] += a * x[j]
+		}
+		dx[i] += p.b[i] * u
+	}
+}
+
+func (p *Plant) output() float64 {
+	if p.model != PlantStateSpace {
+		return p.x[0]
+	}
+	y := p.d * p.u
+	for i, c := range p.c {
+		y += c * p.x[i]
+	}
+	return math.Min(math.Max(y, p.min), p.max)
+}
+
+// SetParam changes a plant parameter at runtime: input, gain, bias, tau, wn,
+// zeta, min, max, x0 (resets the state) or the state_space matrices a, b, c
+// and d (a, b and c reset the state).
+func (p *Plant) SetParam(name string, value string) error {
+	switch name {
+	case "input":
+		expr, err := ParseExpr(value)
+		if err != nil {
+			return err
+		}
+		p.input = expr
+		return nil
+	case "a", "b", "c", "d":
+		if p.model != PlantStateSpace {
+			return fmt.Errorf("%s is a state_space parameter", name)
+		}
+		return p.setMatrix(name, value)
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	switch name {
+	case "tau", "wn":
+		if v <= 0 {
+			return fmt.Errorf("%s must be positive, got %v", name, v)
+		}
+	case "zeta":
+		if v < 0 {
+			return fmt.Errorf("zeta must not be negative, got %v", v)
+		}
+	case "min", "max":
+		lo, hi := p.min, p.max
+		if name == "min" {
+			lo = v
+		} else {
+			hi = v
+		}
+		if lo > hi {
+			return fmt.Errorf("min %v greater than max %v", lo, hi)
+		}
+		p.min, p.max = lo, hi
+		return nil
+	case "x0":
+		p.k[name] = v
+		clear(p.x)
+		if len(p.x) > 0 {
+			p.x[0] = v
+		}
+		return nil
+	}
+	if _, ok := plantParams[name]; !ok {
+		return fmt.Errorf("unknown plant parameter: %s", name)
+	}
+	p.k[name] = v
+	return nil
+}
+
+// setMatrix replaces a state_space matrix, keeping the dimensions consistent
+func (p *Plant) setMatrix(name string, value string) error {
+	m, err := parseMatrix(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	n := len(p.a)
+	switch name {
+	case "a":
+		for _, row := range m {
+			if len(row) != len(m) {
+				return fmt.Errorf("a must be square, got %d rows of %d", len(m), len(row))
+			}
+		}
+		if p.b != nil && len(p.b) != len(m) || p.c != nil && len(p.c) != len(m) {
+			return fmt.Errorf("a is %dx%d, b and c have %d and %d states", len(m), len(m), len(p.b), len(p.c))
+		}
+		p.a = m
+		p.x = make([]float64, len(m))
+		return nil
+	case "b":
+		if len(m) == 1 && n != 1 {
+			m = transpose(m)
+		}
+		if len(m) != n || len(m[0]) != 1 {
+			return fmt.Errorf("b must be %dx1", n)
+		}
+		p.b = transpose(m)[0]
+	case "c":
+		if len(m) != 1 || len(m[0]) != n {
+			return fmt.Errorf("c must be 1x%d", n)
+		}
+		p.c = m[0]
+	case "d":
+		if len(m) != 1 || len(m[0]) != 1 {
+			return fmt.Errorf("d must be a scalar")
+		}
+		p.d = m[0][0]
+		return nil
+	}
+	clear(p.x)
+	return nil
+}
+
+// parseMatrix parses rows separated by ';' of numbers separated by spaces or commas
+func parseMatrix(value string) ([][]float64, error) {
+	var m [][]float64
+	for _, line := range strings.Split(value, ";") {
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty row")
+		}
+		row := make([]float64, len(fields))
+		for i, f := range fields {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, err
+			}
+			row[i] = v
+		}
+		if len(m) > 0 && len(row) != len(m[0]) {
+			return nil, fmt.Errorf("rows of different length")
+		}
+		m = append(m, row)
+	}
+	return m, nil
+}
+
+func transpose(m [][]float64) [][]float64 {
+	t := make([][]float64, len(m[0]))
+	for j := range t {
+		t[j] = make([]float64, len(m))
+		for i := range m {
+			t[j][i] = m[i][j]
+		}
+	}
+	return t
+}
@@ -0,0 +1,246 @@
+package engine
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// constInput resolves every name to u
+func constInput(u float64) Lookup {
+	return func(string) (float64, error) { return u, nil }
+}
+
+// runPlant steps p from t0 every step until d has passed and returns the
+// output at the end
+func runPlant(t *testing.T, p *Plant, lookup Lookup, d, step time.Duration) float64 {
+	t.Helper()
+	t0 := time.Unix(1000, 0)
+	y, err := p.Step(t0, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for at := step; at <= d; at += step {
+		if y, err = p.Step(t0.Add(at), lookup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return y
+}
+
+func TestPlantStep(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  string
+		params map[string]string
+		u      float64
+		d      time.Duration
+		want   float64
+	}{
+		{
+			name:  "first order",
+			model: PlantFirstOrder,
+			// x = gain*u * (1 - e^(-t/tau))
+			params: map[string]string{"gain": "2", "tau": "0.5"},
+			u:      1, d: time.Second, want: 2 * (1 - math.Exp(-2)),
+		},
+		{
+			name:   "first order from x0 with bias",
+			model:  PlantFirstOrder,
+			params: map[string]string{"bias": "10", "x0": "20", "tau": "2"},
+			u:      0, d: 2 * time.Second, want: 10 + 10*math.Exp(-1),
+		},
+		{
+			name:  "second order critically damped",
+			model: PlantSecondOrder,
+			// x = 1 - (1 + wn*t) e^(-wn*t)
+			params: map[string]string{"wn": "2", "zeta": "1"},
+			u:      1, d: time.Second, want: 1 - 3*math.Exp(-2),
+		},
+		{
+			name:  "second order undamped",
+			model: PlantSecondOrder,
+			// x = 1 - cos(wn*t)
+			params: map[string]string{"wn": "3.141592653589793", "zeta": "0"},
+			u:      1, d: time.Second, want: 2,
+		},
+		{
+			name:   "integrator",
+			model:  PlantIntegrator,
+			params: map[string]string{"gain": "2", "bias": "1", "x0": "5"},
+			u:      3, d: 2 * time.Second, want: 19,
+		},
+		{
+			name:   "integrator at max",
+			model:  PlantIntegrator,
+			params: map[string]string{"max": "5"},
+			u:      1, d: 10 * time.Second, want: 5,
+		},
+		{
+			name:   "integrator at min",
+			model:  PlantIntegrator,
+			params: map[string]string{"min": "-1"},
+			u:      -1, d: 10 * time.Second, want: -1,
+		},
+		{
+			name:  "state space oscillator",
+			model: PlantStateSpace,
+			// x'' = -4x + 4u, x = 1 - cos(2t)
+			params: map[string]string{"a": "0 1; -4 0", "b": "0; 4", "c": "1 0"},
+			u:      1, d: time.Second, want: 1 - math.Cos(2),
+		},
+		{
+			name:   "state space feedthrough",
+			model:  PlantStateSpace,
+			params: map[string]string{"a": "-1", "b": "1", "c": "2", "d": "3"},
+			u:      1, d: time.Second, want: 2*(1-math.Exp(-1)) + 3,
+		},
+		{
+			name:   "state space output bounded",
+			model:  PlantStateSpace,
+			params: map[string]string{"a": "0", "b": "1", "c": "1", "max": "0.5"},
+			u:      1, d: time.Second, want: 0.5,
+		},
+	}
+	for _, tt := range tests {
+		// fine steps, steps longer than the integration step and a single gap
+		for _, step := range []time.Duration{time.Millisecond, 20 * time.Millisecond, tt.d} {
+			t.Run(tt.name+"/"+step.String(), func(t *testing.T) {
+				params := maps.Clone(tt.params)
+				params["input"] = "u"
+				p, err := NewPlant(tt.model, params)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := runPlant(t, p, constInput(tt.u), tt.d, step)
+				if math.Abs(got-tt.want) > 1e-6 {
+					t.Errorf("output after %v = %.9f, want %.9f", tt.d, got, tt.want)
+				}
+				if p.Output() != got {
+					t.Errorf("Output = %v, want %v", p.Output(), got)
+				}
+			})
+		}
+	}
+}
+
+func TestPlantClock(t *testing.T) {
+	p, err := NewPlant(PlantIntegrator, map[string]string{"input": "rate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Inputs(); !slices.Equal(got, []string{"rate"}) {
+		t.Errorf("Inputs = %q, want [rate]", got)
+	}
+	t0 := time.Unix(1000, 0)
+	steps := []struct {
+		at   time.Duration
+		u    float64
+		want float64
+	}{
+		{0, 1, 0},                       // the first step starts the clock
+		{time.Second, 1, 1},             // x' = u
+		{time.Second, 5, 1},             // no time passed
+		{500 * time.Millisecond, 5, 1},  // back in time restarts the clock
+		{1500 * time.Millisecond, 2, 3}, // the latest input holds over the step
+		{1000 * time.Second, 0, 3},      // past the substep limit
+		{2000 * time.Second, 0.001, 4},  // larger steps stay exact for a constant rate
+	}
+	for _, s := range steps {
+		got, err := p.Step(t0.Add(s.at), constInput(s.u))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-s.want) > 1e-9 {
+			t.Errorf("at %v with input %v = %v, want %v", s.at, s.u, got, s.want)
+		}
+	}
+
+	// a failed lookup keeps the state and the clock
+	errLookup := errors.New("no rate")
+	got, err := p.Step(t0.Add(3000*time.Second), func(string) (float64, error) { return 0, errLookup })
+	if !errors.Is(err, errLookup) || math.Abs(got-4) > 1e-9 {
+		t.Errorf("Step = %v, %v, want 4 and the lookup error", got, err)
+	}
+}
+
+func TestPlantSetParam(t *testing.T) {
+	p, err := NewPlant(PlantSecondOrder, map[string]string{"wn": "2", "input": "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runPlant(t, p, constInput(1), time.Second, 10*time.Millisecond)
+	if err := p.SetParam("x0", "7"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Output() != 7 || p.x[1] != 0 {
+		t.Errorf("state after x0 = %v, want [7 0]", p.x)
+	}
+
+	s, err := NewPlant(PlantStateSpace, map[string]string{"a": "0 1; -4 0", "b": "0 4", "c": "1 0", "x0": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(s.b, []float64{0, 4}) {
+		t.Errorf("b given as a row = %v, want [0 4]", s.b)
+	}
+	if !slices.Equal(s.x, []float64{2, 0}) {
+		t.Errorf("state = %v, want [2 0]", s.x)
+	}
+	if err := s.SetParam("c", "0 1"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(s.x, []float64{0, 0}) {
+		t.Errorf("state after c = %v, want it reset", s.x)
+	}
+}
+
+func TestPlantErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  string
+		params map[string]string
+	}{
+		{"unknown model", "pid", nil},
+		{"state space without a", PlantStateSpace, map[string]string{"b": "1", "c": "1"}},
+		{"state space without c", PlantStateSpace, map[string]string{"a": "1", "b": "1"}},
+		{"bad input", PlantFirstOrder, map[string]string{"input": "a +"}},
+		{"bad gain", PlantFirstOrder, map[string]string{"gain": "high"}},
+		{"zero tau", PlantFirstOrder, map[string]string{"tau": "0"}},
+		{"negative wn", PlantSecondOrder, map[string]string{"wn": "-1"}},
+		{"negative zeta", PlantSecondOrder, map[string]string{"zeta": "-0.1"}},
+		{"min above max", PlantIntegrator, map[string]string{"min": "2", "max": "1"}},
+		{"matrix on other models", PlantFirstOrder, map[string]string{"a": "1"}},
+		{"a not square", PlantStateSpace, map[string]string{"a": "1 2", "b": "1", "c": "1"}},
+		{"ragged a", PlantStateSpace, map[string]string{"a": "1 2; 3", "b": "1; 1", "c": "1 1"}},
+		{"empty row", PlantStateSpace, map[string]string{"a": "1;", "b": "1", "c": "1"}},
+		{"b too long", PlantStateSpace, map[string]string{"a": "1", "b": "1; 2", "c": "1"}},
+		{"c too short", PlantStateSpace, map[string]string{"a": "1 0; 0 1", "b": "1; 1", "c": "1"}},
+		{"d not scalar", PlantStateSpace, map[string]string{"a": "1", "b": "1", "c": "1", "d": "1 2"}},
+		{"bad number", PlantStateSpace, map[string]string{"a": "1 x", "b": "1", "c": "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPlant(tt.model, tt.params); err == nil {
+				t.Error("NewPlant succeeded, want an error")
+			}
+		})
+	}
+
+	p, err := NewPlant(PlantFirstOrder, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetParam("kp", "1"); err == nil {
+		t.Error("SetParam of an unknown parameter succeeded, want an error")
+	}
+	if err := p.SetParam("min", "5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetParam("max", "4"); err == nil || p.max != math.Inf(1) {
+		t.Errorf("SetParam max below min = %v, max %v, want an error and max unchanged", err, p.max)
+	}
+}
@@ -0,0 +1,242 @@
+package engine
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// interpolation modes of a playback
+const (
+	InterpHold   = "hold"   // keep the last sample until the next one
+	InterpLinear = "linear" // interpolate between samples
+)
+
+// Recording is a time series loaded from a CSV file. Times are relative to
+// the first sample.
+type Recording struct {
+	times  []float64 // seconds
+	values []float64
+}
+
+// LoadRecording reads a CSV file with a timestamp in the first column and
+// values in the others. column selects a value column by header name, or by
+// index from 1 when numeric; empty selects the first value column. Timestamps
+// are seconds or RFC 3339 times. A header row is detected when the first
+// timestamp does not parse. Boolean values read as 1 or 0.
+func LoadRecording(path string, column string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+
+	col := 1
+	n, err := strconv.Atoi(column)
+	named := column != "" && err != nil
+	if err == nil {
+		col = n
+	}
+	rec := &Recording{}
+	var t0 time.Time
+	for line := 1; ; line++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		t, abs, tErr := parseTimestamp(row[0])
+		if tErr != nil {
+			if line > 1 || len(rec.times) > 0 {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, tErr)
+			}
+			// header
+			if i := slices.Index(row, column); named && i > 0 {
+				col, named = i, false
+			}
+			continue
+		}
+		if named {
+			return nil, fmt.Errorf("%s: no column %q", path, column)
+		}
+		if col >= len(row) {
+			return nil, fmt.Errorf("%s:%d: no column %d", path, line, col)
+		}
+		v, err := parseSample(row[col])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+
+		if !abs.IsZero() {
+			if t0.IsZero() {
+				t0 = abs
+			}
+			t = abs.Sub(t0).Seconds()
+		}
+		if n := len(rec.times); n > 0 && t < rec.times[n-1] {
+			return nil, fmt.Errorf("%s:%d: timestamps go backwards", path, line)
+		}
+		rec.times = append(rec.times, t)
+		rec.values = append(rec.values, v)
+	}
+	if len(rec.times) == 0 {
+		return nil, fmt.Errorf("%s: no samples", path)
+	}
+	// relative to the first sample
+	base := rec.times[0]
+	for i := range rec.times {
+		rec.times[i] -= base
+	}
+	return rec, nil
+}
+
+// parseTimestamp returns seconds, or an absolute time for RFC 3339 stamps
+func parseTimestamp(s string) (float64, time.Time, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return 0, t, nil
+}
+
+func parseSample(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+	if b, err := strconv.ParseBool(s); err == nil {
+		if b {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("invalid value %q", s)
+}
+
+// at returns the value at t seconds
+func (r *Recording) at(t float64, interp string) float64 {
+	i, found := slices.BinarySearch(r.times, t)
+	switch {
+	case found:
+		// last of equal timestamps, a step
+		for i+1 < len(r.times) && r.times[i+1] == t {
+			i++
+		}
+		return r.values[i]
+	case i == 0:
+		return r.values[0]
+	case i == len(r.times):
+		return r.values[i-1]
+	}
+	if interp != InterpLinear {
+		return r.values[i-1]
+	}
+	t0, t1 := r.times[i-1], r.times[i]
+	v0, v1 := r.values[i-1], r.values[i]
+	return v0 + (v1-v0)*(t-t0)/(t1-t0)
+}
+
+// Playback plays a recording back on the simulator clock.
+type Playback struct {
+	rec    *Recording
+	interp string
+	loop   bool
+	speed  float64       // recording seconds per second
+	offset time.Duration // position in the recording at start
+	start  time.Time     // clock time of offset, set on the first value
+}
+
+// NewPlayback creates a playback of rec. params may set interpolation
+// (hold or linear, default hold), loop (default false), speed (default 1)
+// and time_offset (a duration into the recording, default 0); other keys
+// are ignored so that a data point info can be passed as is.
+func NewPlayback(rec *Recording, params map[string]string) (*Playback, error) {
+	p := &Playback{rec: rec, interp: InterpHold, speed: 1}
+	for _, name := range []string{"interpolation", "loop", "speed", "time_offset"} {
+		if v, ok := params[name]; ok && v != "" {
+			if err := p.SetParam(name, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+// Value returns the recorded value at now. Past the end the last value is
+// held, unless the playback loops.
+func (p *Playback) Value(now time.Time) float64 {
+	return p.rec.at(p.position(now), p.interp)
+}
+
+// position returns the playback position at now in recording seconds
+func (p *Playback) position(now time.Time) float64 {
+	if p.start.IsZero() {
+		p.start = now
+	}
+	pos := p.offset.Seconds() + now.Sub(p.start).Seconds()*p.speed
+	if end := p.rec.times[len(p.rec.times)-1]; p.loop && end > 0 {
+		pos = math.Mod(pos, end)
+		if pos < 0 {
+			pos += end
+		}
+	}
+	return pos
+}
+
+// SetParam changes a playback parameter (interpolation, loop, speed or
+// time_offset) at runtime. Changing the speed keeps the current position,
+// setting time_offset jumps to it.
+func (p *Playback) SetParam(name string, value string) error {
+	switch name {
+	case "interpolation":
+		if value != InterpHold && value != InterpLinear {
+			return fmt.Errorf("unknown interpolation: %s", value)
+		}
+		p.interp = value
+	case "loop":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid loop %q: %w", value, err)
+		}
+		p.loop = v
+	case "speed":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v <= 0 {
+			return fmt.Errorf("speed must be a positive number, got %q", value)
+		}
+		if !p.start.IsZero() {
+			now := time.Now()
+			p.offset = time.Duration(p.position(now) * float64(time.Second))
+			p.start = now
+		}
+		p.speed = v
+	case "time_offset":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid time_offset %q: %w", value, err)
+		}
+		p.offset = d
+		p.start = time.Time{}
+	default:
+		return fmt.Errorf("unknown playback parameter: %s", name)
+	}
+	return nil
+}
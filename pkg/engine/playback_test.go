@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoadRecording(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		column  string
+		times   []float64
+		values  []float64
+		wantErr bool
+	}{
+		{
+			name:   "seconds",
+			csv:    "0,1\n0.5,2\n1.5,3\n",
+			times:  []float64{0, 0.5, 1.5},
+			values: []float64{1, 2, 3},
+		},
+		{
+			name:   "relative to the first sample",
+			csv:    "10,1\n12,2\n",
+			times:  []float64{0, 2},
+			values: []float64{1, 2},
+		},
+		{
+			name:   "header, comments and spaces",
+			csv:    "# recorded on the bench\ntime, speed, temp\n0, 1, 20\n1, 2, 21\n",
+			times:  []float64{0, 1},
+			values: []float64{1, 2},
+		},
+		{
+			name:   "column by name",
+			csv:    "time,speed,temp\n0,1,20\n1,2,21\n",
+			column: "temp",
+			times:  []float64{0, 1},
+			values: []float64{20, 21},
+		},
+		{
+			name:   "column by index",
+			csv:    "0,1,20\n1,2,21\n",
+			column: "2",
+			times:  []float64{0, 1},
+			values: []float64{20, 21},
+		},
+		{
+			name:   "rfc 3339",
+			csv:    "2024-05-01T12:00:00Z,1\n2024-05-01T12:00:01.5Z,2\n2024-05-01T12:01:00Z,3\n",
+			times:  []float64{0, 1.5, 60},
+			values: []float64{1, 2, 3},
+		},
+		{
+			name:   "booleans",
+			csv:    "0,true\n1,false\n2,1\n",
+			times:  []float64{0, 1, 2},
+			values: []float64{1, 0, 1},
+		},
+		{
+			name:   "equal timestamps",
+			csv:    "0,1\n1,2\n1,3\n",
+			times:  []float64{0, 1, 1},
+			values: []float64{1, 2, 3},
+		},
+		{name: "empty", csv: "", wantErr: true},
+		{name: "header only", csv: "time,speed\n", wantErr: true},
+		{name: "unknown column name", csv: "time,speed\n0,1\n", column: "temp", wantErr: true},
+		{name: "named column without header", csv: "0,1\n", column: "speed", wantErr: true},
+		{name: "column index past the row", csv: "0,1\n", column: "2", wantErr: true},
+		{name: "bad timestamp after the first row", csv: "0,1\nlater,2\n", wantErr: true},
+		{name: "bad value", csv: "0,fast\n", wantErr: true},
+		{name: "backwards", csv: "0,1\n2,2\n1,3\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rec.csv")
+			if err := os.WriteFile(path, []byte(tt.csv), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			rec, err := LoadRecording(path, tt.column)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadRecording = %+v, want an error", rec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(rec.times, tt.times) || !slices.Equal(rec.values, tt.values) {
+				t.Errorf("LoadRecording = %v %v, want %v %v", rec.times, rec.values, tt.times, tt.values)
+			}
+		})
+	}
+
+	if _, err := LoadRecording(filepath.Join(t.TempDir(), "missing.csv"), ""); err == nil {
+		t.Error("LoadRecording of a missing file succeeded, want an error")
+	}
+}
+
+func TestPlaybackValue(t *testing.T) {
+	// a ramp with a step at 2s
+	rec := &Recording{
+		times:  []float64{0, 1, 2, 2, 4},
+		values: []float64{0, 10, 20, 30, 40},
+	}
+	tests := []struct {
+		name    string
+		params  map[string]string
+		elapsed time.Duration
+		want    float64
+	}{
+		{name: "start", elapsed: 0, want: 0},
+		{name: "hold", elapsed: 1500 * time.Millisecond, want: 10},
+		{name: "on a sample", elapsed: time.Second, want: 10},
+		{name: "step takes the last sample", elapsed: 2 * time.Second, want: 30},
+		{name: "linear", params: map[string]string{"interpolation": "linear"}, elapsed: 1500 * time.Millisecond, want: 15},
+		{name: "linear after the step", params: map[string]string{"interpolation": "linear"}, elapsed: 3 * time.Second, want: 35},
+		{name: "past the end holds", elapsed: 10 * time.Second, want: 40},
+		{name: "loop", params: map[string]string{"loop": "true"}, elapsed: 5 * time.Second, want: 10},
+		{name: "loop at the end starts over", params: map[string]string{"loop": "true"}, elapsed: 8 * time.Second, want: 0},
+		{name: "speed", params: map[string]string{"speed": "2"}, elapsed: 750 * time.Millisecond, want: 10},
+		{name: "slow", params: map[string]string{"speed": "0.5", "interpolation": "linear"}, elapsed: time.Second, want: 5},
+		{name: "offset", params: map[string]string{"time_offset": "1500ms"}, elapsed: time.Second, want: 30},
+		{name: "offset before the start", params: map[string]string{"time_offset": "-1s"}, elapsed: 0, want: 0},
+		{
+			name:    "negative offset loops from the end",
+			params:  map[string]string{"time_offset": "-1s", "loop": "true", "interpolation": "linear"},
+			elapsed: 0,
+			want:    35,
+		},
+		{name: "unrelated keys ignored", params: map[string]string{"engine": "playback", "file": "rec.csv"}, elapsed: time.Second, want: 10},
+	}
+	t0 := time.Unix(1000, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPlayback(rec, tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Value(t0) // the first value starts the clock
+			if got := p.Value(t0.Add(tt.elapsed)); got != tt.want {
+				t.Errorf("value after %v = %v, want %v", tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaybackSetParam(t *testing.T) {
+	rec := &Recording{times: []float64{0, 10}, values: []float64{0, 100}}
+	p, err := NewPlayback(rec, map[string]string{"interpolation": "linear"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Unix(1000, 0)
+	p.Value(t0)
+	if got := p.Value(t0.Add(2 * time.Second)); got != 20 {
+		t.Fatalf("value = %v, want 20", got)
+	}
+	// a new offset jumps there and restarts the clock
+	if err := p.SetParam("time_offset", "5s"); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Value(t0.Add(3 * time.Second)); got != 50 {
+		t.Errorf("value after time_offset = %v, want 50", got)
+	}
+	if got := p.Value(t0.Add(4 * time.Second)); got != 60 {
+		t.Errorf("value a second later = %v, want 60", got)
+	}
+
+	for _, tt := range []struct{ name, value string }{
+		{"interpolation", "cubic"},
+		{"loop", "sometimes"},
+		{"speed", "0"},
+		{"speed", "-1"},
+		{"speed", "fast"},
+		{"time_offset", "5"},
+		{"volume", "11"},
+	} {
+		if err := p.SetParam(tt.name, tt.value); err == nil {
+			t.Errorf("SetParam(%s, %q) succeeded, want an error", tt.name, tt.value)
+		}
+		// unknown keys are left to the data point info
+		if _, err := NewPlayback(rec, map[string]string{tt.name: tt.value}); err == nil && tt.name != "volume" {
+			t.Errorf("NewPlayback with %s %q succeeded, want an error", tt.name, tt.value)
+		}
+	}
+}
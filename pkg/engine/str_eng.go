@@ -2,7 +2,6 @@ package engine
 
 import (
 	"math"
-	"strings"
 	"time"
 )
 
@@ -28,7 +27,12 @@ func NewStrEngine(size uint16, freq float64, phase float64) *StrEngine {
 
 // Update returns the next value depending on the type of engine
 func (e *StrEngine) Update(val string) string {
-	// Convert val to float32
+	return string(e.Next([]byte(val)))
+}
+
+// Next writes the next value over val, the current one, and returns it. It
+// allocates only when val has no room for Size bytes.
+func (e *StrEngine) Next(val []byte) []byte {
 	switch e.EngType {
 	case "sin":
 		return e.UpdateSin(val)
@@ -41,6 +45,11 @@ func (e *StrEngine) Update(val string) string {
 
 // GenerateString creates the animated string with the '*' at the calculated position.
 func (e *StrEngine) GenerateString() string {
+	return string(e.fill(nil, e.sinIndex()))
+}
+
+// sinIndex moves the '*' to the position of the sine wave at the current time
+func (e *StrEngine) sinIndex() int {
 	elapsedTime := time.Since(e.startTime).Seconds() // Time elapsed in seconds
 
 	// Calculate the sine wave value (-1 to 1)
@@ -55,29 +64,33 @@ func (e *StrEngine) GenerateString() string {
 	// Clamp the index to ensure it stays within the string bounds [0, StringSize - 1]
 	index = int(math.Max(0, math.Min(float64(e.Size-1), float64(index))))
 	e.starIndex = index
+	return index
+}
 
-	// Create a string of '-' characters
-	animatedString := strings.Repeat("-", int(e.Size))
-
-	// Replace the character at the calculated index with '*'
-	// Go strings are immutable, so we build a new string or byte slice
-	runes := []rune(animatedString) // Use runes to handle potential multi-byte characters (though not needed for '-')
-	runes[index] = '*'
-
-	return string(runes)
+// fill writes a string of Size '-' characters with a '*' at index over dst
+func (e *StrEngine) fill(dst []byte, index int) []byte {
+	dst = dst[:0]
+	for i := range int(e.Size) {
+		c := byte('-')
+		if i == index {
+			c = '*'
+		}
+		dst = append(dst, c)
+	}
+	return dst
 }
 
-func (b *StrEngine) UpdateSin(val string) string {
+func (b *StrEngine) UpdateSin(val []byte) []byte {
 	b.lastUpdate = time.Now()
 	// Calculate the time since the last update
 	elapsed := time.Since(b.lastUpdate).Milliseconds()
 	if elapsed < int64(b.Frequency) {
 		return val
 	}
-	return b.GenerateString()
+	return b.fill(val, b.sinIndex())
 }
 
-func (b *StrEngine) UpdateRamp(val string) string {
+func (b *StrEngine) UpdateRamp(val []byte) []byte {
 	b.lastUpdate = time.Now()
 	// Calculate the time since the last update
 	elapsed := time.Since(b.lastUpdate).Milliseconds()
@@ -90,13 +103,5 @@ func (b *StrEngine) UpdateRamp(val string) string {
 	if b.starIndex >= int(b.Size) {
 		b.starIndex = 0
 	}
-
-	// Create a string of '-' characters
-	animatedString := strings.Repeat("-", int(b.Size))
-
-	// Replace the character at the calculated index with '*'
-	runes := []rune(animatedString)
-	runes[b.starIndex] = '*'
-
-	return string(runes)
+	return b.fill(val, b.starIndex)
 }
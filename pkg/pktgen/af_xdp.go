@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"sync"
+	"time"
 
 	"github.com/asavie/xdp"
 	"github.com/vishvananda/netlink"
 )
 
+// how long Write waits for the tx ring to hand back a frame
+const xdpWriteTimeout = 100 * time.Millisecond
+
 // AFXdpSender implements the Sender interface using AF_XDP
 type AFXdpSender struct {
 	queueID        int
@@ -20,10 +25,15 @@ type AFXdpSender struct {
 	dstPort        uint16
 	payloadSize    int
 	srcMAC, dstMAC net.HardwareAddr
+	opts           []PacketOption // extra options such as hop limit and flow label
+	xsk            *xdp.Socket
+	mu             sync.Mutex // serializes Write and Close across payload managers
 }
 
-// NewAFXdpSender creates a new AFXdpSender with specified parameters
-func NewAFXdpSender(iface string, srcIP, dstIP net.IP, srcPort, dstPort, payloadSize int, srcMAC, dstMAC net.HardwareAddr, queueID int) *AFXdpSender {
+// NewAFXdpSender creates a new AFXdpSender with specified parameters.
+// IPv4 or IPv6 framing is selected from srcIP and dstIP; opts are applied on top
+// of the Ethernet, IP and UDP layer options (e.g. WithHopLimit, WithFlowLabel).
+func NewAFXdpSender(iface string, srcIP, dstIP net.IP, srcPort, dstPort, payloadSize int, srcMAC, dstMAC net.HardwareAddr, queueID int, opts ...PacketOption) *AFXdpSender {
 	return &AFXdpSender{
 		queueID:     queueID,
 		srcMAC:      srcMAC,
@@ -34,31 +44,102 @@ func NewAFXdpSender(iface string, srcIP, dstIP net.IP, srcPort, dstPort, payload
 		srcPort:     uint16(srcPort),
 		dstPort:     uint16(dstPort),
 		payloadSize: payloadSize,
+		opts:        opts,
 	}
 }
 
-// Send sends packets using AFXdpSender
-func (s *AFXdpSender) Send(ctx context.Context) error {
-
-	// Initialize the XDP socket.
+// packetConfig builds the packet configuration for this sender
+func (s *AFXdpSender) packetConfig(extra ...PacketOption) (*PacketConfig, error) {
+	opts := []PacketOption{
+		WithEthernetLayer(s.srcMAC, s.dstMAC),
+		WithIpLayer(s.srcIP, s.dstIP),
+		WithUdpLayer(int(s.srcPort), int(s.dstPort)),
+		WithPayloadSize(s.payloadSize),
+	}
+	opts = append(opts, s.opts...)
+	opts = append(opts, extra...)
+	return NewPacketConfig(opts...)
+}
 
+// open initializes the XDP socket on the configured interface and queue
+func (s *AFXdpSender) open() error {
+	if s.xsk != nil {
+		return nil
+	}
 	link, err := netlink.LinkByName(s.iface)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to find interface %s: %w", s.iface, err)
 	}
 
 	xsk, err := xdp.NewSocket(link.Attrs().Index, s.queueID, nil)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to open xdp socket on %s queue %d: %w", s.iface, s.queueID, err)
+	}
+	s.xsk = xsk
+	return nil
+}
+
+// Write builds a single frame around payload and transmits it
+func (s *AFXdpSender) Write(payload []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.open(); err != nil {
+		return 0, err
+	}
+
+	config, err := s.packetConfig(WithPayload(payload))
+	if err != nil {
+		return 0, fmt.Errorf("error configuring packet: %v", err)
+	}
+	packet, err := BuildPacket(config)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build packet: %w", err)
 	}
 
+	// reclaim completed frames until one is free, dropping the packet when
+	// none is within xdpWriteTimeout
+	deadline := time.Now().Add(xdpWriteTimeout)
+	descs := s.xsk.GetDescs(1)
+	for len(descs) == 0 {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return 0, fmt.Errorf("xdp tx ring full for %v", xdpWriteTimeout)
+		}
+		if _, _, err := s.xsk.Poll(int(wait.Milliseconds()) + 1); err != nil {
+			return 0, fmt.Errorf("xdp poll failed: %w", err)
+		}
+		descs = s.xsk.GetDescs(1)
+	}
+	descs[0].Len = uint32(copy(s.xsk.GetFrame(descs[0]), packet))
+	if s.xsk.Transmit(descs) == 0 {
+		return 0, fmt.Errorf("xdp tx ring full")
+	}
+	return len(payload), nil
+}
+
+// Close closes the XDP socket if it was opened
+func (s *AFXdpSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.xsk == nil {
+		return nil
+	}
+	err := s.xsk.Close()
+	s.xsk = nil
+	return err
+}
+
+// Send sends packets using AFXdpSender
+func (s *AFXdpSender) Send(ctx context.Context) error {
+
+	// Initialize the XDP socket.
+	if err := s.open(); err != nil {
+		return err
+	}
+	xsk := s.xsk
+
 	// create a packet configuration
-	config, err := NewPacketConfig(
-		WithEthernetLayer(s.srcMAC, s.dstMAC),
-		WithIpLayer(s.srcIP, s.dstIP),
-		WithUdpLayer(int(s.srcPort), int(s.dstPort)),
-		WithPayloadSize(s.payloadSize),
-	)
+	config, err := s.packetConfig()
 	if err != nil {
 		return fmt.Errorf("error configuring packet: %v", err)
 	}
@@ -94,4 +175,4 @@ func (s *AFXdpSender) Send(ctx context.Context) error {
 		}
 	}
 
-}
\ No newline at end of file
+}
@@ -0,0 +1,105 @@
+package pktgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Framing selects how payloads are delimited on byte stream transports.
+type Framing string
+
+const (
+	FramingNone Framing = "none" // raw payload bytes, no delimiting
+	FramingSLIP Framing = "slip" // RFC 1055
+	FramingCOBS Framing = "cobs" // consistent overhead byte stuffing, 0x00 delimited
+	FramingHDLC Framing = "hdlc" // 0x7E flag with 0x7D escape, no FCS
+)
+
+const (
+	slipEnd    byte = 0xC0
+	slipEsc    byte = 0xDB
+	slipEscEnd byte = 0xDC
+	slipEscEsc byte = 0xDD
+
+	hdlcFlag byte = 0x7E
+	hdlcEsc  byte = 0x7D
+	hdlcXor  byte = 0x20
+)
+
+// ParseFraming converts a framing name to a Framing. An empty name is FramingNone.
+func ParseFraming(name string) (Framing, error) {
+	switch f := Framing(strings.ToLower(strings.TrimSpace(name))); f {
+	case "":
+		return FramingNone, nil
+	case FramingNone, FramingSLIP, FramingCOBS, FramingHDLC:
+		return f, nil
+	default:
+		return FramingNone, fmt.Errorf("unknown framing: %s", name)
+	}
+}
+
+// AppendFrame appends payload encoded with framing f to dst and returns the extended slice.
+func AppendFrame(dst []byte, f Framing, payload []byte) ([]byte, error) {
+	switch f {
+	case FramingNone, "":
+		return append(dst, payload...), nil
+	case FramingSLIP:
+		return appendSLIP(dst, payload), nil
+	case FramingCOBS:
+		return appendCOBS(dst, payload), nil
+	case FramingHDLC:
+		return appendHDLC(dst, payload), nil
+	default:
+		return dst, fmt.Errorf("unknown framing: %s", f)
+	}
+}
+
+// appendSLIP wraps payload in END bytes and escapes END/ESC inside it.
+func appendSLIP(dst []byte, payload []byte) []byte {
+	dst = append(dst, slipEnd)
+	for _, b := range payload {
+		switch b {
+		case slipEnd:
+			dst = append(dst, slipEsc, slipEscEnd)
+		case slipEsc:
+			dst = append(dst, slipEsc, slipEscEsc)
+		default:
+			dst = append(dst, b)
+		}
+	}
+	return append(dst, slipEnd)
+}
+
+// appendCOBS encodes payload so that it contains no zero bytes and terminates it with 0x00.
+func appendCOBS(dst []byte, payload []byte) []byte {
+	codeIdx := len(dst)
+	dst = append(dst, 0)
+	code := byte(1)
+	for _, b := range payload {
+		if b != 0 {
+			dst = append(dst, b)
+			code++
+		}
+		if b == 0 || code == 0xFF {
+			dst[codeIdx] = code
+			codeIdx = len(dst)
+			dst = append(dst, 0)
+			code = 1
+		}
+	}
+	dst[codeIdx] = code
+	return append(dst, 0)
+}
+
+// appendHDLC wraps payload in flag bytes and escapes flag/escape bytes inside it.
+func appendHDLC(dst []byte, payload []byte) []byte {
+	dst = append(dst, hdlcFlag)
+	for _, b := range payload {
+		if b == hdlcFlag || b == hdlcEsc {
+			dst = append(dst, hdlcEsc, b^hdlcXor)
+			continue
+		}
+		dst = append(dst, b)
+	}
+	return append(dst, hdlcFlag)
+}
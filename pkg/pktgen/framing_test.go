@@ -0,0 +1,170 @@
+package pktgen
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// unframe decodes a single frame as a receiver would
+func unframe(f Framing, frame []byte) ([]byte, error) {
+	switch f {
+	case FramingSLIP:
+		return unescape(frame, slipEnd, slipEsc, func(out []byte, b byte) ([]byte, error) {
+			switch b {
+			case slipEscEnd:
+				return append(out, slipEnd), nil
+			case slipEscEsc:
+				return append(out, slipEsc), nil
+			}
+			return nil, fmt.Errorf("bad slip escape %#x", b)
+		})
+	case FramingHDLC:
+		return unescape(frame, hdlcFlag, hdlcEsc, func(out []byte, b byte) ([]byte, error) {
+			return append(out, b^hdlcXor), nil
+		})
+	case FramingCOBS:
+		if len(frame) == 0 || frame[len(frame)-1] != 0 {
+			return nil, fmt.Errorf("cobs frame not terminated")
+		}
+		frame = frame[:len(frame)-1]
+		var out []byte
+		for i := 0; i < len(frame); {
+			code := int(frame[i])
+			if code == 0 || i+code > len(frame) {
+				return nil, fmt.Errorf("bad cobs code %d at %d", code, i)
+			}
+			out = append(out, frame[i+1:i+code]...)
+			i += code
+			if code < 0xFF && i < len(frame) {
+				out = append(out, 0)
+			}
+		}
+		return out, nil
+	}
+	return frame, nil
+}
+
+// unescape strips the delimiters around frame and undoes the escapes in it
+func unescape(frame []byte, delim, esc byte, undo func(out []byte, b byte) ([]byte, error)) ([]byte, error) {
+	if len(frame) < 2 || frame[0] != delim || frame[len(frame)-1] != delim {
+		return nil, fmt.Errorf("frame not delimited by %#x", delim)
+	}
+	out := []byte{}
+	body := frame[1 : len(frame)-1]
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case delim:
+			return nil, fmt.Errorf("delimiter inside the frame at %d", i)
+		case esc:
+			if i++; i == len(body) {
+				return nil, fmt.Errorf("escape at the end of the frame")
+			}
+			var err error
+			if out, err = undo(out, body[i]); err != nil {
+				return nil, err
+			}
+		default:
+			out = append(out, body[i])
+		}
+	}
+	return out, nil
+}
+
+func TestAppendFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing Framing
+		payload []byte
+		want    []byte
+	}{
+		{"none", FramingNone, []byte{1, 0, 2}, []byte{1, 0, 2}},
+		{"slip plain", FramingSLIP, []byte{1, 2}, []byte{0xC0, 1, 2, 0xC0}},
+		{"slip escapes", FramingSLIP, []byte{0xC0, 0xDB}, []byte{0xC0, 0xDB, 0xDC, 0xDB, 0xDD, 0xC0}},
+		{"slip empty", FramingSLIP, nil, []byte{0xC0, 0xC0}},
+		{"hdlc plain", FramingHDLC, []byte{1, 2}, []byte{0x7E, 1, 2, 0x7E}},
+		{"hdlc escapes", FramingHDLC, []byte{0x7E, 0x7D}, []byte{0x7E, 0x7D, 0x5E, 0x7D, 0x5D, 0x7E}},
+		{"cobs zero", FramingCOBS, []byte{0}, []byte{1, 1, 0}},
+		{"cobs zeros", FramingCOBS, []byte{0, 0}, []byte{1, 1, 1, 0}},
+		{"cobs mixed", FramingCOBS, []byte{0x11, 0x22, 0, 0x33}, []byte{3, 0x11, 0x22, 2, 0x33, 0}},
+		{"cobs trailing zero", FramingCOBS, []byte{0x11, 0}, []byte{2, 0x11, 1, 0}},
+		{"cobs empty", FramingCOBS, nil, []byte{1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AppendFrame(nil, tt.framing, tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("AppendFrame = % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	long := bytes.Repeat([]byte{0xAB}, 600) // COBS blocks of 254 bytes
+	payloads := map[string][]byte{
+		"empty":       {},
+		"all bytes":   all,
+		"delimiters":  {0xC0, 0xDB, 0x7E, 0x7D, 0x00, 0xC0, 0x7E, 0x00},
+		"254 nonzero": long[:254],
+		"255 nonzero": long[:255],
+		"long":        long,
+		"zero ends":   append(append([]byte{0}, long[:300]...), 0),
+	}
+	for _, f := range []Framing{FramingNone, FramingSLIP, FramingCOBS, FramingHDLC} {
+		for name, payload := range payloads {
+			t.Run(string(f)+"/"+name, func(t *testing.T) {
+				// frames are appended, what comes before stays
+				prefix := []byte{0x55}
+				frame, err := AppendFrame(prefix, f, payload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if frame[0] != 0x55 {
+					t.Fatalf("prefix overwritten: % x", frame[:1])
+				}
+				if f == FramingCOBS && bytes.IndexByte(frame[1:len(frame)-1], 0) >= 0 {
+					t.Fatalf("zero inside cobs frame % x", frame)
+				}
+				got, err := unframe(f, frame[1:])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, payload) {
+					t.Errorf("round trip = % x, want % x", got, payload)
+				}
+			})
+		}
+	}
+}
+
+func TestParseFraming(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Framing
+		wantErr bool
+	}{
+		{"", FramingNone, false},
+		{"none", FramingNone, false},
+		{" SLIP ", FramingSLIP, false},
+		{"cobs", FramingCOBS, false},
+		{"Hdlc", FramingHDLC, false},
+		{"kiss", FramingNone, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFraming(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFraming(%q) = %q, %v, want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if _, err := AppendFrame(nil, "kiss", []byte{1}); err == nil {
+		t.Error("AppendFrame with an unknown framing succeeded, want an error")
+	}
+}
@@ -21,9 +21,97 @@ type PacketConfig struct {
 	SrcIP, DstIP     net.IP
 	SrcPort, DstPort layers.UDPPort
 	SrcMAC, DstMAC   net.HardwareAddr
+	HopLimit         uint8  // TTL for IPv4, hop limit for IPv6, 0 = default
+	FlowLabel        uint32 // IPv6 only, 20 bits
+	Iface            *net.Interface // egress interface for multicast
+	MulticastTTL     uint8          // TTL/hop limit for multicast destinations
+	MulticastLoop    bool           // deliver multicast to local listeners
+	Broadcast        bool           // destination is a broadcast address
 	Payload		 	 Payload
 }
 
+const (
+	HopLimitDefault uint8  = 64
+	FlowLabelMax    uint32 = 0xFFFFF
+)
+
+// IsIPv6 reports whether the packet will be built with an IPv6 network layer.
+// The stack is selected from the configured addresses: if either address is
+// IPv6 the packet is IPv6, otherwise it is IPv4.
+func (c *PacketConfig) IsIPv6() bool {
+	return IsIPv6(c.SrcIP) || IsIPv6(c.DstIP)
+}
+
+// IsIPv6 reports whether ip is a non-nil address that has no IPv4 form.
+func IsIPv6(ip net.IP) bool {
+	return ip != nil && ip.To4() == nil
+}
+
+// IsBroadcast reports whether ip is the limited broadcast address or the
+// directed broadcast address of one of the subnets configured on iface.
+func IsBroadcast(ip net.IP, iface *net.Interface) bool {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return false // no broadcast in IPv6
+	}
+	if ip4.Equal(net.IPv4bcast) {
+		return true
+	}
+	if iface == nil || iface.Name == "" {
+		return false
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.To4() == nil {
+			continue
+		}
+		mask := ipnet.Mask
+		if len(mask) == net.IPv6len {
+			mask = mask[12:]
+		}
+		bcast := make(net.IP, net.IPv4len)
+		for i := range bcast {
+			bcast[i] = ipnet.IP.To4()[i] | ^mask[i]
+		}
+		if bcast.Equal(ip4) {
+			return true
+		}
+	}
+	return false
+}
+
+// MulticastMAC returns the Ethernet group address that ip maps to.
+// IPv4 groups map to 01:00:5e plus the low 23 bits, IPv6 groups to 33:33 plus the low 32 bits.
+func MulticastMAC(ip net.IP) (net.HardwareAddr, error) {
+	if !ip.IsMulticast() {
+		return nil, fmt.Errorf("%s is not a multicast address", ip)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return net.HardwareAddr{0x01, 0x00, 0x5e, ip4[1] & 0x7f, ip4[2], ip4[3]}, nil
+	}
+	ip16 := ip.To16()
+	return net.HardwareAddr{0x33, 0x33, ip16[12], ip16[13], ip16[14], ip16[15]}, nil
+}
+
+// Network returns the udp network name ("udp4" or "udp6") for a source and
+// destination pair. A nil source adopts the family of the destination.
+func Network(src, dst net.IP) (string, error) {
+	if dst == nil {
+		return "", fmt.Errorf("no destination address")
+	}
+	if src != nil && !src.IsUnspecified() && IsIPv6(src) != IsIPv6(dst) {
+		return "", fmt.Errorf("address family mismatch: src %s, dst %s", src, dst)
+	}
+	if IsIPv6(dst) {
+		return "udp6", nil
+	}
+	return "udp4", nil
+}
+
 func buildPayload(payloadSize int, payload []byte) []byte {
 	payload_buf := make([]byte, payloadSize)
 	for i := range payload_buf {
@@ -59,6 +147,60 @@ func WithUdpLayer(srcPort, dstPort int) PacketOption {
 	}
 }
 
+// WithHopLimit sets the IPv4 TTL or IPv6 hop limit of the packet.
+func WithHopLimit(hops int) PacketOption {
+	return func(c *PacketConfig) error {
+		if hops < 1 || hops > 255 {
+			return fmt.Errorf("invalid hop limit: %d", hops)
+		}
+		c.HopLimit = uint8(hops)
+		return nil
+	}
+}
+
+// WithFlowLabel sets the IPv6 flow label. It is ignored for IPv4 packets.
+func WithFlowLabel(label uint32) PacketOption {
+	return func(c *PacketConfig) error {
+		if label > FlowLabelMax {
+			return fmt.Errorf("invalid flow label: %#x exceeds 20 bits", label)
+		}
+		c.FlowLabel = label
+		return nil
+	}
+}
+
+// WithMulticast configures output to a multicast group: the egress interface
+// (nil lets the kernel route), the TTL/hop limit and whether local listeners
+// on the sending host receive a copy.
+func WithMulticast(iface *net.Interface, ttl int, loopback bool) PacketOption {
+	return func(c *PacketConfig) error {
+		if ttl < 0 || ttl > 255 {
+			return fmt.Errorf("invalid multicast ttl: %d", ttl)
+		}
+		c.Iface = iface
+		c.MulticastTTL = uint8(ttl)
+		c.MulticastLoop = loopback
+		return nil
+	}
+}
+
+// WithBroadcast marks the destination as a broadcast address.
+func WithBroadcast() PacketOption {
+	return func(c *PacketConfig) error {
+		c.Broadcast = true
+		return nil
+	}
+}
+
+// WithPayload sets the payload data for the packet.
+func WithPayload(data []byte) PacketOption {
+	return func(c *PacketConfig) error {
+		c.Payload.Data = data
+		c.Payload.Size = len(data)
+		return nil
+	}
+}
+
 // WithPayloadSize sets the payload size for the packet.
 func WithPayloadSize(size int) PacketOption {
 	return func(c *PacketConfig) error {
@@ -80,29 +222,79 @@ func NewPacketConfig(opts ...PacketOption) (*PacketConfig, error) {
 }
 
 // BuildPacket constructs the packet based on the PacketConfig.
-// It automatically includes the Ethernet layer if both SrcMAC and DstMAC are provided.
+// It automatically includes the Ethernet layer if both SrcMAC and DstMAC are provided;
+// for multicast and broadcast destinations DstMAC is derived when not set.
 func BuildPacket(c *PacketConfig) ([]byte, error) {
 	buf := gopacket.NewSerializeBuffer()
 	var layersToSerialize []gopacket.SerializableLayer
 
+	if c.SrcIP == nil || c.DstIP == nil {
+		return nil, fmt.Errorf("source and destination IP are required")
+	}
+	if _, err := Network(c.SrcIP, c.DstIP); err != nil {
+		return nil, err
+	}
+	hopLimit := c.HopLimit
+	if hopLimit == 0 {
+		hopLimit = HopLimitDefault
+	}
+	if c.DstIP.IsMulticast() {
+		hopLimit = c.MulticastTTL
+		if hopLimit == 0 {
+			hopLimit = 1 // same default as the kernel
+		}
+	}
+
+	// Group addresses have a fixed destination MAC
+	dstMAC := c.DstMAC
+	if c.SrcMAC != nil && dstMAC == nil {
+		switch {
+		case c.DstIP.IsMulticast():
+			mac, err := MulticastMAC(c.DstIP)
+			if err != nil {
+				return nil, err
+			}
+			dstMAC = mac
+		case c.Broadcast || c.DstIP.Equal(net.IPv4bcast):
+			dstMAC = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		}
+	}
+
+	// Select the network layer from the configured addresses
+	var ipLayer interface {
+		gopacket.NetworkLayer
+		gopacket.SerializableLayer
+	}
+	ethType := layers.EthernetTypeIPv4
+	if c.IsIPv6() {
+		ethType = layers.EthernetTypeIPv6
+		ipLayer = &layers.IPv6{
+			Version:    6,
+			FlowLabel:  c.FlowLabel,
+			HopLimit:   hopLimit,
+			SrcIP:      c.SrcIP,
+			DstIP:      c.DstIP,
+			NextHeader: layers.IPProtocolUDP,
+		}
+	} else {
+		ipLayer = &layers.IPv4{
+			Version:  4,
+			TTL:      hopLimit,
+			SrcIP:    c.SrcIP,
+			DstIP:    c.DstIP,
+			Protocol: layers.IPProtocolUDP,
+		}
+	}
+
 	// Automatically include the Ethernet layer if MAC addresses are provided
-	if c.SrcMAC != nil && c.DstMAC != nil {
+	if c.SrcMAC != nil && dstMAC != nil {
 		ethLayer := &layers.Ethernet{
 			SrcMAC:       c.SrcMAC,
-			DstMAC:       c.DstMAC,
-			EthernetType: layers.EthernetTypeIPv4,
+			DstMAC:       dstMAC,
+			EthernetType: ethType,
 		}
 		layersToSerialize = append(layersToSerialize, ethLayer)
 	}
-
-	// Set IP layer
-	ipLayer := &layers.IPv4{
-		Version:  4,
-		TTL:      64,
-		SrcIP:    c.SrcIP,
-		DstIP:    c.DstIP,
-		Protocol: layers.IPProtocolUDP,
-	}
 	layersToSerialize = append(layersToSerialize, ipLayer)
 
 	udpLayer := &layers.UDP{
@@ -0,0 +1,261 @@
+package pktgen
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var (
+	testSrcMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	testDstMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+)
+
+// decode parses a built packet, starting at the Ethernet layer when eth is set
+func decode(t *testing.T, pkt []byte, eth bool, v6 bool) gopacket.Packet {
+	t.Helper()
+	first := layers.LayerTypeIPv4
+	switch {
+	case eth:
+		first = layers.LayerTypeEthernet
+	case v6:
+		first = layers.LayerTypeIPv6
+	}
+	p := gopacket.NewPacket(pkt, first, gopacket.Default)
+	if err := p.ErrorLayer(); err != nil {
+		t.Fatalf("decoding packet: %v", err.Error())
+	}
+	return p
+}
+
+func TestBuildPacketIPv6(t *testing.T) {
+	payload := []byte("payload")
+	tests := []struct {
+		name      string
+		opts      []PacketOption
+		eth       bool
+		hopLimit  uint8
+		flowLabel uint32
+	}{
+		{
+			name:     "default hop limit",
+			opts:     []PacketOption{WithIpLayer(net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2"))},
+			hopLimit: HopLimitDefault,
+		},
+		{
+			name: "hop limit and flow label",
+			opts: []PacketOption{
+				WithIpLayer(net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2")),
+				WithHopLimit(7),
+				WithFlowLabel(0xabcde),
+			},
+			hopLimit:  7,
+			flowLabel: 0xabcde,
+		},
+		{
+			name:     "unspecified source",
+			opts:     []PacketOption{WithIpLayer(net.IPv6unspecified, net.ParseIP("2001:db8::2"))},
+			hopLimit: HopLimitDefault,
+		},
+		{
+			name: "ethernet",
+			opts: []PacketOption{
+				WithEthernetLayer(testSrcMAC, testDstMAC),
+				WithIpLayer(net.ParseIP("fe80::1"), net.ParseIP("fe80::2")),
+			},
+			eth:      true,
+			hopLimit: HopLimitDefault,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append(tt.opts, WithUdpLayer(5000, 6000), WithPayload(payload))
+			c, err := NewPacketConfig(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkt, err := BuildPacket(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := decode(t, pkt, tt.eth, true)
+
+			if tt.eth {
+				eth, _ := p.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+				if eth == nil || eth.EthernetType != layers.EthernetTypeIPv6 {
+					t.Fatalf("ethernet layer %+v, want type IPv6", eth)
+				}
+			}
+			ip, _ := p.Layer(layers.LayerTypeIPv6).(*layers.IPv6)
+			if ip == nil {
+				t.Fatal("no IPv6 layer")
+			}
+			if ip.HopLimit != tt.hopLimit {
+				t.Errorf("hop limit %d, want %d", ip.HopLimit, tt.hopLimit)
+			}
+			if ip.FlowLabel != tt.flowLabel {
+				t.Errorf("flow label %#x, want %#x", ip.FlowLabel, tt.flowLabel)
+			}
+			if ip.NextHeader != layers.IPProtocolUDP {
+				t.Errorf("next header %v, want UDP", ip.NextHeader)
+			}
+			udp, _ := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+			if udp == nil || udp.SrcPort != 5000 || udp.DstPort != 6000 {
+				t.Fatalf("udp layer %+v, want ports 5000 -> 6000", udp)
+			}
+			if !bytes.Equal(udp.Payload, payload) {
+				t.Errorf("payload %q, want %q", udp.Payload, payload)
+			}
+		})
+	}
+}
+
+func TestBuildPacketErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		c    PacketConfig
+	}{
+		{"no source", PacketConfig{DstIP: net.ParseIP("2001:db8::2")}},
+		{"no destination", PacketConfig{SrcIP: net.ParseIP("2001:db8::1")}},
+		{"v4 to v6", PacketConfig{SrcIP: net.ParseIP("192.0.2.1"), DstIP: net.ParseIP("2001:db8::2")}},
+		{"v6 to v4", PacketConfig{SrcIP: net.ParseIP("2001:db8::1"), DstIP: net.ParseIP("192.0.2.2")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildPacket(&tt.c); err == nil {
+				t.Error("BuildPacket succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestPacketOptionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  PacketOption
+	}{
+		{"hop limit 0", WithHopLimit(0)},
+		{"hop limit 256", WithHopLimit(256)},
+		{"flow label over 20 bits", WithFlowLabel(FlowLabelMax + 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPacketConfig(tt.opt); err == nil {
+				t.Error("NewPacketConfig succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestMulticastMAC(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"224.0.0.1", "01:00:5e:00:00:01"},
+		{"239.255.1.2", "01:00:5e:7f:01:02"},
+		{"239.128.1.2", "01:00:5e:00:01:02"}, // the high bit of the second byte is dropped
+		{"ff02::1", "33:33:00:00:00:01"},
+		{"ff15::1:ff00:1234", "33:33:ff:00:12:34"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			mac, err := MulticastMAC(net.ParseIP(tt.ip))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mac.String() != tt.want {
+				t.Errorf("MulticastMAC(%s) = %s, want %s", tt.ip, mac, tt.want)
+			}
+		})
+	}
+	if _, err := MulticastMAC(net.ParseIP("192.0.2.1")); err == nil {
+		t.Error("MulticastMAC of a unicast address succeeded, want an error")
+	}
+}
+
+func TestBuildPacketGroups(t *testing.T) {
+	bcast := "ff:ff:ff:ff:ff:ff"
+	tests := []struct {
+		name   string
+		src    string
+		dst    string
+		opts   []PacketOption
+		dstMAC net.HardwareAddr // configured, nil to derive
+		want   string           // destination MAC
+		ttl    uint8
+	}{
+		{name: "v4 multicast", src: "192.0.2.1", dst: "239.1.2.3", want: "01:00:5e:01:02:03", ttl: 1},
+		{
+			name: "v4 multicast ttl", src: "192.0.2.1", dst: "239.1.2.3",
+			opts: []PacketOption{WithMulticast(nil, 16, false)},
+			want: "01:00:5e:01:02:03", ttl: 16,
+		},
+		{name: "v6 multicast", src: "fe80::1", dst: "ff02::fb", want: "33:33:00:00:00:fb", ttl: 1},
+		{name: "limited broadcast", src: "192.0.2.1", dst: "255.255.255.255", want: bcast, ttl: HopLimitDefault},
+		{
+			name: "directed broadcast", src: "192.0.2.1", dst: "192.0.2.255",
+			opts: []PacketOption{WithBroadcast()},
+			want: bcast, ttl: HopLimitDefault,
+		},
+		{
+			name: "configured MAC wins", src: "192.0.2.1", dst: "239.1.2.3",
+			dstMAC: testDstMAC, want: testDstMAC.String(), ttl: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]PacketOption{
+				WithEthernetLayer(testSrcMAC, tt.dstMAC),
+				WithIpLayer(net.ParseIP(tt.src), net.ParseIP(tt.dst)),
+				WithUdpLayer(5000, 6000),
+			}, tt.opts...)
+			c, err := NewPacketConfig(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkt, err := BuildPacket(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := decode(t, pkt, true, c.IsIPv6())
+
+			eth, _ := p.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+			if eth == nil {
+				t.Fatal("no ethernet layer")
+			}
+			if eth.DstMAC.String() != tt.want {
+				t.Errorf("destination MAC %s, want %s", eth.DstMAC, tt.want)
+			}
+			var ttl uint8
+			if ip, ok := p.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+				ttl = ip.HopLimit
+			} else if ip, ok := p.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+				ttl = ip.TTL
+			}
+			if ttl != tt.ttl {
+				t.Errorf("ttl %d, want %d", ttl, tt.ttl)
+			}
+		})
+	}
+}
+
+func TestIsBroadcast(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"255.255.255.255", true},
+		{"192.0.2.255", false}, // directed broadcasts need the interface
+		{"239.1.2.3", false},
+		{"ff02::1", false},
+	}
+	for _, tt := range tests {
+		if got := IsBroadcast(net.ParseIP(tt.ip), nil); got != tt.want {
+			t.Errorf("IsBroadcast(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package pktgen
+
+import "errors"
+
+// ErrDropped is returned by Write when the transport cannot take a payload
+// without blocking and drops it instead.
+var ErrDropped = errors.New("payload dropped")
+
+// Sender writes fully assembled payloads to an output transport.
+type Sender interface {
+	// Write sends a single payload and returns the number of bytes written.
+	Write(payload []byte) (int, error)
+	// Close releases the resources held by the transport.
+	Close() error
+}
+
+// Receiver reads whole payloads from an input transport.
+type Receiver interface {
+	// Read receives a single payload into buf and returns its length.
+	Read(buf []byte) (int, error)
+	// Close releases the resources held by the transport.
+	Close() error
+}
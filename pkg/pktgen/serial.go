@@ -0,0 +1,233 @@
+package pktgen
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// SerialPty is the device name that makes NewSerialSender create a pseudo-terminal
+// pair instead of opening a serial port. Decoders under test open the slave path.
+const SerialPty = "pty"
+
+// baudRates maps supported line rates to their termios speed constants
+var baudRates = map[int]uint32{
+	1200:    unix.B1200,
+	2400:    unix.B2400,
+	4800:    unix.B4800,
+	9600:    unix.B9600,
+	19200:   unix.B19200,
+	38400:   unix.B38400,
+	57600:   unix.B57600,
+	115200:  unix.B115200,
+	230400:  unix.B230400,
+	460800:  unix.B460800,
+	921600:  unix.B921600,
+	1000000: unix.B1000000,
+	2000000: unix.B2000000,
+	3000000: unix.B3000000,
+	4000000: unix.B4000000,
+}
+
+// SerialSender implements the Sender interface on a serial device or pty.
+// Each payload is framed with the configured Framing and frames are spaced by
+// at least the inter-frame gap after the previous frame has left the line.
+// Writes never block: a frame the device or pty cannot take whole, because
+// nothing reads the other end, is dropped and counted.
+type SerialSender struct {
+	mu      sync.Mutex
+	file    *os.File
+	slave   *os.File // held open for pty pairs so writes never see EIO
+	framing Framing
+	baud    int
+	gap     time.Duration
+	nextTx  time.Time
+	buf     []byte
+	dropped atomic.Uint64
+}
+
+// NewSerialSender opens device (or a new pty pair when device is SerialPty)
+// in raw 8N1 mode at the given baud rate.
+func NewSerialSender(device string, baud int, framing Framing, gap time.Duration) (*SerialSender, error) {
+	speed, ok := baudRates[baud]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate: %d", baud)
+	}
+	if _, err := ParseFraming(string(framing)); err != nil {
+		return nil, err
+	}
+
+	s := &SerialSender{
+		framing: framing,
+		baud:    baud,
+		gap:     gap,
+	}
+
+	if device == SerialPty {
+		master, slave, err := openPty()
+		if err != nil {
+			return nil, err
+		}
+		s.file, s.slave = master, slave
+		// the line discipline sits on the slave side
+		if err := setRaw(slave, speed); err != nil {
+			s.Close()
+			return nil, err
+		}
+		if err := s.setNonblock(); err != nil {
+			s.Close()
+			return nil, err
+		}
+		return s, nil
+	}
+
+	f, err := os.OpenFile(device, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial device %s: %w", device, err)
+	}
+	s.file = f
+	if err := setRaw(f, speed); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if err := s.setNonblock(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// setNonblock makes writes to the device return instead of waiting for room.
+// It comes last, as Fd puts the file back into blocking mode.
+func (s *SerialSender) setNonblock() error {
+	if err := unix.SetNonblock(int(s.file.Fd()), true); err != nil {
+		return fmt.Errorf("failed to set %s non-blocking: %w", s.file.Name(), err)
+	}
+	return nil
+}
+
+// openPty allocates a pseudo-terminal and returns its master and slave ends
+func openPty() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open /dev/ptmx: %w", err)
+	}
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to unlock pty: %w", err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to get pty number: %w", err)
+	}
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to open pty slave: %w", err)
+	}
+	return master, slave, nil
+}
+
+// setRaw puts the terminal in raw 8N1 mode at speed
+func setRaw(f *os.File, speed uint32) error {
+	fd := int(f.Fd())
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return fmt.Errorf("failed to read termios for %s: %w", f.Name(), err)
+	}
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CLOCAL | unix.CREAD | speed
+	t.Ispeed = speed
+	t.Ospeed = speed
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, t); err != nil {
+		return fmt.Errorf("failed to set termios for %s: %w", f.Name(), err)
+	}
+	return nil
+}
+
+// SlavePath returns the path decoders should open when the sender uses a pty pair.
+func (s *SerialSender) SlavePath() string {
+	if s.slave == nil {
+		return ""
+	}
+	return s.slave.Name()
+}
+
+// Write frames payload and writes it to the device, honouring the inter-frame gap.
+func (s *SerialSender) Write(payload []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frame, err := AppendFrame(s.buf[:0], s.framing, payload)
+	if err != nil {
+		return 0, err
+	}
+	s.buf = frame
+
+	if wait := time.Until(s.nextTx); wait > 0 {
+		time.Sleep(wait)
+	}
+	n, err := s.write(frame)
+	if err == unix.EAGAIN || (err == nil && n < len(frame)) {
+		// a partial frame is cut short, framing lets the decoder resync
+		s.dropped.Add(1)
+		return 0, fmt.Errorf("serial write to %s: output buffer full: %w", s.file.Name(), ErrDropped)
+	}
+	if err != nil {
+		return n, fmt.Errorf("serial write to %s failed: %w", s.file.Name(), err)
+	}
+
+	// 10 bit times per byte on an 8N1 line
+	lineTime := time.Duration(len(frame)*10) * time.Second / time.Duration(s.baud)
+	s.nextTx = time.Now().Add(lineTime + s.gap)
+	return len(payload), nil
+}
+
+// write writes frame once without waiting for the device to take it
+func (s *SerialSender) write(frame []byte) (int, error) {
+	raw, err := s.file.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	var werr error
+	err = raw.Write(func(fd uintptr) bool {
+		n, werr = unix.Write(int(fd), frame)
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return max(n, 0), werr
+}
+
+// Dropped returns the number of frames dropped because the device or pty
+// could not take them.
+func (s *SerialSender) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close closes the device and the pty slave if one was created.
+func (s *SerialSender) Close() error {
+	var err error
+	if s.slave != nil {
+		err = s.slave.Close()
+	}
+	if s.file != nil {
+		if cerr := s.file.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,227 @@
+package pktgen
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// UDPSender implements the Sender interface using a kernel UDP socket.
+// The socket family (udp4 or udp6) is selected from the configured addresses,
+// and multicast and broadcast destinations are configured from WithMulticast
+// and WithBroadcast. The TTL or hop limit is left to the system unless
+// WithHopLimit sets one.
+// Flow labels are only applied by senders that build the IP layer themselves,
+// so NewUDPSender rejects WithFlowLabel.
+type UDPSender struct {
+	conn    *net.UDPConn
+	network string
+	config  *PacketConfig
+}
+
+// NewUDPSender creates a UDPSender connected to the configured destination.
+// It accepts the same options as NewPacketConfig; WithIpLayer and WithUdpLayer are required.
+func NewUDPSender(opts ...PacketOption) (*UDPSender, error) {
+	config, err := NewPacketConfig(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring udp sender: %w", err)
+	}
+
+	network, err := Network(config.SrcIP, config.DstIP)
+	if err != nil {
+		return nil, err
+	}
+	if config.FlowLabel != 0 {
+		return nil, fmt.Errorf("flow label %#x: not supported on udp sockets", config.FlowLabel)
+	}
+
+	var laddr *net.UDPAddr
+	if config.SrcIP != nil || config.SrcPort != 0 {
+		laddr = &net.UDPAddr{IP: config.SrcIP, Port: int(config.SrcPort)}
+	}
+	raddr := &net.UDPAddr{IP: config.DstIP, Port: int(config.DstPort)}
+
+	conn, err := net.DialUDP(network, laddr, raddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s socket to %s: %w", network, raddr, err)
+	}
+
+	s := &UDPSender{
+		conn:    conn,
+		network: network,
+		config:  config,
+	}
+	if config.HopLimit != 0 {
+		if err := s.setHopLimit(int(config.HopLimit)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if config.DstIP.IsMulticast() {
+		if err := s.setMulticast(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if config.Broadcast || config.DstIP.Equal(net.IPv4bcast) {
+		if err := setSockOptInt(conn, unix.SOL_SOCKET, unix.SO_BROADCAST, 1); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// setHopLimit sets the unicast TTL (IPv4) or hop limit (IPv6) on the socket.
+func (s *UDPSender) setHopLimit(hops int) error {
+	level, opt := unix.IPPROTO_IP, unix.IP_TTL
+	if s.network == "udp6" {
+		level, opt = unix.IPPROTO_IPV6, unix.IPV6_UNICAST_HOPS
+	}
+	return setSockOptInt(s.conn, level, opt, hops)
+}
+
+// setMulticast applies the egress interface, TTL/hop limit and loopback
+// settings for a multicast destination.
+func (s *UDPSender) setMulticast() error {
+	c := s.config
+	ttl := int(c.MulticastTTL)
+	if ttl == 0 {
+		ttl = 1
+	}
+	loop := 0
+	if c.MulticastLoop {
+		loop = 1
+	}
+
+	if s.network == "udp6" {
+		if c.Iface != nil && c.Iface.Index != 0 {
+			if err := setSockOptInt(s.conn, unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_IF, c.Iface.Index); err != nil {
+				return err
+			}
+		}
+		if err := setSockOptInt(s.conn, unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_HOPS, ttl); err != nil {
+			return err
+		}
+		return setSockOptInt(s.conn, unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_LOOP, loop)
+	}
+
+	if c.Iface != nil && c.Iface.Index != 0 {
+		raw, err := s.conn.SyscallConn()
+		if err != nil {
+			return fmt.Errorf("failed to access raw socket: %w", err)
+		}
+		var sockErr error
+		err = raw.Control(func(fd uintptr) {
+			mreq := &unix.IPMreqn{Ifindex: int32(c.Iface.Index)}
+			sockErr = unix.SetsockoptIPMreqn(int(fd), unix.IPPROTO_IP, unix.IP_MULTICAST_IF, mreq)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to access raw socket: %w", err)
+		}
+		if sockErr != nil {
+			return fmt.Errorf("failed to select multicast interface %s: %w", c.Iface.Name, sockErr)
+		}
+	}
+	if err := setSockOptInt(s.conn, unix.IPPROTO_IP, unix.IP_MULTICAST_TTL, ttl); err != nil {
+		return err
+	}
+	return setSockOptInt(s.conn, unix.IPPROTO_IP, unix.IP_MULTICAST_LOOP, loop)
+}
+
+// setSockOptInt sets an integer socket option on a UDP connection.
+func setSockOptInt(conn *net.UDPConn, level, opt, value int) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("failed to access raw socket: %w", err)
+	}
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), level, opt, value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to access raw socket: %w", err)
+	}
+	if sockErr != nil {
+		return fmt.Errorf("setsockopt(%d, %d) failed: %w", level, opt, sockErr)
+	}
+	return nil
+}
+
+// Network returns the socket network in use ("udp4" or "udp6").
+func (s *UDPSender) Network() string {
+	return s.network
+}
+
+// Write sends payload as a single UDP datagram.
+func (s *UDPSender) Write(payload []byte) (int, error) {
+	n, err := s.conn.Write(payload)
+	// a connected socket reports ICMP port unreachable on the next write;
+	// the receiver not listening yet is not a sender error
+	if err != nil && !errors.Is(err, syscall.ECONNREFUSED) {
+		return n, fmt.Errorf("udp write to %s failed: %w", s.conn.RemoteAddr(), err)
+	}
+	return n, nil
+}
+
+// Close closes the underlying socket.
+func (s *UDPSender) Close() error {
+	return s.conn.Close()
+}
+
+// UDPReceiver implements the Receiver interface using a kernel UDP socket.
+// Listening on a multicast group joins it on the configured interface.
+type UDPReceiver struct {
+	conn *net.UDPConn
+}
+
+// NewUDPReceiver listens on addr:port. A nil or unspecified addr listens on all
+// addresses of the family; iface selects the interface used to join a multicast group.
+func NewUDPReceiver(addr net.IP, port int, iface *net.Interface) (*UDPReceiver, error) {
+	network := "udp4"
+	if IsIPv6(addr) {
+		network = "udp6"
+	}
+	laddr := &net.UDPAddr{IP: addr, Port: port}
+
+	var conn *net.UDPConn
+	var err error
+	if addr != nil && addr.IsMulticast() {
+		conn, err = net.ListenMulticastUDP(network, iface, laddr)
+	} else {
+		conn, err = net.ListenUDP(network, laddr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s %s: %w", network, laddr, err)
+	}
+	return &UDPReceiver{conn: conn}, nil
+}
+
+// Read receives a single datagram into buf.
+func (r *UDPReceiver) Read(buf []byte) (int, error) {
+	n, _, err := r.conn.ReadFromUDP(buf)
+	return n, err
+}
+
+// ReadFrom receives a single datagram into buf and returns the sender's address.
+func (r *UDPReceiver) ReadFrom(buf []byte) (int, *net.UDPAddr, error) {
+	return r.conn.ReadFromUDP(buf)
+}
+
+// WriteTo sends payload back to addr from the receiving socket.
+func (r *UDPReceiver) WriteTo(payload []byte, addr *net.UDPAddr) (int, error) {
+	return r.conn.WriteToUDP(payload, addr)
+}
+
+// LocalAddr returns the address the receiver is bound to.
+func (r *UDPReceiver) LocalAddr() net.Addr {
+	return r.conn.LocalAddr()
+}
+
+// Close closes the underlying socket.
+func (r *UDPReceiver) Close() error {
+	return r.conn.Close()
+}